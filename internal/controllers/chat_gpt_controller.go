@@ -3,64 +3,332 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
 	"golang-gin-boilerplate/internal/services"
-	"os"
+	"log"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
+	"golang.org/x/text/language"
 )
 
 type ChatGPTController struct {
-	client  *openai.Client
-	context *services.ConversationContext
+	provider   interfaces.LLMProvider
+	sessions   *services.SessionManager
+	summarizer interfaces.Summarizer
+	compaction services.CompactionConfig
 }
 
 func NewChatGPTController() *ChatGPTController {
-	apiKey := os.Getenv("OPEN_API_KEY")
-	if apiKey == "" {
-		// Logging or error handling for missing API key
-		panic("OPENAI_API_KEY environment variable is not set")
+	provider, err := NewLLMProviderFromEnv()
+	if err != nil {
+		// Logging or error handling for missing configuration
+		panic(err.Error())
+	}
+
+	sessions, err := NewSessionManagerFromEnv(provider.Model())
+	if err != nil {
+		panic(err.Error())
+	}
+
+	controller := NewChatGPTControllerWithProvider(provider, sessions)
+
+	compaction, enabled, err := NewCompactionConfigFromEnv()
+	if err != nil {
+		panic(err.Error())
+	}
+	if enabled {
+		controller.EnableCompaction(NewLLMSummarizer(provider), compaction)
 	}
 
+	return controller
+}
+
+func NewChatGPTControllerWithProvider(provider interfaces.LLMProvider, sessions *services.SessionManager) *ChatGPTController {
 	return &ChatGPTController{
-		client:  openai.NewClient(apiKey),
-		context: services.NewConversationContext(openai.GPT3Dot5Turbo),
+		provider: provider,
+		sessions: sessions,
 	}
 }
 
-func (c *ChatGPTController) ProcessConversation(userInput string) (string, error) {
-	// Add user message
-	c.context.AddMessage(services.MessageTypeUser, userInput)
+// EnableCompaction summarizes old turns into a memory instead of dropping them
+func (c *ChatGPTController) EnableCompaction(summarizer interfaces.Summarizer, config services.CompactionConfig) {
+	c.summarizer = summarizer
+	c.compaction = config
+}
+
+func (c *ChatGPTController) ProcessConversation(ctx context.Context, sessionID string, input services.ConversationInput) (string, error) {
+	responseText, _, err := c.runTurn(ctx, sessionID, input, func(messages []openai.ChatCompletionMessage) (string, openai.Usage, error) {
+		responseText, err := c.provider.CreateChatCompletion(ctx, messages)
+		return responseText, openai.Usage{}, err
+	})
+	return responseText, err
+}
+
+// ProcessConversationStream is ProcessConversation with the reply delivered
+// to onDelta as it is generated. The full reply is still appended to the
+// conversation. Usage falls back to local token counts when the backend
+// does not report it.
+func (c *ChatGPTController) ProcessConversationStream(ctx context.Context, sessionID string, input services.ConversationInput, onDelta func(delta string) error) (string, openai.Usage, error) {
+	return c.runTurn(ctx, sessionID, input, func(messages []openai.ChatCompletionMessage) (string, openai.Usage, error) {
+		return c.provider.StreamChatCompletion(ctx, messages, onDelta)
+	})
+}
+
+// ProcessConversationSpoken runs a conversation turn and speaks the reply
+// while it is still being generated, in the conversation's language and in
+// voice. Each completed sentence goes to the pipeline; onDelta (optional)
+// sees the raw text and onAudio the audio in order.
+//
+// Input without words is not sent to the model; the user is asked to repeat
+// it instead. When the model fails before anything was said, an apology is
+// spoken in place of the reply.
+func (c *ChatGPTController) ProcessConversationSpoken(
+	ctx context.Context,
+	sessionID string,
+	input services.ConversationInput,
+	pipeline *TTSPipeline,
+	voice models.VoiceSelectionModel,
+	onDelta func(delta string) error,
+	onAudio func(audio []byte) error,
+) (string, error) {
+	replyLanguage := input.Language
+	if replyLanguage == language.Und {
+		var err error
+		if replyLanguage, err = c.SessionLanguage(ctx, sessionID); err != nil {
+			return "", err
+		}
+	}
+	speech := models.SpeechOptions{Voice: voice}
+	if replyLanguage != language.Und {
+		speech.Language = replyLanguage.String()
+	}
+
+	if strings.TrimSpace(input.Text) == "" {
+		return speakFallback(ctx, services.MsgFallbackNotHeard, replyLanguage, pipeline, speech, onDelta, onAudio)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sentences := make(chan string)
+	queued := false
+	queueSentence := func(sentence string) error {
+		select {
+		case sentences <- sentence:
+			queued = true
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var reply string
+	var replyErr error
+	replyDone := make(chan struct{})
+
+	go func() {
+		defer close(replyDone)
+		defer close(sentences)
+
+		splitter := services.NewSentenceSplitter()
+		reply, _, replyErr = c.ProcessConversationStream(ctx, sessionID, input, func(delta string) error {
+			if onDelta != nil {
+				if err := onDelta(delta); err != nil {
+					return err
+				}
+			}
+			for _, sentence := range splitter.Push(delta) {
+				if err := queueSentence(sentence); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if replyErr != nil {
+			replyErr = fmt.Errorf("failed to process conversation: %v", replyErr)
+			return
+		}
 
-	// Prepare request
-	req := openai.ChatCompletionRequest{
-		Model:     openai.GPT3Dot5Turbo,
-		Messages:  c.context.Messages,
-		MaxTokens: 1500,
+		if rest := splitter.Flush(); rest != "" {
+			queueSentence(rest)
+		}
+	}()
+
+	speechErr := pipeline.Synthesize(ctx, sentences, speech, onAudio)
+	if speechErr != nil {
+		// Stop generating text nobody will hear
+		cancel()
 	}
+	<-replyDone
 
-	// Get response from OpenAI
-	resp, err := c.client.CreateChatCompletion(context.Background(), req)
+	if replyErr != nil && speechErr == nil {
+		if queued || ctx.Err() != nil {
+			return "", replyErr
+		}
+		log.Printf("Answering with an apology: %v", replyErr)
+		return speakFallback(ctx, services.MsgFallbackNoAnswer, replyLanguage, pipeline, speech, onDelta, onAudio)
+	}
+	if speechErr != nil {
+		return "", speechErr
+	}
+
+	return reply, nil
+}
+
+// speakFallback says the localized message key in place of a reply
+func speakFallback(
+	ctx context.Context,
+	key string,
+	lang language.Tag,
+	pipeline *TTSPipeline,
+	speech models.SpeechOptions,
+	onDelta func(delta string) error,
+	onAudio func(audio []byte) error,
+) (string, error) {
+	text := services.Localize(lang, key)
+	if onDelta != nil {
+		if err := onDelta(text); err != nil {
+			return "", err
+		}
+	}
+	if err := pipeline.Speak(ctx, text, speech, onAudio); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// runTurn adds the user input, asks the model through complete and records the reply
+func (c *ChatGPTController) runTurn(
+	ctx context.Context,
+	sessionID string,
+	input services.ConversationInput,
+	complete func(messages []openai.ChatCompletionMessage) (string, openai.Usage, error),
+) (string, openai.Usage, error) {
+	// One turn at a time per session, or the last to save loses the others
+	unlock, err := c.sessions.LockTurn(ctx, sessionID)
+	if err != nil {
+		return "", openai.Usage{}, err
+	}
+	defer unlock()
+
+	conversation, err := c.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return "", openai.Usage{}, err
+	}
+
+	// Answer in the language the user spoke
+	if input.Language != language.Und {
+		conversation.SetLanguagePreference(input.Language)
+	}
+
+	// Add user message, one per speaker when the input names them. With
+	// compaction, turns that no longer fit are summarized before they go.
+	if c.summarizer != nil {
+		conversation.AppendUserInput(input)
+		c.compact(ctx, conversation)
+	} else {
+		conversation.AddUserInput(input)
+	}
+
+	// Get response from the configured model, using the same messages the
+	// history and token endpoints report
+	messages := conversation.Snapshot()
+	responseText, usage, err := complete(messages)
 	if err != nil {
-		return "", fmt.Errorf("error creating chat completion: %v", err)
+		// An unanswered or cancelled turn must not be left for the next one
+		conversation.RemoveUserInput(input)
+		return "", openai.Usage{}, err
 	}
 
-	// Extract and add assistant response
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+	if usage.TotalTokens == 0 {
+		usage.PromptTokens = conversation.CountTokens(messages)
+		usage.CompletionTokens = services.CountTextTokens(c.provider.Model(), responseText)
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 	}
 
-	responseText := resp.Choices[0].Message.Content
-	c.context.AddMessage(services.MessageTypeAssistant, responseText)
+	// Add assistant response
+	if c.summarizer != nil {
+		conversation.AppendMessage(services.MessageTypeAssistant, responseText)
+		c.compact(ctx, conversation)
+	} else {
+		conversation.AddMessage(services.MessageTypeAssistant, responseText)
+	}
+
+	if err := c.sessions.Save(ctx, sessionID, conversation); err != nil {
+		return "", openai.Usage{}, err
+	}
 
-	return responseText, nil
+	return responseText, usage, nil
+}
+
+// compact summarizes the turns that no longer fit, or trims them when that fails
+func (c *ChatGPTController) compact(ctx context.Context, conversation *services.ConversationContext) {
+	if err := conversation.Compact(ctx, c.summarizer, c.compaction); err != nil {
+		log.Printf("Falling back to trimming: %v", err)
+	}
 }
 
 // Optional: Method to reset conversation context
-func (c *ChatGPTController) ResetConversation() {
-	c.context.ResetContext()
+func (c *ChatGPTController) ResetConversation(sessionID string) error {
+	ctx := context.Background()
+
+	unlock, err := c.sessions.LockTurn(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	conversation, ok, err := c.sessions.Lookup(ctx, sessionID)
+	if err != nil || !ok {
+		return err
+	}
+
+	conversation.ResetContext()
+	return c.sessions.Save(ctx, sessionID, conversation)
+}
+
+// SessionLanguage returns the language the session's replies are written
+// in, language.Und for a new session or one that has not chosen
+func (c *ChatGPTController) SessionLanguage(ctx context.Context, sessionID string) (language.Tag, error) {
+	conversation, ok, err := c.sessions.Lookup(ctx, sessionID)
+	if err != nil || !ok {
+		return language.Und, err
+	}
+	return conversation.Language(), nil
 }
 
 // Optional: Method to get current context tokens
-func (c *ChatGPTController) GetCurrentTokenCount() int {
-	return c.context.CalculateTotalTokens()
+func (c *ChatGPTController) GetCurrentTokenCount(sessionID string) (int, error) {
+	conversation, ok, err := c.sessions.Lookup(context.Background(), sessionID)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return conversation.CalculateTotalTokens(), nil
+}
+
+// GetConversationHistory returns the session's messages and their token count
+func (c *ChatGPTController) GetConversationHistory(sessionID string) (models.ConversationHistoryModel, error) {
+	history := models.ConversationHistoryModel{
+		SessionID: sessionID,
+		Messages:  []models.ConversationMessageModel{},
+	}
+
+	conversation, ok, err := c.sessions.Lookup(context.Background(), sessionID)
+	if err != nil || !ok {
+		return history, err
+	}
+
+	messages := conversation.Snapshot()
+	for _, message := range messages {
+		history.Messages = append(history.Messages, models.ConversationMessageModel{
+			Role:    message.Role,
+			Name:    message.Name,
+			Content: message.Content,
+		})
+	}
+	history.TotalTokens = conversation.CountTokens(messages)
+
+	return history, nil
 }
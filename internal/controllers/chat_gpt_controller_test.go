@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func newTestChatController(t *testing.T, responses ...string) *ChatGPTController {
+	t.Helper()
+	provider := NewScriptedLLMProvider(responses...)
+	return NewChatGPTControllerWithProvider(provider, services.NewSessionManager(provider.Model(), 0, 0, nil))
+}
+
+func historyContents(t *testing.T, c *ChatGPTController, sessionID string) []string {
+	t.Helper()
+	history, err := c.GetConversationHistory(sessionID)
+	if err != nil {
+		t.Fatalf("GetConversationHistory: %v", err)
+	}
+	var contents []string
+	for _, message := range history.Messages {
+		if message.Role == openai.ChatMessageRoleSystem {
+			continue
+		}
+		contents = append(contents, message.Role+": "+message.Content)
+	}
+	return contents
+}
+
+func TestFailedTurnLeavesNoUserMessage(t *testing.T) {
+	c := newTestChatController(t, "Hello there.", "Second answer.")
+	ctx := context.Background()
+
+	if _, err := c.ProcessConversation(ctx, "session", services.TextInput("Hi")); err != nil {
+		t.Fatalf("first turn: %v", err)
+	}
+
+	clientGone := errors.New("client disconnected")
+	_, _, err := c.ProcessConversationStream(ctx, "session", services.TextInput("Are you there?"), func(string) error {
+		return clientGone
+	})
+	if !errors.Is(err, clientGone) {
+		t.Fatalf("stream error = %v, want %v", err, clientGone)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := c.ProcessConversationStream(cancelled, "session", services.TextInput("Hello?"), func(string) error { return nil }); err == nil {
+		t.Fatal("cancelled turn succeeded")
+	}
+
+	want := []string{"user: Hi", "assistant: Hello there."}
+	got := historyContents(t, c, "session")
+	if len(got) != len(want) {
+		t.Fatalf("history = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("history = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestFailedTurnRemovesEverySpeaker(t *testing.T) {
+	c := newTestChatController(t, "Noted.")
+	input := services.ConversationInput{
+		Text: "Hi there. Hello.",
+		Speakers: []services.SpeakerTurn{
+			{Speaker: "speaker_0", Text: "Hi there."},
+			{Speaker: "speaker_1", Text: "Hello."},
+		},
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := c.ProcessConversationStream(cancelled, "session", input, func(string) error { return nil }); err == nil {
+		t.Fatal("cancelled turn succeeded")
+	}
+
+	if got := historyContents(t, c, "session"); len(got) != 0 {
+		t.Fatalf("history = %q, want only the system prompt", got)
+	}
+}
+
+// slowLLMProvider answers after a pause, long enough for concurrent turns to
+// overlap
+type slowLLMProvider struct {
+	*ScriptedLLMProvider
+}
+
+func (p slowLLMProvider) CreateChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	time.Sleep(50 * time.Millisecond)
+	return p.ScriptedLLMProvider.CreateChatCompletion(ctx, messages)
+}
+
+func TestConcurrentTurnsKeepEachOther(t *testing.T) {
+	store, err := services.NewFileConversationStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := slowLLMProvider{NewScriptedLLMProvider("First answer.", "Second answer.")}
+	c := NewChatGPTControllerWithProvider(provider, services.NewSessionManager(provider.Model(), 0, 0, store))
+
+	var wg sync.WaitGroup
+	for _, text := range []string{"One", "Two"} {
+		wg.Add(1)
+		go func(text string) {
+			defer wg.Done()
+			if _, err := c.ProcessConversation(context.Background(), "session", services.TextInput(text)); err != nil {
+				t.Errorf("turn %q: %v", text, err)
+			}
+		}(text)
+	}
+	wg.Wait()
+
+	got := historyContents(t, c, "session")
+	if len(got) != 4 {
+		t.Fatalf("history = %q, want both turns", got)
+	}
+	// Each turn saw the one before it
+	if requests := provider.Requests(); len(requests[1]) != len(requests[0])+2 {
+		t.Errorf("second turn sent %d messages, want %d", len(requests[1]), len(requests[0])+2)
+	}
+	sort.Strings(got)
+	want := []string{"assistant: First answer.", "assistant: Second answer.", "user: One", "user: Two"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("history = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang-gin-boilerplate/internal/models"
+
+	"golang.org/x/text/language"
+)
+
+const coquiVoiceID = "default"
+
+// CoquiTTSProvider calls the Coqui TTS FastAPI service (POST /generate)
+type CoquiTTSProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewCoquiTTSProvider(baseURL string) *CoquiTTSProvider {
+	return &CoquiTTSProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{},
+	}
+}
+
+func (p *CoquiTTSProvider) Name() string {
+	return TTSProviderCoqui
+}
+
+func (p *CoquiTTSProvider) ContentType() string {
+	return "audio/mpeg"
+}
+
+// ConvertTextToSpeech passes the language on as its ISO 639-1 code, which
+// multilingual models such as XTTS take
+func (p *CoquiTTSProvider) ConvertTextToSpeech(ctx context.Context, text string, options models.SpeechOptions) ([]byte, error) {
+	url := fmt.Sprintf("%s/generate", p.baseURL)
+
+	payload := map[string]interface{}{
+		"text": text,
+	}
+	if code := coquiLanguage(options); code != "" {
+		payload["language"] = code
+	}
+
+	return postTTSRequest(ctx, p.client, url, payload, nil)
+}
+
+// Voices has the one voice of the service's model
+func (p *CoquiTTSProvider) Voices(ctx context.Context) ([]models.VoiceModel, error) {
+	return []models.VoiceModel{{ID: coquiVoiceID, Name: "Coqui"}}, nil
+}
+
+func (p *CoquiTTSProvider) DefaultVoice(lang string) string {
+	return coquiVoiceID
+}
+
+// Capabilities is empty, the service takes no voice settings
+func (p *CoquiTTSProvider) Capabilities() models.VoiceCapabilitiesModel {
+	return models.VoiceCapabilitiesModel{}
+}
+
+func (p *CoquiTTSProvider) VoiceKey(options models.SpeechOptions) string {
+	return p.baseURL + "|" + coquiLanguage(options)
+}
+
+// coquiLanguage is the ISO 639-1 code of the language asked for, if any
+func coquiLanguage(options models.SpeechOptions) string {
+	tag, err := language.Parse(options.Language)
+	if err != nil {
+		return ""
+	}
+	base, _ := tag.Base()
+	return base.String()
+}
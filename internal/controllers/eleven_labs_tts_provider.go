@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+
+	"golang.org/x/text/language"
+)
+
+// Ranges of the Eleven Labs voice settings
+const (
+	minElevenLabsSpeed = 0.7
+	maxElevenLabsSpeed = 1.2
+)
+
+// elevenLabsCatalogTTL is how long the account's voice list is reused
+const elevenLabsCatalogTTL = 10 * time.Minute
+
+type ElevenLabsVoiceSettings struct {
+	Stability       float64 `json:"stability"`
+	SimilarityBoost float64 `json:"similarity_boost"`
+	Speed           float64 `json:"speed,omitempty"`
+}
+
+// ElevenLabsTTSProvider synthesizes speech through the Eleven Labs API
+type ElevenLabsTTSProvider struct {
+	baseURL  string
+	apiKey   string
+	voiceID  string
+	modelID  string
+	settings ElevenLabsVoiceSettings
+	client   *http.Client
+	// LanguageVoices maps BCP 47 tags, or just the language as in "es", to
+	// the voice that speaks them. Other languages use the default voice.
+	LanguageVoices map[string]string
+
+	catalogMu      sync.Mutex
+	catalog        []models.VoiceModel
+	catalogFetched time.Time
+}
+
+func NewElevenLabsTTSProvider(baseURL, apiKey, voiceID, modelID string, settings ElevenLabsVoiceSettings) *ElevenLabsTTSProvider {
+	return &ElevenLabsTTSProvider{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		voiceID:  voiceID,
+		modelID:  modelID,
+		settings: settings,
+		client:   &http.Client{},
+	}
+}
+
+func (p *ElevenLabsTTSProvider) Name() string {
+	return TTSProviderElevenLabs
+}
+
+func (p *ElevenLabsTTSProvider) ContentType() string {
+	return "audio/mpeg"
+}
+
+func (p *ElevenLabsTTSProvider) ConvertTextToSpeech(ctx context.Context, text string, options models.SpeechOptions) ([]byte, error) {
+	voiceID, settings := p.voice(options)
+	url := fmt.Sprintf("%s/v1/text-to-speech/%s", p.baseURL, voiceID)
+
+	// Prepare the request body
+	payload := map[string]interface{}{
+		"text":           text,
+		"voice_settings": settings,
+	}
+	if p.modelID != "" {
+		payload["model_id"] = p.modelID
+	}
+
+	headers := map[string]string{
+		"xi-api-key": p.apiKey,
+		"Accept":     p.ContentType(),
+	}
+
+	return postTTSRequest(ctx, p.client, url, payload, headers)
+}
+
+func (p *ElevenLabsTTSProvider) VoiceKey(options models.SpeechOptions) string {
+	voiceID, settings := p.voice(options)
+	return fmt.Sprintf("%s|%s|%s|%g|%g|%g", p.baseURL, voiceID, p.modelID,
+		settings.Stability, settings.SimilarityBoost, settings.Speed)
+}
+
+func (p *ElevenLabsTTSProvider) DefaultVoice(lang string) string {
+	return voiceForLanguage(p.LanguageVoices, lang, p.voiceID)
+}
+
+// Capabilities defaults to the configured voice settings
+func (p *ElevenLabsTTSProvider) Capabilities() models.VoiceCapabilitiesModel {
+	speed := p.settings.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	return models.VoiceCapabilitiesModel{
+		Speed:           &models.VoiceSettingRangeModel{Min: minElevenLabsSpeed, Max: maxElevenLabsSpeed, Default: speed},
+		Stability:       &models.VoiceSettingRangeModel{Min: 0, Max: 1, Default: p.settings.Stability},
+		SimilarityBoost: &models.VoiceSettingRangeModel{Min: 0, Max: 1, Default: p.settings.SimilarityBoost},
+	}
+}
+
+// voice picks the voice and settings options ask for, the configured ones
+// where they ask for none
+func (p *ElevenLabsTTSProvider) voice(options models.SpeechOptions) (string, ElevenLabsVoiceSettings) {
+	voiceID := options.Voice.VoiceID
+	if voiceID == "" {
+		voiceID = p.DefaultVoice(options.Language)
+	}
+
+	settings := p.settings
+	if options.Voice.Stability != nil {
+		settings.Stability = *options.Voice.Stability
+	}
+	if options.Voice.SimilarityBoost != nil {
+		settings.SimilarityBoost = *options.Voice.SimilarityBoost
+	}
+	if options.Voice.Speed != nil {
+		settings.Speed = *options.Voice.Speed
+	}
+	return voiceID, settings
+}
+
+// Voices lists the voices of the account, fetched again once they are
+// older than elevenLabsCatalogTTL
+func (p *ElevenLabsTTSProvider) Voices(ctx context.Context) ([]models.VoiceModel, error) {
+	p.catalogMu.Lock()
+	defer p.catalogMu.Unlock()
+
+	if p.catalog != nil && time.Since(p.catalogFetched) < elevenLabsCatalogTTL {
+		return p.catalog, nil
+	}
+
+	voices, err := p.fetchVoices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.catalog, p.catalogFetched = voices, time.Now()
+	return voices, nil
+}
+
+func (p *ElevenLabsTTSProvider) fetchVoices(ctx context.Context) ([]models.VoiceModel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/v1/voices", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("xi-api-key", p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list voices: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned non-200 status: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var body struct {
+		Voices []struct {
+			VoiceID           string            `json:"voice_id"`
+			Name              string            `json:"name"`
+			Labels            map[string]string `json:"labels"`
+			VerifiedLanguages []struct {
+				Language string `json:"language"`
+				Locale   string `json:"locale"`
+			} `json:"verified_languages"`
+		} `json:"voices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode voices: %v", err)
+	}
+
+	voices := make([]models.VoiceModel, 0, len(body.Voices))
+	for _, v := range body.Voices {
+		var tags []string
+		for _, verified := range v.VerifiedLanguages {
+			tag := verified.Locale
+			if tag == "" {
+				tag = verified.Language
+			}
+			tags = append(tags, tag)
+		}
+		if len(tags) == 0 && v.Labels["language"] != "" {
+			tags = append(tags, v.Labels["language"])
+		}
+		voices = append(voices, models.VoiceModel{ID: v.VoiceID, Name: v.Name, Languages: canonicalLanguages(tags)})
+	}
+	return voices, nil
+}
+
+// canonicalLanguages keeps the valid tags, canonical and without duplicates
+func canonicalLanguages(tags []string) []string {
+	var languages []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		parsed, err := language.Parse(tag)
+		if err != nil || seen[parsed.String()] {
+			continue
+		}
+		seen[parsed.String()] = true
+		languages = append(languages, parsed.String())
+	}
+	return languages
+}
+
+// voiceForLanguage picks the voice for a BCP 47 tag, trying the full tag
+// before the language alone
+func voiceForLanguage(voices map[string]string, tag, fallback string) string {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return fallback
+	}
+	if voice, ok := voices[parsed.String()]; ok {
+		return voice
+	}
+	base, _ := parsed.Base()
+	if voice, ok := voices[base.String()]; ok {
+		return voice
+	}
+	return fallback
+}
+
+// postTTSRequest sends a JSON payload and returns the raw audio body
+func postTTSRequest(ctx context.Context, client *http.Client, url string, payload interface{}, headers map[string]string) ([]byte, error) {
+	// Create JSON payload
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JSON payload: %v", err)
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	// Send the request
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned non-200 status: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	// Read the audio response
+	audioData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	return audioData, nil
+}
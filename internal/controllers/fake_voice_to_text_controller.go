@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// FakeVoiceToTextController is an in-process provider that never leaves the
+// machine. It returns a fixed transcript so the whole pipeline can run
+// without cloud credentials.
+type FakeVoiceToTextController struct {
+	Transcript string
+}
+
+func NewFakeVoiceToTextController(transcript string) *FakeVoiceToTextController {
+	if transcript == "" {
+		transcript = "Hello, this is a test transcript."
+	}
+	return &FakeVoiceToTextController{Transcript: transcript}
+}
+
+func (f *FakeVoiceToTextController) ConvertVoiceToText(audioFilePath string) (string, error) {
+	// Still make sure the upload actually reached the disk
+	if _, err := os.Stat(audioFilePath); err != nil {
+		return "", fmt.Errorf("failed to open audio file: %v", err)
+	}
+
+	return f.Transcript, nil
+}
+
+// StreamVoiceToText reveals the transcript one word per chunk of audio read,
+// then sends it as a final result once audio is exhausted
+func (f *FakeVoiceToTextController) StreamVoiceToText(ctx context.Context, audio io.Reader, sampleRate int, options models.TranscriptionOptions) (<-chan models.StreamingTranscript, error) {
+	results := make(chan models.StreamingTranscript)
+	words := strings.Fields(f.Transcript)
+
+	go func() {
+		defer close(results)
+
+		buf := make([]byte, streamingChunkBytes)
+		revealed := 0
+		for {
+			_, err := audio.Read(buf)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				emitStreamingTranscript(ctx, results, models.StreamingTranscript{Err: fmt.Errorf("failed to read audio: %v", err)})
+				return
+			}
+			if revealed < len(words) {
+				revealed++
+				interim := models.StreamingTranscript{Text: strings.Join(words[:revealed], " ")}
+				if !emitStreamingTranscript(ctx, results, interim) {
+					return
+				}
+			}
+		}
+
+		emitStreamingTranscript(ctx, results, models.StreamingTranscript{Text: f.Transcript, IsFinal: true})
+	}()
+
+	return results, nil
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"golang-gin-boilerplate/internal/models"
+)
+
+// About 100ms of 16 kHz mono audio per request, as Google recommends
+const streamingChunkBytes = 3200
+
+// StreamVoiceToText recognizes PCM read from audio with StreamingRecognize,
+// sending interim results while the caller is still producing audio. A
+// single stream is limited by Google to about five minutes.
+func (v *VoiceToTextController) StreamVoiceToText(ctx context.Context, audio io.Reader, sampleRate int, options models.TranscriptionOptions) (<-chan models.StreamingTranscript, error) {
+	client, err := newSpeechClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := client.StreamingRecognize(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("streaming recognition failed: %v", err)
+	}
+
+	config := &speechpb.RecognitionConfig{
+		Encoding:          speechpb.RecognitionConfig_LINEAR16,
+		SampleRateHertz:   int32(sampleRate),
+		AudioChannelCount: 1,
+	}
+	v.applyLanguage(config, options)
+	applyVocabulary(config, options)
+
+	// The first request carries only the configuration
+	err = stream.Send(&speechpb.StreamingRecognizeRequest{
+		StreamingRequest: &speechpb.StreamingRecognizeRequest_StreamingConfig{
+			StreamingConfig: &speechpb.StreamingRecognitionConfig{
+				Config:         config,
+				InterimResults: true,
+			},
+		},
+	})
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("streaming recognition failed: %v", err)
+	}
+
+	results := make(chan models.StreamingTranscript)
+	sendErrs := make(chan error, 1)
+
+	// Upload audio as it arrives
+	go func() {
+		sendErrs <- sendStreamingAudio(stream, audio)
+	}()
+
+	// Forward results until the service closes the stream
+	go func() {
+		defer client.Close()
+		defer close(results)
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				emitStreamingTranscript(ctx, results, models.StreamingTranscript{Err: fmt.Errorf("streaming recognition failed: %v", err)})
+				return
+			}
+			if resp.Error != nil {
+				emitStreamingTranscript(ctx, results, models.StreamingTranscript{Err: fmt.Errorf("streaming recognition failed: %s", resp.Error.Message)})
+				return
+			}
+
+			for _, result := range resp.Results {
+				if len(result.Alternatives) == 0 {
+					continue
+				}
+				transcript := models.StreamingTranscript{
+					Text:      strings.TrimSpace(result.Alternatives[0].Transcript),
+					IsFinal:   result.IsFinal,
+					Stability: result.Stability,
+				}
+				if result.IsFinal {
+					transcript.Language = canonicalLanguage(result.LanguageCode)
+				}
+				if !emitStreamingTranscript(ctx, results, transcript) {
+					return
+				}
+			}
+		}
+
+		if err := <-sendErrs; err != nil {
+			emitStreamingTranscript(ctx, results, models.StreamingTranscript{Err: err})
+		}
+	}()
+
+	return results, nil
+}
+
+func sendStreamingAudio(stream speechpb.Speech_StreamingRecognizeClient, audio io.Reader) error {
+	buf := make([]byte, streamingChunkBytes)
+	for {
+		n, err := audio.Read(buf)
+		if n > 0 {
+			sendErr := stream.Send(&speechpb.StreamingRecognizeRequest{
+				StreamingRequest: &speechpb.StreamingRecognizeRequest_AudioContent{
+					AudioContent: append([]byte(nil), buf[:n]...),
+				},
+			})
+			if sendErr == io.EOF {
+				// The service ended the stream; Recv reports why
+				return nil
+			}
+			if sendErr != nil {
+				return fmt.Errorf("failed to send audio: %v", sendErr)
+			}
+		}
+		if err == io.EOF {
+			return stream.CloseSend()
+		}
+		if err != nil {
+			stream.CloseSend()
+			return fmt.Errorf("failed to read audio: %v", err)
+		}
+	}
+}
+
+// emitStreamingTranscript delivers a result unless the caller has gone away
+func emitStreamingTranscript(ctx context.Context, results chan<- models.StreamingTranscript, transcript models.StreamingTranscript) bool {
+	select {
+	case results <- transcript:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"golang-gin-boilerplate/internal/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSpeechServer answers StreamingRecognize with handle and Recognize with
+// recognize, in process
+type fakeSpeechServer struct {
+	speechpb.UnimplementedSpeechServer
+	handle    func(stream speechpb.Speech_StreamingRecognizeServer) error
+	recognize func(ctx context.Context, req *speechpb.RecognizeRequest) (*speechpb.RecognizeResponse, error)
+}
+
+func (s *fakeSpeechServer) StreamingRecognize(stream speechpb.Speech_StreamingRecognizeServer) error {
+	return s.handle(stream)
+}
+
+func (s *fakeSpeechServer) Recognize(ctx context.Context, req *speechpb.RecognizeRequest) (*speechpb.RecognizeResponse, error) {
+	return s.recognize(ctx, req)
+}
+
+// startFakeSpeechServer serves handle on a local port and points
+// newSpeechClient at it through SPEECH_ENDPOINT
+func startFakeSpeechServer(t *testing.T, handle func(stream speechpb.Speech_StreamingRecognizeServer) error) {
+	t.Helper()
+	serveFakeSpeech(t, &fakeSpeechServer{handle: handle})
+}
+
+func serveFakeSpeech(t *testing.T, fake *fakeSpeechServer) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	speechpb.RegisterSpeechServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	t.Setenv("SPEECH_ENDPOINT", listener.Addr().String())
+}
+
+// receiveStreamingConfig reads the first request, which must carry only the configuration
+func receiveStreamingConfig(stream speechpb.Speech_StreamingRecognizeServer) (*speechpb.StreamingRecognitionConfig, error) {
+	req, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	config := req.GetStreamingConfig()
+	if config == nil {
+		return nil, status.Error(codes.InvalidArgument, "first request has no streaming config")
+	}
+	return config, nil
+}
+
+// receiveAudio reads audio requests until the client closes its side
+func receiveAudio(stream speechpb.Speech_StreamingRecognizeServer) ([]byte, error) {
+	var audio []byte
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return audio, nil
+		}
+		if err != nil {
+			return audio, err
+		}
+		audio = append(audio, req.GetAudioContent()...)
+	}
+}
+
+func streamingResult(text string, isFinal bool, languageCode string) *speechpb.StreamingRecognizeResponse {
+	return &speechpb.StreamingRecognizeResponse{
+		Results: []*speechpb.StreamingRecognitionResult{{
+			Alternatives: []*speechpb.SpeechRecognitionAlternative{{Transcript: text}},
+			IsFinal:      isFinal,
+			Stability:    0.5,
+			LanguageCode: languageCode,
+		}},
+	}
+}
+
+// collectTranscripts drains results, failing if the channel is not closed in time
+func collectTranscripts(t *testing.T, results <-chan models.StreamingTranscript) []models.StreamingTranscript {
+	t.Helper()
+
+	var transcripts []models.StreamingTranscript
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case transcript, ok := <-results:
+			if !ok {
+				return transcripts
+			}
+			transcripts = append(transcripts, transcript)
+		case <-timeout:
+			t.Fatalf("results not closed, got %+v", transcripts)
+		}
+	}
+}
+
+func TestStreamVoiceToTextInterimAndFinal(t *testing.T) {
+	audio := bytes.Repeat([]byte{1, 2}, streamingChunkBytes*2)
+	configs := make(chan *speechpb.StreamingRecognitionConfig, 1)
+	uploads := make(chan []byte, 1)
+
+	startFakeSpeechServer(t, func(stream speechpb.Speech_StreamingRecognizeServer) error {
+		config, err := receiveStreamingConfig(stream)
+		if err != nil {
+			return err
+		}
+		configs <- config
+		if err := stream.Send(streamingResult("turn on", false, "")); err != nil {
+			return err
+		}
+		received, err := receiveAudio(stream)
+		if err != nil {
+			return err
+		}
+		uploads <- received
+		return stream.Send(streamingResult(" turn on the lights ", true, "en-us"))
+	})
+
+	controller := &VoiceToTextController{}
+	options := models.TranscriptionOptions{
+		Language: "en-GB",
+		Vocabulary: &models.VocabularyModel{Phrases: []models.PhraseHintModel{
+			{Phrase: "lights", Boost: 10},
+		}},
+	}
+	results, err := controller.StreamVoiceToText(context.Background(), bytes.NewReader(audio), 16000, options)
+	if err != nil {
+		t.Fatalf("StreamVoiceToText: %v", err)
+	}
+	transcripts := collectTranscripts(t, results)
+
+	if len(transcripts) != 2 {
+		t.Fatalf("got %d transcripts, want 2: %+v", len(transcripts), transcripts)
+	}
+	if got := transcripts[0]; got.Text != "turn on" || got.IsFinal || got.Stability != 0.5 || got.Language != "" || got.Err != nil {
+		t.Errorf("interim = %+v", got)
+	}
+	if got := transcripts[1]; got.Text != "turn on the lights" || !got.IsFinal || got.Language != "en-US" || got.Err != nil {
+		t.Errorf("final = %+v", got)
+	}
+
+	if received := <-uploads; !bytes.Equal(received, audio) {
+		t.Errorf("server received %d audio bytes, want %d", len(received), len(audio))
+	}
+	config := <-configs
+	recognition := config.GetConfig()
+	if !config.InterimResults || recognition.SampleRateHertz != 16000 || recognition.LanguageCode != "en-GB" {
+		t.Errorf("streaming config = %+v", config)
+	}
+	if contexts := recognition.SpeechContexts; len(contexts) != 1 || contexts[0].Boost != 10 || contexts[0].Phrases[0] != "lights" {
+		t.Errorf("speech contexts = %+v, want the vocabulary", contexts)
+	}
+}
+
+func TestStreamVoiceToTextErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(stream speechpb.Speech_StreamingRecognizeServer) error
+		want   string
+	}{
+		{
+			name: "status",
+			handle: func(stream speechpb.Speech_StreamingRecognizeServer) error {
+				if _, err := receiveStreamingConfig(stream); err != nil {
+					return err
+				}
+				return status.Error(codes.InvalidArgument, "sample rate mismatch")
+			},
+			want: "sample rate mismatch",
+		},
+		{
+			name: "after interim result",
+			handle: func(stream speechpb.Speech_StreamingRecognizeServer) error {
+				if _, err := receiveStreamingConfig(stream); err != nil {
+					return err
+				}
+				if err := stream.Send(streamingResult("hello", false, "")); err != nil {
+					return err
+				}
+				return status.Error(codes.ResourceExhausted, "quota exceeded")
+			},
+			want: "quota exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startFakeSpeechServer(t, tt.handle)
+
+			results, err := (&VoiceToTextController{}).StreamVoiceToText(context.Background(), bytes.NewReader(make([]byte, 3200)), 16000, models.TranscriptionOptions{})
+			if err != nil {
+				t.Fatalf("StreamVoiceToText: %v", err)
+			}
+			transcripts := collectTranscripts(t, results)
+			if len(transcripts) == 0 {
+				t.Fatal("no transcripts, want an error")
+			}
+
+			last := transcripts[len(transcripts)-1]
+			if last.Err == nil || !strings.Contains(last.Err.Error(), tt.want) {
+				t.Fatalf("last transcript error = %v, want %q", last.Err, tt.want)
+			}
+			for _, transcript := range transcripts[:len(transcripts)-1] {
+				if transcript.Err != nil {
+					t.Errorf("error before the last transcript: %v", transcript.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamVoiceToTextCancel(t *testing.T) {
+	serverDone := make(chan error, 1)
+	startFakeSpeechServer(t, func(stream speechpb.Speech_StreamingRecognizeServer) error {
+		if _, err := receiveStreamingConfig(stream); err != nil {
+			return err
+		}
+		// Keep sending interim results until the client goes away
+		for {
+			if err := stream.Send(streamingResult("still listening", false, "")); err != nil {
+				serverDone <- err
+				return err
+			}
+			select {
+			case <-stream.Context().Done():
+				serverDone <- stream.Context().Err()
+				return stream.Context().Err()
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	})
+
+	// The audio never ends, as when the client is still talking
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := (&VoiceToTextController{}).StreamVoiceToText(ctx, reader, 16000, models.TranscriptionOptions{})
+	if err != nil {
+		t.Fatalf("StreamVoiceToText: %v", err)
+	}
+	if transcript := <-results; transcript.Text != "still listening" {
+		t.Fatalf("first transcript = %+v", transcript)
+	}
+	cancel()
+
+	collectTranscripts(t, results)
+	select {
+	case <-serverDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server stream not cancelled")
+	}
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang-gin-boilerplate/internal/interfaces"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	LLMProviderOpenAI           = "openai"
+	LLMProviderOpenAICompatible = "openai-compatible"
+	LLMProviderFake             = "fake"
+)
+
+// NewLLMProviderFromEnv builds the provider selected by LLM_PROVIDER.
+//
+//	LLM_PROVIDER        openai (default), openai-compatible or fake
+//	LLM_MODEL           model name, defaults to gpt-3.5-turbo
+//	LLM_BASE_URL        base URL for openai-compatible servers
+//	LLM_API_KEY         API key, falls back to OPEN_API_KEY
+//	LLM_MAX_TOKENS      completion token limit, defaults to 1500
+//	FAKE_LLM_RESPONSES  "|" separated replies for the fake provider
+func NewLLMProviderFromEnv() (interfaces.LLMProvider, error) {
+	model := os.Getenv("LLM_MODEL")
+	if model == "" {
+		model = openai.GPT3Dot5Turbo
+	}
+
+	maxTokens := 1500
+	if value := os.Getenv("LLM_MAX_TOKENS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid LLM_MAX_TOKENS %q", value)
+		}
+		maxTokens = parsed
+	}
+
+	apiKey := os.Getenv("LLM_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPEN_API_KEY")
+	}
+
+	switch name := strings.ToLower(os.Getenv("LLM_PROVIDER")); name {
+	case "", LLMProviderOpenAI:
+		if apiKey == "" {
+			return nil, fmt.Errorf("OPEN_API_KEY environment variable is not set")
+		}
+		return NewOpenAILLMProvider(apiKey, model, maxTokens), nil
+	case LLMProviderOpenAICompatible:
+		baseURL := os.Getenv("LLM_BASE_URL")
+		if baseURL == "" {
+			return nil, fmt.Errorf("LLM_BASE_URL environment variable is not set")
+		}
+		return NewOpenAICompatibleLLMProvider(baseURL, apiKey, model, maxTokens), nil
+	case LLMProviderFake:
+		responses := []string{"This is a scripted assistant response."}
+		if value := os.Getenv("FAKE_LLM_RESPONSES"); value != "" {
+			responses = strings.Split(value, "|")
+		}
+		return NewScriptedLLMProvider(responses...), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", name)
+	}
+}
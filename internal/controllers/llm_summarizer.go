@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang-gin-boilerplate/internal/interfaces"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const summarizerPrompt = `You maintain the long-term memory of a voice assistant conversation.
+Merge the existing memory with the new transcript excerpt into a concise summary.
+Keep every name, number, date, preference, fact and decision the user or the assistant mentioned.
+Drop small talk. Write in the third person, at most 200 words, as plain sentences.`
+
+// LLMSummarizer asks the chat model to fold evicted turns into a memory
+type LLMSummarizer struct {
+	provider interfaces.LLMProvider
+}
+
+func NewLLMSummarizer(provider interfaces.LLMProvider) *LLMSummarizer {
+	return &LLMSummarizer{provider: provider}
+}
+
+func (s *LLMSummarizer) Summarize(ctx context.Context, previousSummary string, messages []openai.ChatCompletionMessage) (string, error) {
+	var transcript strings.Builder
+	if previousSummary != "" {
+		fmt.Fprintf(&transcript, "Existing memory:\n%s\n\n", previousSummary)
+	}
+	transcript.WriteString("Transcript excerpt:\n")
+	for _, message := range messages {
+		speaker := message.Role
+		if message.Name != "" {
+			// One of several speakers in a recording
+			speaker += " " + message.Name
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", speaker, message.Content)
+	}
+
+	return s.provider.CreateChatCompletion(ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: summarizerPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: transcript.String()},
+	})
+}
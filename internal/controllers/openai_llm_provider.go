@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAILLMProvider talks to OpenAI or to any server speaking the same chat
+// completions API (vLLM, Ollama, llama.cpp server, ...)
+type OpenAILLMProvider struct {
+	name      string
+	client    *openai.Client
+	model     string
+	maxTokens int
+}
+
+func NewOpenAILLMProvider(apiKey, model string, maxTokens int) *OpenAILLMProvider {
+	return &OpenAILLMProvider{
+		name:      LLMProviderOpenAI,
+		client:    openai.NewClient(apiKey),
+		model:     model,
+		maxTokens: maxTokens,
+	}
+}
+
+// NewOpenAICompatibleLLMProvider points the OpenAI client at a self-hosted
+// base URL such as http://localhost:11434/v1
+func NewOpenAICompatibleLLMProvider(baseURL, apiKey, model string, maxTokens int) *OpenAILLMProvider {
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = strings.TrimRight(baseURL, "/")
+
+	return &OpenAILLMProvider{
+		name:      LLMProviderOpenAICompatible,
+		client:    openai.NewClientWithConfig(config),
+		model:     model,
+		maxTokens: maxTokens,
+	}
+}
+
+func (p *OpenAILLMProvider) Name() string {
+	return p.name
+}
+
+func (p *OpenAILLMProvider) Model() string {
+	return p.model
+}
+
+func (p *OpenAILLMProvider) CreateChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	req := openai.ChatCompletionRequest{
+		Model:     p.model,
+		Messages:  messages,
+		MaxTokens: p.maxTokens,
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("error creating chat completion: %v", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response choices returned")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+func (p *OpenAILLMProvider) StreamChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage, onDelta func(delta string) error) (string, openai.Usage, error) {
+	req := openai.ChatCompletionRequest{
+		Model:     p.model,
+		Messages:  messages,
+		MaxTokens: p.maxTokens,
+		Stream:    true,
+		// Ask for the usage chunk at the end; servers without support ignore it
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return "", openai.Usage{}, fmt.Errorf("error creating chat completion stream: %v", err)
+	}
+	defer stream.Close()
+
+	var reply strings.Builder
+	var usage openai.Usage
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return reply.String(), usage, fmt.Errorf("error reading chat completion stream: %v", err)
+		}
+
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		reply.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return reply.String(), usage, err
+		}
+	}
+
+	if reply.Len() == 0 {
+		return "", usage, fmt.Errorf("no response choices returned")
+	}
+
+	return reply.String(), usage, nil
+}
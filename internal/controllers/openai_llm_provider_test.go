@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// chatServer stands in for /v1/chat/completions. It records the last request
+// and answers with handle.
+func chatServer(t *testing.T, handle func(w http.ResponseWriter, req openai.ChatCompletionRequest)) (*httptest.Server, *openai.ChatCompletionRequest) {
+	t.Helper()
+
+	var received openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handle(w, received)
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+// writeSSE sends each chunk as a server-sent event, then [DONE]
+func writeSSE(w http.ResponseWriter, chunks ...interface{}) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, chunk := range chunks {
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func deltaChunk(content string) openai.ChatCompletionStreamResponse {
+	return openai.ChatCompletionStreamResponse{
+		Choices: []openai.ChatCompletionStreamChoice{{Delta: openai.ChatCompletionStreamChoiceDelta{Content: content}}},
+	}
+}
+
+var testMessages = []openai.ChatCompletionMessage{
+	{Role: openai.ChatMessageRoleSystem, Content: "Be brief."},
+	{Role: openai.ChatMessageRoleUser, Content: "Hello"},
+}
+
+func TestOpenAICreateChatCompletion(t *testing.T) {
+	server, received := chatServer(t, func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Hi!"}}},
+		})
+	})
+
+	provider := NewOpenAICompatibleLLMProvider(server.URL+"/v1/", "key", "test-model", 50)
+	reply, err := provider.CreateChatCompletion(context.Background(), testMessages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Hi!" {
+		t.Errorf("reply = %q, want %q", reply, "Hi!")
+	}
+	if received.Model != "test-model" || received.MaxTokens != 50 || received.Stream {
+		t.Errorf("request model = %q, max_tokens = %d, stream = %v", received.Model, received.MaxTokens, received.Stream)
+	}
+	if !reflect.DeepEqual(received.Messages, testMessages) {
+		t.Errorf("messages = %+v, want %+v", received.Messages, testMessages)
+	}
+}
+
+func TestOpenAICreateChatCompletionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(w http.ResponseWriter, req openai.ChatCompletionRequest)
+		want   string
+	}{
+		{
+			name: "error status",
+			handle: func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"error":{"message":"rate limited","type":"requests"}}`)
+			},
+			want: "rate limited",
+		},
+		{
+			name: "server error",
+			handle: func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `{"error":{"message":"boom"}}`)
+			},
+			want: "500",
+		},
+		{
+			name: "no choices",
+			handle: func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+				json.NewEncoder(w).Encode(openai.ChatCompletionResponse{})
+			},
+			want: "no response choices",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _ := chatServer(t, test.handle)
+			provider := NewOpenAICompatibleLLMProvider(server.URL+"/v1", "key", "test-model", 0)
+
+			_, err := provider.CreateChatCompletion(context.Background(), testMessages)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("err = %v, want it to mention %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIStreamChatCompletion(t *testing.T) {
+	usage := openai.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}
+	server, received := chatServer(t, func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+		// The usage arrives on a last chunk without choices
+		writeSSE(w, deltaChunk("Hel"), deltaChunk(""), deltaChunk("lo"), deltaChunk(" there"),
+			openai.ChatCompletionStreamResponse{Usage: &usage})
+	})
+
+	provider := NewOpenAICompatibleLLMProvider(server.URL+"/v1", "key", "test-model", 0)
+	var deltas []string
+	reply, gotUsage, err := provider.StreamChatCompletion(context.Background(), testMessages, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Hello there" {
+		t.Errorf("reply = %q, want %q", reply, "Hello there")
+	}
+	if want := []string{"Hel", "lo", " there"}; !reflect.DeepEqual(deltas, want) {
+		t.Errorf("deltas = %q, want %q", deltas, want)
+	}
+	if gotUsage != usage {
+		t.Errorf("usage = %+v, want %+v", gotUsage, usage)
+	}
+	if !received.Stream || received.StreamOptions == nil || !received.StreamOptions.IncludeUsage {
+		t.Errorf("request stream = %v, stream_options = %+v, want usage asked for", received.Stream, received.StreamOptions)
+	}
+}
+
+func TestOpenAIStreamChatCompletionWithoutUsage(t *testing.T) {
+	server, _ := chatServer(t, func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+		writeSSE(w, deltaChunk("Hi"))
+	})
+
+	provider := NewOpenAICompatibleLLMProvider(server.URL+"/v1", "key", "test-model", 0)
+	reply, usage, err := provider.StreamChatCompletion(context.Background(), testMessages, func(string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Hi" || usage != (openai.Usage{}) {
+		t.Errorf("reply = %q, usage = %+v, want %q and no usage", reply, usage, "Hi")
+	}
+}
+
+func TestOpenAIStreamChatCompletionErrors(t *testing.T) {
+	t.Run("error status", func(t *testing.T) {
+		server, _ := chatServer(t, func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"message":"bad key","type":"invalid_request_error"}}`)
+		})
+		provider := NewOpenAICompatibleLLMProvider(server.URL+"/v1", "key", "test-model", 0)
+
+		_, _, err := provider.StreamChatCompletion(context.Background(), testMessages, func(string) error { return nil })
+		if err == nil || !strings.Contains(err.Error(), "bad key") {
+			t.Fatalf("err = %v, want the server's message", err)
+		}
+	})
+
+	t.Run("empty reply", func(t *testing.T) {
+		server, _ := chatServer(t, func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+			writeSSE(w)
+		})
+		provider := NewOpenAICompatibleLLMProvider(server.URL+"/v1", "key", "test-model", 0)
+
+		_, _, err := provider.StreamChatCompletion(context.Background(), testMessages, func(string) error { return nil })
+		if err == nil || !strings.Contains(err.Error(), "no response choices") {
+			t.Fatalf("err = %v, want no response choices", err)
+		}
+	})
+
+	t.Run("onDelta fails", func(t *testing.T) {
+		server, _ := chatServer(t, func(w http.ResponseWriter, req openai.ChatCompletionRequest) {
+			writeSSE(w, deltaChunk("a"), deltaChunk("b"))
+		})
+		provider := NewOpenAICompatibleLLMProvider(server.URL+"/v1", "key", "test-model", 0)
+
+		stop := errors.New("client gone")
+		reply, _, err := provider.StreamChatCompletion(context.Background(), testMessages, func(string) error { return stop })
+		if !errors.Is(err, stop) {
+			t.Fatalf("err = %v, want %v", err, stop)
+		}
+		if reply != "a" {
+			t.Errorf("partial reply = %q, want %q", reply, "a")
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ScriptedLLMProvider replays canned responses in order and records every
+// request it receives. Once the script runs out the last response repeats.
+type ScriptedLLMProvider struct {
+	mu        sync.Mutex
+	responses []string
+	next      int
+	requests  [][]openai.ChatCompletionMessage
+}
+
+func NewScriptedLLMProvider(responses ...string) *ScriptedLLMProvider {
+	return &ScriptedLLMProvider{responses: responses}
+}
+
+func (p *ScriptedLLMProvider) Name() string {
+	return LLMProviderFake
+}
+
+func (p *ScriptedLLMProvider) Model() string {
+	return openai.GPT3Dot5Turbo
+}
+
+func (p *ScriptedLLMProvider) CreateChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sent := make([]openai.ChatCompletionMessage, len(messages))
+	copy(sent, messages)
+	p.requests = append(p.requests, sent)
+
+	if len(p.responses) == 0 {
+		return "", fmt.Errorf("no response choices returned")
+	}
+
+	response := p.responses[p.next]
+	if p.next < len(p.responses)-1 {
+		p.next++
+	}
+
+	return response, nil
+}
+
+// StreamChatCompletion replays the next response word by word
+func (p *ScriptedLLMProvider) StreamChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage, onDelta func(delta string) error) (string, openai.Usage, error) {
+	response, err := p.CreateChatCompletion(ctx, messages)
+	if err != nil {
+		return "", openai.Usage{}, err
+	}
+
+	words := strings.SplitAfter(response, " ")
+	for _, word := range words {
+		if err := ctx.Err(); err != nil {
+			return "", openai.Usage{}, err
+		}
+		if err := onDelta(word); err != nil {
+			return "", openai.Usage{}, err
+		}
+	}
+
+	return response, openai.Usage{}, nil
+}
+
+// Requests returns copies of the message lists sent so far
+func (p *ScriptedLLMProvider) Requests() [][]openai.ChatCompletionMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	requests := make([][]openai.ChatCompletionMessage, len(p.requests))
+	copy(requests, p.requests)
+	return requests
+}
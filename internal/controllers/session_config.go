@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/services"
+)
+
+const (
+	ConversationStoreMemory = "memory"
+	ConversationStoreFile   = "file"
+	ConversationStoreSQLite = "sqlite"
+	ConversationStoreRedis  = "redis"
+)
+
+// NewSessionManagerFromEnv reads the session limits, see sessionLimitsFromEnv,
+// and attaches the store selected by CONVERSATION_STORE
+func NewSessionManagerFromEnv(model string) (*services.SessionManager, error) {
+	idleTimeout, maxSessions, err := sessionLimitsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := NewConversationStoreFromEnv(idleTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.NewSessionManager(model, idleTimeout, maxSessions, store), nil
+}
+
+// sessionLimitsFromEnv reads SESSION_IDLE_TIMEOUT (a Go duration, default
+// 30m) and SESSION_MAX_COUNT (default 1000)
+func sessionLimitsFromEnv() (time.Duration, int, error) {
+	idleTimeout := 30 * time.Minute
+	if value := os.Getenv("SESSION_IDLE_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid SESSION_IDLE_TIMEOUT %q: %v", value, err)
+		}
+		idleTimeout = parsed
+	}
+
+	maxSessions := 1000
+	if value := os.Getenv("SESSION_MAX_COUNT"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("invalid SESSION_MAX_COUNT %q", value)
+		}
+		maxSessions = parsed
+	}
+
+	return idleTimeout, maxSessions, nil
+}
+
+// NewConversationStoreFromEnv builds the store selected by CONVERSATION_STORE.
+//
+//	CONVERSATION_STORE       unset (no persistence), memory, file, sqlite or redis
+//	CONVERSATION_STORE_PATH  directory for file, database path for sqlite
+//	REDIS_URL                redis://[:password@]host:port/db
+//
+// Redis keys expire after ttl so abandoned sessions clean themselves up.
+func NewConversationStoreFromEnv(ttl time.Duration) (interfaces.ConversationStore, error) {
+	path := os.Getenv("CONVERSATION_STORE_PATH")
+
+	switch name := strings.ToLower(os.Getenv("CONVERSATION_STORE")); name {
+	case "":
+		return nil, nil
+	case ConversationStoreMemory:
+		return services.NewMemoryConversationStore(), nil
+	case ConversationStoreFile:
+		if path == "" {
+			path = "/tmp/conversations"
+		}
+		return services.NewFileConversationStore(path)
+	case ConversationStoreSQLite:
+		if path == "" {
+			path = "/tmp/conversations.db"
+		}
+		return services.NewSQLiteConversationStore(path)
+	case ConversationStoreRedis:
+		url := os.Getenv("REDIS_URL")
+		if url == "" {
+			return nil, fmt.Errorf("REDIS_URL environment variable is not set")
+		}
+		return services.NewRedisConversationStore(url, ttl)
+	default:
+		return nil, fmt.Errorf("unknown conversation store %q", name)
+	}
+}
+
+// NewCompactionConfigFromEnv reports whether CONTEXT_COMPACTION=summarize is
+// set and reads the thresholds
+//
+//	CONTEXT_COMPACTION_TRIGGER_RATIO   share of the token budget that triggers compaction, default 0.8
+//	CONTEXT_COMPACTION_TARGET_RATIO    share the recent turns are cut down to, default 0.5
+//	CONTEXT_COMPACTION_KEEP_MESSAGES   recent messages never summarized, default 4
+func NewCompactionConfigFromEnv() (services.CompactionConfig, bool, error) {
+	config := services.DefaultCompactionConfig()
+
+	switch mode := strings.ToLower(os.Getenv("CONTEXT_COMPACTION")); mode {
+	case "", "trim":
+		return config, false, nil
+	case "summarize":
+	default:
+		return config, false, fmt.Errorf("unknown CONTEXT_COMPACTION %q", mode)
+	}
+
+	var err error
+	if config.TriggerRatio, err = envUnitInterval("CONTEXT_COMPACTION_TRIGGER_RATIO", config.TriggerRatio); err != nil {
+		return config, false, err
+	}
+	if config.TargetRatio, err = envUnitInterval("CONTEXT_COMPACTION_TARGET_RATIO", config.TargetRatio); err != nil {
+		return config, false, err
+	}
+	if config.TargetRatio >= config.TriggerRatio {
+		return config, false, fmt.Errorf("CONTEXT_COMPACTION_TARGET_RATIO must be below CONTEXT_COMPACTION_TRIGGER_RATIO")
+	}
+
+	if value := os.Getenv("CONTEXT_COMPACTION_KEEP_MESSAGES"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return config, false, fmt.Errorf("invalid CONTEXT_COMPACTION_KEEP_MESSAGES %q", value)
+		}
+		config.KeepRecentMessages = parsed
+	}
+
+	return config, true, nil
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+)
+
+const toneVoiceID = "tone"
+
+// ToneTTSProvider is an offline stand-in that renders a sine tone whose
+// length follows the length of the text. Useful for tests and local runs.
+type ToneTTSProvider struct {
+	SampleRate int
+	Frequency  float64
+	// PerRune is how long each character of text sounds, in seconds
+	PerRune float64
+}
+
+func NewToneTTSProvider() *ToneTTSProvider {
+	return &ToneTTSProvider{
+		SampleRate: 16000,
+		Frequency:  440,
+		PerRune:    0.05,
+	}
+}
+
+func (p *ToneTTSProvider) Name() string {
+	return TTSProviderTone
+}
+
+func (p *ToneTTSProvider) ContentType() string {
+	return "audio/wav"
+}
+
+func (p *ToneTTSProvider) VoiceKey(options models.SpeechOptions) string {
+	frequency, perRune := p.tone(options)
+	return fmt.Sprintf("%d|%g|%g", p.SampleRate, frequency, perRune)
+}
+
+// Voices has the one tone, which speaks any language
+func (p *ToneTTSProvider) Voices(ctx context.Context) ([]models.VoiceModel, error) {
+	return []models.VoiceModel{{ID: toneVoiceID, Name: "Sine tone"}}, nil
+}
+
+func (p *ToneTTSProvider) DefaultVoice(lang string) string {
+	return toneVoiceID
+}
+
+// Capabilities allows the speed and pitch to change, which is enough to
+// hear that settings are applied
+func (p *ToneTTSProvider) Capabilities() models.VoiceCapabilitiesModel {
+	return models.VoiceCapabilitiesModel{
+		Speed: &models.VoiceSettingRangeModel{Min: 0.5, Max: 2, Default: 1},
+		Pitch: &models.VoiceSettingRangeModel{Min: -12, Max: 12, Default: 0},
+	}
+}
+
+// tone applies the speed and pitch of options
+func (p *ToneTTSProvider) tone(options models.SpeechOptions) (frequency, perRune float64) {
+	capabilities := p.Capabilities()
+	speed := capabilities.Speed.Setting(options.Voice.Speed)
+	pitch := capabilities.Pitch.Setting(options.Voice.Pitch)
+	return p.Frequency * math.Pow(2, pitch/12), p.PerRune / speed
+}
+
+// ConvertTextToSpeech sounds the same in every language
+func (p *ToneTTSProvider) ConvertTextToSpeech(ctx context.Context, text string, options models.SpeechOptions) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	frequency, perRune := p.tone(options)
+	numSamples := int(float64(utf8.RuneCountInString(text)) * perRune * float64(p.SampleRate))
+	pcm := make([]byte, numSamples*2)
+	for i := 0; i < numSamples; i++ {
+		t := float64(i) / float64(p.SampleRate)
+		sample := int16(0.3 * math.MaxInt16 * math.Sin(2*math.Pi*frequency*t))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(sample))
+	}
+
+	return services.EncodeWAV(pcm, p.SampleRate, 1), nil
+}
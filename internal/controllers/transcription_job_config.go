@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/services"
+)
+
+const (
+	TranscriptionJobStoreMemory = "memory"
+	TranscriptionJobStoreSQLite = "sqlite"
+)
+
+// NewTranscriptionJobControllerFromEnv configures background transcription
+//
+//	TRANSCRIPTION_WORKERS          concurrent jobs, default 2
+//	TRANSCRIPTION_QUEUE_SIZE       jobs that may wait, default 100
+//	TRANSCRIPTION_SPOOL_DIR        where uploads wait, default $TMPDIR/transcriptions
+//	TRANSCRIPTION_JOB_RETENTION    how long finished jobs are kept, default 24h
+//	TRANSCRIPTION_JOB_STORE        memory (default) or sqlite
+//	TRANSCRIPTION_JOB_STORE_PATH   sqlite database, default /tmp/transcriptions.db
+//	TRANSCRIPTION_WEBHOOK_SECRET   signs webhook bodies when set
+//	TRANSCRIPTION_WEBHOOK_ALLOWED_HOSTS
+//	                               comma separated hosts webhooks may reach on
+//	                               private addresses, none by default
+func NewTranscriptionJobControllerFromEnv(registry *VoiceToTextRegistry) (*TranscriptionJobController, error) {
+	config := TranscriptionJobConfig{
+		Workers:       2,
+		QueueSize:     100,
+		SpoolDir:      envOrDefault("TRANSCRIPTION_SPOOL_DIR", filepath.Join(os.TempDir(), "transcriptions")),
+		Retention:     24 * time.Hour,
+		WebhookSecret: os.Getenv("TRANSCRIPTION_WEBHOOK_SECRET"),
+	}
+	for _, host := range strings.Split(os.Getenv("TRANSCRIPTION_WEBHOOK_ALLOWED_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			config.WebhookAllowedHosts = append(config.WebhookAllowedHosts, host)
+		}
+	}
+
+	var err error
+	if config.Workers, err = envPositiveInt("TRANSCRIPTION_WORKERS", config.Workers); err != nil {
+		return nil, err
+	}
+	if config.QueueSize, err = envPositiveInt("TRANSCRIPTION_QUEUE_SIZE", config.QueueSize); err != nil {
+		return nil, err
+	}
+
+	if value := os.Getenv("TRANSCRIPTION_JOB_RETENTION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid TRANSCRIPTION_JOB_RETENTION %q", value)
+		}
+		config.Retention = parsed
+	}
+
+	store, err := newTranscriptionJobStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTranscriptionJobController(registry, store, config)
+}
+
+func newTranscriptionJobStoreFromEnv() (interfaces.TranscriptionJobStore, error) {
+	switch name := strings.ToLower(os.Getenv("TRANSCRIPTION_JOB_STORE")); name {
+	case "", TranscriptionJobStoreMemory:
+		return services.NewMemoryTranscriptionJobStore(), nil
+	case TranscriptionJobStoreSQLite:
+		return services.NewSQLiteTranscriptionJobStore(envOrDefault("TRANSCRIPTION_JOB_STORE_PATH", "/tmp/transcriptions.db"))
+	default:
+		return nil, fmt.Errorf("unknown transcription job store %q", name)
+	}
+}
+
+func envPositiveInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrTranscriptionQueueFull is returned when more jobs are waiting than the
+// queue holds
+var ErrTranscriptionQueueFull = errors.New("transcription queue is full")
+
+const (
+	webhookAttempts = 3
+	webhookTimeout  = 10 * time.Second
+	jobPruneEvery   = time.Hour
+)
+
+// TranscriptionJobConfig sizes the worker pool and says where uploads wait
+type TranscriptionJobConfig struct {
+	Workers   int
+	QueueSize int
+	SpoolDir  string
+	// Finished jobs are deleted after Retention
+	Retention time.Duration
+	// WebhookSecret signs webhook bodies when set
+	WebhookSecret string
+	// WebhookAllowedHosts may be reached on private, loopback and link-local
+	// addresses, which webhooks are refused otherwise
+	WebhookAllowedHosts []string
+}
+
+// TranscriptionJobController runs transcriptions in the background on a
+// fixed pool of workers. Jobs and their state live in the store, uploads in
+// the spool directory until their job finishes.
+type TranscriptionJobController struct {
+	registry *VoiceToTextRegistry
+	store    interfaces.TranscriptionJobStore
+	config   TranscriptionJobConfig
+	queue    chan string
+	// slots has room for as many jobs as queue. Submit takes one before the
+	// job is saved, so a full queue refuses it with nothing stored.
+	slots chan struct{}
+	// backlog holds the jobs a previous run left unfinished. Workers take
+	// them before the queue, which stays free for new submissions.
+	backlogMu sync.Mutex
+	backlog   []string
+	webhooks  *http.Client
+}
+
+// NewTranscriptionJobController starts the workers and picks up the jobs a
+// previous run left unfinished
+func NewTranscriptionJobController(
+	registry *VoiceToTextRegistry,
+	store interfaces.TranscriptionJobStore,
+	config TranscriptionJobConfig,
+) (*TranscriptionJobController, error) {
+	if err := os.MkdirAll(config.SpoolDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %v", err)
+	}
+
+	c := &TranscriptionJobController{
+		registry: registry,
+		store:    store,
+		config:   config,
+		queue:    make(chan string, config.QueueSize),
+		slots:    make(chan struct{}, config.QueueSize),
+		webhooks: newWebhookClient(config.WebhookAllowedHosts),
+	}
+
+	// The backlog is filled before any worker looks at it
+	c.resume()
+	for i := 0; i < config.Workers; i++ {
+		go c.work()
+	}
+	go c.prune()
+
+	return c, nil
+}
+
+// Submit takes ownership of the uploaded file and queues its transcription
+func (c *TranscriptionJobController) Submit(
+	ctx context.Context,
+	audioFilePath, sttProvider, webhookURL string,
+	options models.TranscriptionOptions,
+) (models.TranscriptionJobModel, error) {
+	if _, err := c.registry.Resolve(sttProvider); err != nil {
+		return models.TranscriptionJobModel{}, err
+	}
+	if webhookURL != "" {
+		if err := checkWebhookURL(ctx, webhookURL, c.config.WebhookAllowedHosts); err != nil {
+			return models.TranscriptionJobModel{}, err
+		}
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		return models.TranscriptionJobModel{}, ErrTranscriptionQueueFull
+	}
+
+	job := models.TranscriptionJobModel{
+		ID:          uuid.NewString(),
+		Status:      models.TranscriptionJobQueued,
+		STTProvider: sttProvider,
+		WebhookURL:  webhookURL,
+		Options:     options,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	// Keep the extension, providers sniff raw PCM by it
+	job.AudioPath = filepath.Join(c.config.SpoolDir, job.ID+filepath.Ext(audioFilePath))
+	if err := moveFile(audioFilePath, job.AudioPath); err != nil {
+		<-c.slots
+		return job, fmt.Errorf("failed to spool audio: %v", err)
+	}
+
+	if err := c.store.Save(ctx, job); err != nil {
+		<-c.slots
+		os.Remove(job.AudioPath)
+		return job, err
+	}
+
+	// The slot taken above guarantees room
+	c.queue <- job.ID
+
+	return job, nil
+}
+
+func (c *TranscriptionJobController) Get(ctx context.Context, id string) (models.TranscriptionJobModel, bool, error) {
+	return c.store.Load(ctx, id)
+}
+
+func (c *TranscriptionJobController) work() {
+	for {
+		if id, ok := c.nextResumed(); ok {
+			c.run(id)
+			continue
+		}
+
+		id := <-c.queue
+		<-c.slots
+		c.run(id)
+	}
+}
+
+// nextResumed takes the oldest job left in the backlog
+func (c *TranscriptionJobController) nextResumed() (string, bool) {
+	c.backlogMu.Lock()
+	defer c.backlogMu.Unlock()
+
+	if len(c.backlog) == 0 {
+		return "", false
+	}
+	id := c.backlog[0]
+	c.backlog = c.backlog[1:]
+	return id, true
+}
+
+func (c *TranscriptionJobController) run(id string) {
+	ctx := context.Background()
+
+	job, ok, err := c.store.Load(ctx, id)
+	if err != nil || !ok {
+		log.Printf("Failed to load transcription job %s: %v", id, err)
+		return
+	}
+
+	started := time.Now().UTC()
+	job.Status = models.TranscriptionJobRunning
+	job.StartedAt = &started
+	if err := c.store.Save(ctx, job); err != nil {
+		log.Printf("Failed to save transcription job %s: %v", id, err)
+	}
+
+	provider, err := c.registry.Resolve(job.STTProvider)
+	if err != nil {
+		c.finish(&job, models.VoiceToTextModel{}, err)
+		return
+	}
+
+	result, err := TranscribeAudio(provider, job.AudioPath, job.Options)
+	c.finish(&job, result, err)
+}
+
+// finish records the outcome, drops the spooled audio and calls the webhook
+func (c *TranscriptionJobController) finish(job *models.TranscriptionJobModel, result models.VoiceToTextModel, err error) {
+	completed := time.Now().UTC()
+	job.CompletedAt = &completed
+
+	if err != nil {
+		job.Status = models.TranscriptionJobFailed
+		job.Error = err.Error()
+	} else {
+		// The spool path means nothing to the client
+		result.AudioFilePath = ""
+		job.Status = models.TranscriptionJobSucceeded
+		job.Result = &result
+	}
+
+	if removeErr := os.Remove(job.AudioPath); removeErr != nil && !os.IsNotExist(removeErr) {
+		log.Printf("Failed to remove spooled audio: %v", removeErr)
+	}
+	job.AudioPath = ""
+
+	if err := c.store.Save(context.Background(), *job); err != nil {
+		log.Printf("Failed to save transcription job %s: %v", job.ID, err)
+	}
+
+	if job.WebhookURL != "" {
+		go c.notify(*job)
+	}
+}
+
+// notify posts the finished job to its webhook, retrying failures with
+// backoff. With a secret the body is signed in X-Signature-SHA256 as the
+// hex HMAC-SHA256 of the body.
+func (c *TranscriptionJobController) notify(job models.TranscriptionJobModel) {
+	body, err := json.Marshal(job)
+	if err != nil {
+		log.Printf("Failed to encode webhook for job %s: %v", job.ID, err)
+		return
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = c.postWebhook(job.WebhookURL, body)
+		if err == nil {
+			return
+		}
+		if attempt < webhookAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	log.Printf("Failed to deliver webhook for job %s: %v", job.ID, err)
+}
+
+func (c *TranscriptionJobController) postWebhook(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.config.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(c.config.WebhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-SHA256", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := c.webhooks.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// resume puts the jobs that were waiting or running when the process
+// stopped in the backlog. Running ones start over.
+func (c *TranscriptionJobController) resume() {
+	jobs, err := c.store.ListUnfinished(context.Background())
+	if err != nil {
+		log.Printf("Failed to resume transcription jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if _, err := os.Stat(job.AudioPath); err != nil {
+			c.finish(&job, models.VoiceToTextModel{}, fmt.Errorf("audio was lost before the job ran"))
+			continue
+		}
+		c.backlog = append(c.backlog, job.ID)
+	}
+}
+
+// prune deletes finished jobs once they are older than the retention
+func (c *TranscriptionJobController) prune() {
+	ticker := time.NewTicker(jobPruneEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-c.config.Retention)
+		if err := c.store.DeleteFinishedBefore(context.Background(), cutoff); err != nil {
+			log.Printf("Failed to prune transcription jobs: %v", err)
+		}
+	}
+}
+
+// moveFile renames, falling back to a copy when the spool is on another
+// filesystem
+func moveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := target.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+
+	return os.Remove(from)
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+)
+
+func newTestJobController(t *testing.T, store interfaces.TranscriptionJobStore, config TranscriptionJobConfig) *TranscriptionJobController {
+	t.Helper()
+
+	registry := NewVoiceToTextRegistry(VoiceToTextProviderFake)
+	registry.Register(VoiceToTextProviderFake, NewFakeVoiceToTextController("turn on the lights"))
+
+	if config.SpoolDir == "" {
+		config.SpoolDir = t.TempDir()
+	}
+	controller, err := NewTranscriptionJobController(registry, store, config)
+	if err != nil {
+		t.Fatalf("NewTranscriptionJobController: %v", err)
+	}
+	return controller
+}
+
+// testUpload writes a short silent WAV where uploads are saved
+func testUpload(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "upload.wav")
+	if err := os.WriteFile(path, services.EncodeWAV(make([]byte, 3200), 16000, 1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// waitForJob polls until the job has finished
+func waitForJob(t *testing.T, controller *TranscriptionJobController, id string) models.TranscriptionJobModel {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok, err := controller.Get(context.Background(), id)
+		if err != nil || !ok {
+			t.Fatalf("Get(%s) = %v, %v", id, ok, err)
+		}
+		if job.CompletedAt != nil {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return models.TranscriptionJobModel{}
+}
+
+func TestSubmitRefusedWhenQueueFull(t *testing.T) {
+	webhookCalls := make(chan struct{}, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhookCalls <- struct{}{}
+	}))
+	defer webhook.Close()
+	webhookURL, _ := url.Parse(webhook.URL)
+
+	store := services.NewMemoryTranscriptionJobStore()
+	// No workers, so the one queued job keeps its place
+	controller := newTestJobController(t, store, TranscriptionJobConfig{
+		QueueSize:           1,
+		WebhookAllowedHosts: []string{webhookURL.Hostname()},
+	})
+	ctx := context.Background()
+
+	if _, err := controller.Submit(ctx, testUpload(t), VoiceToTextProviderFake, webhook.URL, models.TranscriptionOptions{}); err != nil {
+		t.Fatalf("first Submit: %v", err)
+	}
+
+	upload := testUpload(t)
+	if _, err := controller.Submit(ctx, upload, VoiceToTextProviderFake, webhook.URL, models.TranscriptionOptions{}); !errors.Is(err, ErrTranscriptionQueueFull) {
+		t.Fatalf("second Submit error = %v, want ErrTranscriptionQueueFull", err)
+	}
+
+	jobs, err := store.ListUnfinished(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("store holds %d jobs, want only the accepted one", len(jobs))
+	}
+	if _, err := os.Stat(upload); err != nil {
+		t.Errorf("refused upload was taken: %v", err)
+	}
+	select {
+	case <-webhookCalls:
+		t.Error("webhook called for a refused submission")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestResumeDoesNotTakeQueueRoom(t *testing.T) {
+	store := services.NewMemoryTranscriptionJobStore()
+	spool := t.TempDir()
+	ctx := context.Background()
+
+	// More unfinished jobs than the queue holds
+	var resumed []string
+	for i := 0; i < 3; i++ {
+		job := models.TranscriptionJobModel{
+			ID:          "resumed-" + string(rune('a'+i)),
+			Status:      models.TranscriptionJobQueued,
+			STTProvider: VoiceToTextProviderFake,
+			CreatedAt:   time.Now().UTC(),
+		}
+		job.AudioPath = filepath.Join(spool, job.ID+".wav")
+		if err := os.WriteFile(job.AudioPath, services.EncodeWAV(make([]byte, 3200), 16000, 1), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Save(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		resumed = append(resumed, job.ID)
+	}
+
+	controller := newTestJobController(t, store, TranscriptionJobConfig{Workers: 1, QueueSize: 1, SpoolDir: spool})
+
+	job, err := controller.Submit(ctx, testUpload(t), VoiceToTextProviderFake, "", models.TranscriptionOptions{})
+	if err != nil {
+		t.Fatalf("Submit while resuming: %v", err)
+	}
+
+	for _, id := range append(resumed, job.ID) {
+		if finished := waitForJob(t, controller, id); finished.Status != models.TranscriptionJobSucceeded {
+			t.Errorf("job %s %s: %s", id, finished.Status, finished.Error)
+		}
+	}
+}
+
+func TestWebhookPrivateDestinations(t *testing.T) {
+	ctx := context.Background()
+	for _, webhookURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/hook",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"ftp://example.com/hook",
+	} {
+		if err := checkWebhookURL(ctx, webhookURL, nil); !errors.Is(err, ErrWebhookNotAllowed) {
+			t.Errorf("checkWebhookURL(%q) = %v, want ErrWebhookNotAllowed", webhookURL, err)
+		}
+	}
+
+	for _, webhookURL := range []string{"http://8.8.8.8/hook", "https://[2001:4860:4860::8888]/hook"} {
+		if err := checkWebhookURL(ctx, webhookURL, nil); err != nil {
+			t.Errorf("checkWebhookURL(%q) = %v, want it allowed", webhookURL, err)
+		}
+	}
+	if err := checkWebhookURL(ctx, "http://127.0.0.1:8080/hook", []string{"127.0.0.1"}); err != nil {
+		t.Errorf("allowed host refused: %v", err)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	delivered := make(chan models.TranscriptionJobModel, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var job models.TranscriptionJobModel
+		json.NewDecoder(r.Body).Decode(&job)
+		delivered <- job
+	}))
+	defer webhook.Close()
+	webhookURL, _ := url.Parse(webhook.URL)
+
+	// The client refuses the loopback server unless it is allowed
+	if err := (&TranscriptionJobController{webhooks: newWebhookClient(nil)}).postWebhook(webhook.URL, []byte("{}")); !errors.Is(err, ErrWebhookNotAllowed) {
+		t.Fatalf("post to loopback = %v, want ErrWebhookNotAllowed", err)
+	}
+
+	controller := newTestJobController(t, services.NewMemoryTranscriptionJobStore(), TranscriptionJobConfig{
+		Workers:             1,
+		QueueSize:           1,
+		WebhookAllowedHosts: []string{webhookURL.Hostname()},
+	})
+	job, err := controller.Submit(context.Background(), testUpload(t), VoiceToTextProviderFake, webhook.URL, models.TranscriptionOptions{})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	select {
+	case got := <-delivered:
+		if got.ID != job.ID || got.Status != models.TranscriptionJobSucceeded {
+			t.Errorf("webhook got job %s %s, want %s succeeded", got.ID, got.Status, job.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+}
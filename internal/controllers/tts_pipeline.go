@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// TTSPipeline synthesizes sentences concurrently, with at most parallelism
+// requests in flight, and hands the audio back in sentence order
+type TTSPipeline struct {
+	provider    interfaces.TTSProvider
+	parallelism int
+	cache       *services.TTSCache
+
+	// inFlight holds the syntheses under way, by cache key, so concurrent
+	// requests for the same sentence share one
+	inFlight   map[string]*pendingSpeech
+	inFlightMu sync.Mutex
+}
+
+func NewTTSPipeline(provider interfaces.TTSProvider, parallelism int) *TTSPipeline {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return &TTSPipeline{
+		provider:    provider,
+		parallelism: parallelism,
+		inFlight:    make(map[string]*pendingSpeech),
+	}
+}
+
+func (p *TTSPipeline) Provider() interfaces.TTSProvider {
+	return p.provider
+}
+
+// EnableCache reuses the audio of sentences spoken before, when the
+// provider is cacheable
+func (p *TTSPipeline) EnableCache(cache *services.TTSCache) {
+	p.cache = cache
+}
+
+// CacheStats reports on the cache, Enabled false when there is none
+func (p *TTSPipeline) CacheStats() models.TTSCacheStatsModel {
+	if p.cache == nil {
+		return models.TTSCacheStatsModel{}
+	}
+	return p.cache.Stats()
+}
+
+// speak synthesizes one sentence, through the cache when there is one. The
+// cache is keyed on the normalized text, which is also what is spoken, and
+// on the provider, voice and settings. A sentence already being synthesized
+// for another request is waited for rather than requested again.
+func (p *TTSPipeline) speak(ctx context.Context, text string, options models.SpeechOptions) ([]byte, error) {
+	cacheable, ok := p.provider.(interfaces.CacheableTTSProvider)
+	if p.cache == nil || !ok {
+		return p.provider.ConvertTextToSpeech(ctx, text, options)
+	}
+
+	text = normalizeSpeechText(text)
+	key := ttsCacheKey(cacheable, text, options)
+	for {
+		if audio, ok := p.cache.Get(key); ok {
+			return audio, nil
+		}
+
+		p.inFlightMu.Lock()
+		pending, waiting := p.inFlight[key]
+		if !waiting {
+			pending = &pendingSpeech{done: make(chan struct{})}
+			p.inFlight[key] = pending
+		}
+		p.inFlightMu.Unlock()
+
+		if !waiting {
+			return p.synthesize(ctx, key, text, options, pending)
+		}
+
+		select {
+		case <-pending.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		// A synthesis cancelled along with its own request says nothing
+		// about this one, which tries again
+		if pending.err == nil || (!errors.Is(pending.err, context.Canceled) && !errors.Is(pending.err, context.DeadlineExceeded)) {
+			return pending.audio, pending.err
+		}
+	}
+}
+
+// synthesize calls the provider for the in-flight entry pending, caches the
+// audio and hands it to whoever waits for key
+func (p *TTSPipeline) synthesize(ctx context.Context, key, text string, options models.SpeechOptions, pending *pendingSpeech) ([]byte, error) {
+	defer func() {
+		p.inFlightMu.Lock()
+		delete(p.inFlight, key)
+		p.inFlightMu.Unlock()
+		close(pending.done)
+	}()
+
+	pending.audio, pending.err = p.provider.ConvertTextToSpeech(ctx, text, options)
+	if pending.err == nil {
+		p.cache.Put(key, pending.audio)
+	} else if ctx.Err() != nil {
+		// Providers do not all wrap the context's error
+		pending.err = ctx.Err()
+	}
+	return pending.audio, pending.err
+}
+
+// normalizeSpeechText composes Unicode and collapses whitespace, neither of
+// which changes how text sounds
+func normalizeSpeechText(text string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(text)), " ")
+}
+
+// ttsCacheKey hashes everything the audio depends on
+func ttsCacheKey(provider interfaces.CacheableTTSProvider, text string, options models.SpeechOptions) string {
+	hash := sha256.New()
+	for _, part := range []string{provider.Name(), provider.ContentType(), provider.VoiceKey(options), text} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type pendingSpeech struct {
+	done  chan struct{}
+	audio []byte
+	err   error
+}
+
+// Synthesize reads sentences until the channel is closed and calls emit
+// with each sentence's audio, in order, as soon as it and all earlier
+// sentences are ready. It stops at the first error.
+func (p *TTSPipeline) Synthesize(ctx context.Context, sentences <-chan string, options models.SpeechOptions, emit func(audio []byte) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan *pendingSpeech, p.parallelism)
+	slots := make(chan struct{}, p.parallelism)
+	var workers sync.WaitGroup
+
+	go func() {
+		defer close(queue)
+		for {
+			var sentence string
+			select {
+			case next, ok := <-sentences:
+				if !ok {
+					return
+				}
+				sentence = next
+			case <-ctx.Done():
+				return
+			}
+
+			job := &pendingSpeech{done: make(chan struct{})}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			queue <- job
+
+			workers.Add(1)
+			go func(sentence string) {
+				defer workers.Done()
+				defer close(job.done)
+				defer func() { <-slots }()
+				job.audio, job.err = p.speak(ctx, sentence, options)
+			}(sentence)
+		}
+	}()
+
+	var firstErr error
+	for job := range queue {
+		<-job.done
+		if firstErr != nil {
+			continue
+		}
+		if job.err != nil {
+			firstErr = fmt.Errorf("failed to convert text to speech: %v", job.err)
+			cancel()
+			continue
+		}
+		if err := emit(job.audio); err != nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	workers.Wait()
+
+	return firstErr
+}
+
+// Speak synthesizes a single piece of text
+func (p *TTSPipeline) Speak(ctx context.Context, text string, options models.SpeechOptions, emit func(audio []byte) error) error {
+	sentences := make(chan string, 1)
+	sentences <- text
+	close(sentences)
+	return p.Synthesize(ctx, sentences, options, emit)
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+)
+
+// blockingTTSProvider is the tone provider, holding each synthesis until
+// release is closed and counting the calls
+type blockingTTSProvider struct {
+	*ToneTTSProvider
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingTTSProvider() *blockingTTSProvider {
+	return &blockingTTSProvider{
+		ToneTTSProvider: NewToneTTSProvider(),
+		started:         make(chan struct{}, 10),
+		release:         make(chan struct{}),
+	}
+}
+
+func (p *blockingTTSProvider) ConvertTextToSpeech(ctx context.Context, text string, options models.SpeechOptions) ([]byte, error) {
+	p.calls.Add(1)
+	p.started <- struct{}{}
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		return nil, errors.New("request aborted")
+	}
+	return p.ToneTTSProvider.ConvertTextToSpeech(ctx, text, options)
+}
+
+func newCachedTestPipeline(t *testing.T, provider *blockingTTSProvider) *TTSPipeline {
+	t.Helper()
+	cache, err := services.NewTTSCache(services.TTSCacheConfig{MemoryBytes: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline := NewTTSPipeline(provider, 2)
+	pipeline.EnableCache(cache)
+	return pipeline
+}
+
+func TestSpeakSharesInFlightSynthesis(t *testing.T) {
+	provider := newBlockingTTSProvider()
+	pipeline := newCachedTestPipeline(t, provider)
+
+	const requests = 5
+	results := make([][]byte, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Differently spaced, the same once normalized
+			text := "Hello  there."
+			if i%2 == 0 {
+				text = "Hello there. "
+			}
+			audio, err := pipeline.speak(context.Background(), text, models.SpeechOptions{})
+			if err != nil {
+				t.Errorf("speak: %v", err)
+			}
+			results[i] = audio
+		}(i)
+	}
+
+	<-provider.started
+	// Give the others time to find the synthesis under way
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("provider called %d times, want 1", calls)
+	}
+	for i, audio := range results {
+		if len(audio) == 0 || !bytes.Equal(audio, results[0]) {
+			t.Errorf("request %d got different audio", i)
+		}
+	}
+	if stats := pipeline.CacheStats(); stats.Memory.Entries != 1 {
+		t.Errorf("cache holds %d entries, want 1", stats.Memory.Entries)
+	}
+}
+
+func TestSpeakRetriesAfterCancelledSynthesis(t *testing.T) {
+	provider := newBlockingTTSProvider()
+	pipeline := newCachedTestPipeline(t, provider)
+
+	// The first request goes away while its synthesis is still running
+	first, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := pipeline.speak(first, "Goodbye.", models.SpeechOptions{})
+		firstDone <- err
+	}()
+	<-provider.started
+
+	secondDone := make(chan error, 1)
+	go func() {
+		audio, err := pipeline.speak(context.Background(), "Goodbye.", models.SpeechOptions{})
+		if err == nil && len(audio) == 0 {
+			err = errors.New("no audio")
+		}
+		secondDone <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled request error = %v, want context.Canceled", err)
+	}
+
+	// The waiting request synthesizes the sentence itself
+	<-provider.started
+	close(provider.release)
+	if err := <-secondDone; err != nil {
+		t.Errorf("waiting request failed: %v", err)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("provider called %d times, want 2", calls)
+	}
+}
+
+// echoTTSProvider returns the text as its audio, taking longer for earlier
+// sentences so they finish last, and records how many run at once
+type echoTTSProvider struct {
+	*ToneTTSProvider
+	delays map[string]time.Duration
+
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (p *echoTTSProvider) ConvertTextToSpeech(ctx context.Context, text string, options models.SpeechOptions) ([]byte, error) {
+	p.mu.Lock()
+	p.running++
+	if p.running > p.peak {
+		p.peak = p.running
+	}
+	p.mu.Unlock()
+
+	time.Sleep(p.delays[text])
+
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
+	return []byte(text), nil
+}
+
+func TestSynthesizeKeepsSentenceOrder(t *testing.T) {
+	sentences := []string{"One.", "Two.", "Three.", "Four.", "Five."}
+	provider := &echoTTSProvider{ToneTTSProvider: NewToneTTSProvider(), delays: map[string]time.Duration{}}
+	for i, sentence := range sentences {
+		provider.delays[sentence] = time.Duration(len(sentences)-i) * 20 * time.Millisecond
+	}
+	pipeline := NewTTSPipeline(provider, 3)
+
+	queue := make(chan string, len(sentences))
+	for _, sentence := range sentences {
+		queue <- sentence
+	}
+	close(queue)
+
+	var got []string
+	err := pipeline.Synthesize(context.Background(), queue, models.SpeechOptions{}, func(audio []byte) error {
+		got = append(got, string(audio))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	if !reflect.DeepEqual(got, sentences) {
+		t.Errorf("clips came out as %q, want %q", got, sentences)
+	}
+	if peak := provider.peak; peak < 2 || peak > 3 {
+		t.Errorf("%d syntheses ran at once, want up to the parallelism of 3", peak)
+	}
+}
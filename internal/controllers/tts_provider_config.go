@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/services"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	TTSProviderElevenLabs = "elevenlabs"
+	TTSProviderCoqui      = "coqui"
+	TTSProviderTone       = "tone"
+
+	defaultCoquiBaseURL      = "https://coqui-service-75777829797.us-central1.run.app"
+	defaultElevenLabsBaseURL = "https://api.elevenlabs.io"
+	defaultElevenLabsVoiceID = "21m00Tcm4TlvDq8ikWAM"
+	defaultTTSParallelism    = 3
+	defaultTTSCacheMemoryMB  = 64
+	defaultTTSCacheDiskMB    = 1024
+)
+
+// NewTTSProviderFromEnv builds the provider selected by TTS_PROVIDER.
+//
+//	TTS_PROVIDER                  coqui (default), elevenlabs or tone
+//	COQUI_BASE_URL                Coqui FastAPI service
+//	ELEVEN_LABS_API_KEY           Eleven Labs API key
+//	ELEVEN_LABS_BASE_URL          defaults to https://api.elevenlabs.io
+//	ELEVEN_LABS_VOICE_ID          defaults to the "Rachel" voice
+//	ELEVEN_LABS_VOICES            voices by language, e.g. es=<voice id>,fr-CA=<voice id>
+//	ELEVEN_LABS_MODEL_ID          optional model, e.g. eleven_multilingual_v2
+//	ELEVEN_LABS_STABILITY         0..1, defaults to 0.5
+//	ELEVEN_LABS_SIMILARITY_BOOST  0..1, defaults to 0.5
+//	ELEVEN_LABS_SPEED             0.7..1.2, defaults to 1
+//
+// The voice and settings are defaults; requests and sessions may choose
+// others from the catalog.
+func NewTTSProviderFromEnv() (interfaces.TTSProvider, error) {
+	switch name := strings.ToLower(os.Getenv("TTS_PROVIDER")); name {
+	case "", TTSProviderCoqui:
+		return NewCoquiTTSProvider(envOrDefault("COQUI_BASE_URL", defaultCoquiBaseURL)), nil
+	case TTSProviderElevenLabs:
+		apiKey := os.Getenv("ELEVEN_LABS_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("ELEVEN_LABS_API_KEY environment variable is not set")
+		}
+
+		stability, err := envUnitInterval("ELEVEN_LABS_STABILITY", 0.5)
+		if err != nil {
+			return nil, err
+		}
+		similarityBoost, err := envUnitInterval("ELEVEN_LABS_SIMILARITY_BOOST", 0.5)
+		if err != nil {
+			return nil, err
+		}
+		speed, err := envFloatRange("ELEVEN_LABS_SPEED", 1, minElevenLabsSpeed, maxElevenLabsSpeed)
+		if err != nil {
+			return nil, err
+		}
+
+		voices, err := envLanguageMap("ELEVEN_LABS_VOICES")
+		if err != nil {
+			return nil, err
+		}
+
+		provider := NewElevenLabsTTSProvider(
+			envOrDefault("ELEVEN_LABS_BASE_URL", defaultElevenLabsBaseURL),
+			apiKey,
+			envOrDefault("ELEVEN_LABS_VOICE_ID", defaultElevenLabsVoiceID),
+			os.Getenv("ELEVEN_LABS_MODEL_ID"),
+			ElevenLabsVoiceSettings{
+				Stability:       stability,
+				SimilarityBoost: similarityBoost,
+				Speed:           speed,
+			},
+		)
+		provider.LanguageVoices = voices
+		return provider, nil
+	case TTSProviderTone:
+		return NewToneTTSProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown TTS provider %q", name)
+	}
+}
+
+// NewTTSPipelineFromEnv wraps provider in a pipeline that synthesizes up to
+// TTS_PARALLELISM sentences at once (default 3), caching the audio
+//
+//	TTS_CACHE_MEMORY_MB  memory tier, default 64, 0 turns it off
+//	TTS_CACHE_DIR        disk tier directory, no disk tier when unset
+//	TTS_CACHE_DISK_MB    disk tier size, default 1024
+func NewTTSPipelineFromEnv(provider interfaces.TTSProvider) (*TTSPipeline, error) {
+	parallelism := defaultTTSParallelism
+	if value := os.Getenv("TTS_PARALLELISM"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid TTS_PARALLELISM %q", value)
+		}
+		parallelism = parsed
+	}
+
+	pipeline := NewTTSPipeline(provider, parallelism)
+
+	memoryMB, err := envMegabytes("TTS_CACHE_MEMORY_MB", defaultTTSCacheMemoryMB)
+	if err != nil {
+		return nil, err
+	}
+	diskMB, err := envMegabytes("TTS_CACHE_DISK_MB", defaultTTSCacheDiskMB)
+	if err != nil {
+		return nil, err
+	}
+	config := services.TTSCacheConfig{
+		MemoryBytes: memoryMB << 20,
+		DiskDir:     os.Getenv("TTS_CACHE_DIR"),
+		DiskBytes:   diskMB << 20,
+	}
+	if config.MemoryBytes > 0 || config.DiskDir != "" {
+		cache, err := services.NewTTSCache(config)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.EnableCache(cache)
+	}
+
+	return pipeline, nil
+}
+
+// envMegabytes parses a size in megabytes, which may be 0
+func envMegabytes(key string, fallback int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return parsed, nil
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envUnitInterval parses a float in [0, 1] from the environment
+func envUnitInterval(key string, fallback float64) (float64, error) {
+	return envFloatRange(key, fallback, 0, 1)
+}
+
+// envFloatRange parses a float in [min, max] from the environment
+func envFloatRange(key string, fallback, min, max float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < min || parsed > max {
+		return 0, fmt.Errorf("invalid %s %q: must be between %g and %g", key, value, min, max)
+	}
+
+	return parsed, nil
+}
+
+// envLanguageMap parses a comma separated list of language=value pairs,
+// keyed by canonical BCP 47 tag
+func envLanguageMap(key string) (map[string]string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	entries := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		tag, mapped, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		parsed, err := language.Parse(strings.TrimSpace(tag))
+		if !ok || err != nil || strings.TrimSpace(mapped) == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: want language=value", key, pair)
+		}
+		entries[parsed.String()] = strings.TrimSpace(mapped)
+	}
+
+	return entries, nil
+}
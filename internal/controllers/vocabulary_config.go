@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/services"
+)
+
+const (
+	VocabularyStoreMemory = "memory"
+	VocabularyStoreSQLite = "sqlite"
+)
+
+// NewVocabularyControllerFromEnv configures where vocabularies are kept and
+// who may change them
+//
+//	VOCABULARY_STORE        memory (default) or sqlite
+//	VOCABULARY_STORE_PATH   sqlite database, default /tmp/vocabularies.db
+//	TENANT_HEADER           the only header naming the tenant, set by the
+//	                        authenticating proxy; unset, X-Tenant-ID or the
+//	                        tenant_id query parameter name it
+//	VOCABULARY_WRITE_TOKEN  bearer token PUT and DELETE /v1/vocabulary need
+func NewVocabularyControllerFromEnv() (*VocabularyController, error) {
+	store, err := newVocabularyStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	controller := NewVocabularyController(store)
+	controller.TenantHeader = os.Getenv("TENANT_HEADER")
+	controller.WriteToken = os.Getenv("VOCABULARY_WRITE_TOKEN")
+	return controller, nil
+}
+
+func newVocabularyStoreFromEnv() (interfaces.VocabularyStore, error) {
+	switch name := strings.ToLower(os.Getenv("VOCABULARY_STORE")); name {
+	case "", VocabularyStoreMemory:
+		return services.NewMemoryVocabularyStore(), nil
+	case VocabularyStoreSQLite:
+		return services.NewSQLiteVocabularyStore(envOrDefault("VOCABULARY_STORE_PATH", "/tmp/vocabularies.db"))
+	default:
+		return nil, fmt.Errorf("unknown vocabulary store %q", name)
+	}
+}
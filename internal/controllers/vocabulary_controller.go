@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+)
+
+// ErrInvalidVocabulary is returned for vocabularies recognizers would reject
+var ErrInvalidVocabulary = errors.New("invalid vocabulary")
+
+// Limits of Google speech adaptation, which the other providers live with
+const (
+	maxVocabularyPhrases      = 5000
+	maxPhraseLength           = 100
+	maxPhraseBoost            = 20
+	maxVocabularyReplacements = 1000
+)
+
+// DefaultTenantID owns the vocabulary of requests that name no tenant
+const DefaultTenantID = "default"
+
+// VocabularyController manages the custom vocabulary of each tenant
+type VocabularyController struct {
+	store interfaces.VocabularyStore
+	// TenantHeader, when set, is the only header that names the tenant. It
+	// is meant for a proxy that authenticates callers and sets it.
+	TenantHeader string
+	// WriteToken, when set, is the bearer token changes need
+	WriteToken string
+}
+
+func NewVocabularyController(store interfaces.VocabularyStore) *VocabularyController {
+	return &VocabularyController{store: store}
+}
+
+// Get returns the tenant's vocabulary, empty when it has none
+func (c *VocabularyController) Get(ctx context.Context, tenantID string) (models.VocabularyModel, error) {
+	vocabulary, ok, err := c.store.Load(ctx, tenantID)
+	if err != nil {
+		return vocabulary, err
+	}
+	if !ok {
+		vocabulary = models.VocabularyModel{TenantID: tenantID}
+	}
+	if vocabulary.Phrases == nil {
+		vocabulary.Phrases = []models.PhraseHintModel{}
+	}
+	if vocabulary.Replacements == nil {
+		vocabulary.Replacements = []models.ReplacementModel{}
+	}
+	return vocabulary, nil
+}
+
+// ForTranscription returns the tenant's vocabulary for TranscriptionOptions,
+// nil when it has none
+func (c *VocabularyController) ForTranscription(ctx context.Context, tenantID string) (*models.VocabularyModel, error) {
+	vocabulary, ok, err := c.store.Load(ctx, tenantID)
+	if err != nil || !ok || vocabulary.Empty() {
+		return nil, err
+	}
+	return &vocabulary, nil
+}
+
+// Put validates the vocabulary and replaces the tenant's with it. Phrases
+// and replacement sources are trimmed; duplicate phrases keep the highest
+// boost.
+func (c *VocabularyController) Put(ctx context.Context, tenantID string, vocabulary models.VocabularyModel) (models.VocabularyModel, error) {
+	if len(vocabulary.Phrases) > maxVocabularyPhrases {
+		return vocabulary, fmt.Errorf("%w: at most %d phrases", ErrInvalidVocabulary, maxVocabularyPhrases)
+	}
+	if len(vocabulary.Replacements) > maxVocabularyReplacements {
+		return vocabulary, fmt.Errorf("%w: at most %d replacements", ErrInvalidVocabulary, maxVocabularyReplacements)
+	}
+
+	phrases := make([]models.PhraseHintModel, 0, len(vocabulary.Phrases))
+	seen := make(map[string]int)
+	for _, hint := range vocabulary.Phrases {
+		hint.Phrase = strings.TrimSpace(hint.Phrase)
+		if hint.Phrase == "" || utf8.RuneCountInString(hint.Phrase) > maxPhraseLength {
+			return vocabulary, fmt.Errorf("%w: phrases must have 1 to %d characters", ErrInvalidVocabulary, maxPhraseLength)
+		}
+		if hint.Boost < 0 || hint.Boost > maxPhraseBoost {
+			return vocabulary, fmt.Errorf("%w: boost of %q must be between 0 and %d", ErrInvalidVocabulary, hint.Phrase, maxPhraseBoost)
+		}
+
+		key := strings.ToLower(hint.Phrase)
+		if index, ok := seen[key]; ok {
+			if hint.Boost > phrases[index].Boost {
+				phrases[index].Boost = hint.Boost
+			}
+			continue
+		}
+		seen[key] = len(phrases)
+		phrases = append(phrases, hint)
+	}
+
+	replacements := make([]models.ReplacementModel, 0, len(vocabulary.Replacements))
+	for _, replacement := range vocabulary.Replacements {
+		replacement.From = strings.TrimSpace(replacement.From)
+		if replacement.From == "" || utf8.RuneCountInString(replacement.From) > maxPhraseLength {
+			return vocabulary, fmt.Errorf("%w: replacements must match 1 to %d characters", ErrInvalidVocabulary, maxPhraseLength)
+		}
+		replacements = append(replacements, replacement)
+	}
+
+	vocabulary = models.VocabularyModel{
+		TenantID:     tenantID,
+		Phrases:      phrases,
+		Replacements: replacements,
+		UpdatedAt:    time.Now().UTC(),
+	}
+	if err := c.store.Save(ctx, vocabulary); err != nil {
+		return vocabulary, err
+	}
+	return vocabulary, nil
+}
+
+// CanWrite tells whether the Authorization header allows changing
+// vocabularies
+func (c *VocabularyController) CanWrite(authorization string) bool {
+	if c.WriteToken == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(c.WriteToken)) == 1
+}
+
+func (c *VocabularyController) Delete(ctx context.Context, tenantID string) error {
+	return c.store.Delete(ctx, tenantID)
+}
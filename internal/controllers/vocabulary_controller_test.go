@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+)
+
+func TestVocabularyPutMergesDuplicates(t *testing.T) {
+	controller := NewVocabularyController(services.NewMemoryVocabularyStore())
+	ctx := context.Background()
+
+	saved, err := controller.Put(ctx, "acme", models.VocabularyModel{
+		TenantID: "someone else",
+		Phrases: []models.PhraseHintModel{
+			{Phrase: " Acme Cloud ", Boost: 5},
+			{Phrase: "kubectl"},
+			{Phrase: "acme cloud", Boost: 15},
+			{Phrase: "ACME CLOUD", Boost: 10},
+		},
+		Replacements: []models.ReplacementModel{{From: "  acne cloud ", To: "Acme Cloud"}},
+	})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	// The first spelling stays, with the highest boost
+	wantPhrases := []models.PhraseHintModel{{Phrase: "Acme Cloud", Boost: 15}, {Phrase: "kubectl"}}
+	if !reflect.DeepEqual(saved.Phrases, wantPhrases) {
+		t.Errorf("phrases = %+v, want %+v", saved.Phrases, wantPhrases)
+	}
+	if saved.Replacements[0].From != "acne cloud" {
+		t.Errorf("replacement source = %q, want it trimmed", saved.Replacements[0].From)
+	}
+	if saved.TenantID != "acme" || saved.UpdatedAt.IsZero() {
+		t.Errorf("saved tenant %q at %v, want acme and a time", saved.TenantID, saved.UpdatedAt)
+	}
+
+	loaded, err := controller.Get(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Phrases, wantPhrases) {
+		t.Errorf("stored phrases = %+v, want %+v", loaded.Phrases, wantPhrases)
+	}
+	if other, _ := controller.ForTranscription(ctx, "other"); other != nil {
+		t.Errorf("other tenant has vocabulary %+v", other)
+	}
+}
+
+func TestVocabularyPutLimits(t *testing.T) {
+	phrases := func(n int) []models.PhraseHintModel {
+		hints := make([]models.PhraseHintModel, n)
+		for i := range hints {
+			hints[i].Phrase = fmt.Sprintf("phrase %d", i)
+		}
+		return hints
+	}
+	replacements := func(n int) []models.ReplacementModel {
+		return make([]models.ReplacementModel, n)
+	}
+
+	tests := []struct {
+		name       string
+		vocabulary models.VocabularyModel
+		wantErr    bool
+	}{
+		{name: "at the phrase limit", vocabulary: models.VocabularyModel{Phrases: phrases(maxVocabularyPhrases)}},
+		{name: "too many phrases", vocabulary: models.VocabularyModel{Phrases: phrases(maxVocabularyPhrases + 1)}, wantErr: true},
+		{name: "blank phrase", vocabulary: models.VocabularyModel{Phrases: []models.PhraseHintModel{{Phrase: "  "}}}, wantErr: true},
+		{name: "longest phrase", vocabulary: models.VocabularyModel{Phrases: []models.PhraseHintModel{{Phrase: strings.Repeat("é", maxPhraseLength)}}}},
+		{name: "phrase too long", vocabulary: models.VocabularyModel{Phrases: []models.PhraseHintModel{{Phrase: strings.Repeat("é", maxPhraseLength+1)}}}, wantErr: true},
+		{name: "highest boost", vocabulary: models.VocabularyModel{Phrases: []models.PhraseHintModel{{Phrase: "a", Boost: maxPhraseBoost}}}},
+		{name: "boost too high", vocabulary: models.VocabularyModel{Phrases: []models.PhraseHintModel{{Phrase: "a", Boost: maxPhraseBoost + 1}}}, wantErr: true},
+		{name: "negative boost", vocabulary: models.VocabularyModel{Phrases: []models.PhraseHintModel{{Phrase: "a", Boost: -1}}}, wantErr: true},
+		{name: "too many replacements", vocabulary: models.VocabularyModel{Replacements: replacements(maxVocabularyReplacements + 1)}, wantErr: true},
+		{name: "blank replacement source", vocabulary: models.VocabularyModel{Replacements: []models.ReplacementModel{{From: " ", To: "x"}}}, wantErr: true},
+		{name: "replacement to nothing", vocabulary: models.VocabularyModel{Replacements: []models.ReplacementModel{{From: "um"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NewVocabularyController(services.NewMemoryVocabularyStore())
+			_, err := controller.Put(context.Background(), "acme", tt.vocabulary)
+			if tt.wantErr != errors.Is(err, ErrInvalidVocabulary) {
+				t.Fatalf("Put error = %v, want invalid %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if vocabulary, _ := controller.ForTranscription(context.Background(), "acme"); vocabulary != nil {
+					t.Error("rejected vocabulary was stored")
+				}
+			}
+		})
+	}
+}
+
+func TestVocabularyCanWrite(t *testing.T) {
+	controller := NewVocabularyController(services.NewMemoryVocabularyStore())
+	if !controller.CanWrite("") {
+		t.Error("writes refused without a write token configured")
+	}
+
+	controller.WriteToken = "s3cret"
+	for authorization, want := range map[string]bool{
+		"Bearer s3cret":  true,
+		"":               false,
+		"s3cret":         false,
+		"Bearer s3cret2": false,
+		"Bearer ":        false,
+		"Basic s3cret":   false,
+	} {
+		if got := controller.CanWrite(authorization); got != want {
+			t.Errorf("CanWrite(%q) = %v, want %v", authorization, got, want)
+		}
+	}
+}
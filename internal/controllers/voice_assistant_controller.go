@@ -1,11 +1,17 @@
 package controllers
 
+import (
+	"context"
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/services"
+)
+
 type VoiceAssistantController struct {
-	voiceToText *VoiceToTextController
+	voiceToText interfaces.VoiceToTextInterface
 	chatGPT     *ChatGPTController
 }
 
-func (v *VoiceAssistantController) ProcessVoiceInput(audioFilePath string) (string, error) {
+func (v *VoiceAssistantController) ProcessVoiceInput(sessionID, audioFilePath string) (string, error) {
 	// Convert voice to text
 	transcribedText, err := v.voiceToText.ConvertVoiceToText(audioFilePath)
 	if err != nil {
@@ -13,7 +19,7 @@ func (v *VoiceAssistantController) ProcessVoiceInput(audioFilePath string) (stri
 	}
 
 	// Process with ChatGPT
-	response, err := v.chatGPT.ProcessConversation(transcribedText)
+	response, err := v.chatGPT.ProcessConversation(context.Background(), sessionID, services.TextInput(transcribedText))
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/services"
+)
+
+const (
+	VoiceStoreMemory = "memory"
+	VoiceStoreSQLite = "sqlite"
+)
+
+// NewVoiceControllerFromEnv configures where the voices sessions choose are
+// kept
+//
+//	VOICE_STORE        memory (default) or sqlite
+//	VOICE_STORE_PATH   sqlite database, default /tmp/voices.db
+//
+// The memory store forgets voices with the same SESSION_IDLE_TIMEOUT and
+// SESSION_MAX_COUNT as the sessions themselves.
+func NewVoiceControllerFromEnv(provider interfaces.TTSProvider) (*VoiceController, error) {
+	store, err := newSessionVoiceStoreFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewVoiceController(provider, store), nil
+}
+
+func newSessionVoiceStoreFromEnv() (interfaces.SessionVoiceStore, error) {
+	switch name := strings.ToLower(os.Getenv("VOICE_STORE")); name {
+	case "", VoiceStoreMemory:
+		idleTimeout, maxSessions, err := sessionLimitsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return services.NewMemorySessionVoiceStore(idleTimeout, maxSessions), nil
+	case VoiceStoreSQLite:
+		return services.NewSQLiteSessionVoiceStore(envOrDefault("VOICE_STORE_PATH", "/tmp/voices.db"))
+	default:
+		return nil, fmt.Errorf("unknown voice store %q", name)
+	}
+}
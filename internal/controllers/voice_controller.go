@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+
+	"golang.org/x/text/language"
+)
+
+// ErrInvalidVoice is returned for voices or settings the provider lacks
+var ErrInvalidVoice = errors.New("invalid voice")
+
+// VoiceController checks voice choices against the text-to-speech provider
+// and keeps the voice of each session
+type VoiceController struct {
+	provider interfaces.TTSProvider
+	store    interfaces.SessionVoiceStore
+}
+
+func NewVoiceController(provider interfaces.TTSProvider, store interfaces.SessionVoiceStore) *VoiceController {
+	return &VoiceController{provider: provider, store: store}
+}
+
+// Catalog lists the voices and settings that can be chosen. With a
+// language, only voices speaking it are listed and the default is the one
+// for that language.
+func (c *VoiceController) Catalog(ctx context.Context, lang language.Tag) (models.VoiceCatalogModel, error) {
+	voices, err := c.provider.Voices(ctx)
+	if err != nil {
+		return models.VoiceCatalogModel{}, err
+	}
+
+	catalog := models.VoiceCatalogModel{
+		Provider:     c.provider.Name(),
+		Voices:       make([]models.VoiceModel, 0, len(voices)),
+		Capabilities: c.provider.Capabilities(),
+	}
+	if lang == language.Und {
+		catalog.DefaultVoiceID = c.provider.DefaultVoice("")
+		catalog.Voices = append(catalog.Voices, voices...)
+		return catalog, nil
+	}
+
+	catalog.DefaultVoiceID = c.provider.DefaultVoice(lang.String())
+	for _, voice := range voices {
+		if speaksLanguage(voice, lang) {
+			catalog.Voices = append(catalog.Voices, voice)
+		}
+	}
+	return catalog, nil
+}
+
+// speaksLanguage tells whether the voice speaks lang, ignoring the region
+func speaksLanguage(voice models.VoiceModel, lang language.Tag) bool {
+	if len(voice.Languages) == 0 {
+		return true
+	}
+	want, _ := lang.Base()
+	for _, tag := range voice.Languages {
+		if parsed, err := language.Parse(tag); err == nil {
+			if base, _ := parsed.Base(); base == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate requires the voice to be in the catalog and every setting to be
+// one the provider honors, within its range
+func (c *VoiceController) Validate(ctx context.Context, voice models.VoiceSelectionModel) error {
+	capabilities := c.provider.Capabilities()
+	settings := []struct {
+		name   string
+		value  *float64
+		bounds *models.VoiceSettingRangeModel
+	}{
+		{"speed", voice.Speed, capabilities.Speed},
+		{"pitch", voice.Pitch, capabilities.Pitch},
+		{"stability", voice.Stability, capabilities.Stability},
+		{"similarity_boost", voice.SimilarityBoost, capabilities.SimilarityBoost},
+	}
+	for _, setting := range settings {
+		if setting.value == nil {
+			continue
+		}
+		if setting.bounds == nil {
+			return fmt.Errorf("%w: %s cannot change the %s", ErrInvalidVoice, c.provider.Name(), setting.name)
+		}
+		if value := *setting.value; !(value >= setting.bounds.Min && value <= setting.bounds.Max) {
+			return fmt.Errorf("%w: %s must be between %g and %g", ErrInvalidVoice, setting.name, setting.bounds.Min, setting.bounds.Max)
+		}
+	}
+
+	if voice.VoiceID == "" {
+		return nil
+	}
+	voices, err := c.provider.Voices(ctx)
+	if err != nil {
+		return err
+	}
+	for _, known := range voices {
+		if known.ID == voice.VoiceID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s has no voice %q", ErrInvalidVoice, c.provider.Name(), voice.VoiceID)
+}
+
+// SessionVoice returns the session's voice, with nothing chosen when it has
+// none
+func (c *VoiceController) SessionVoice(ctx context.Context, sessionID string) (models.SessionVoiceModel, error) {
+	voice, ok, err := c.store.Load(ctx, sessionID)
+	if err != nil {
+		return voice, err
+	}
+	if !ok {
+		voice = models.SessionVoiceModel{SessionID: sessionID}
+	}
+	return voice, nil
+}
+
+// SetSessionVoice validates voice and replaces the session's with it
+func (c *VoiceController) SetSessionVoice(ctx context.Context, sessionID string, voice models.VoiceSelectionModel) (models.SessionVoiceModel, error) {
+	if err := c.Validate(ctx, voice); err != nil {
+		return models.SessionVoiceModel{}, err
+	}
+
+	sessionVoice := models.SessionVoiceModel{
+		SessionID:           sessionID,
+		VoiceSelectionModel: voice,
+		UpdatedAt:           time.Now().UTC(),
+	}
+	if err := c.store.Save(ctx, sessionVoice); err != nil {
+		return sessionVoice, err
+	}
+	return sessionVoice, nil
+}
+
+func (c *VoiceController) DeleteSessionVoice(ctx context.Context, sessionID string) error {
+	return c.store.Delete(ctx, sessionID)
+}
+
+// ForSpeech is the voice a reply is spoken in: the session's, with what the
+// request chose taking precedence. The request's choice is validated; the
+// session's was when it was set.
+func (c *VoiceController) ForSpeech(ctx context.Context, sessionID string, requested models.VoiceSelectionModel) (models.VoiceSelectionModel, error) {
+	if err := c.Validate(ctx, requested); err != nil {
+		return requested, err
+	}
+
+	voice, ok, err := c.store.Load(ctx, sessionID)
+	if err != nil {
+		return requested, err
+	}
+	if !ok {
+		return requested, nil
+	}
+	return voice.VoiceSelectionModel.Override(requested), nil
+}
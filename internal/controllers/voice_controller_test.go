@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+)
+
+func setting(value float64) *float64 {
+	return &value
+}
+
+func TestVoiceValidate(t *testing.T) {
+	voices := NewVoiceController(NewToneTTSProvider(), services.NewMemorySessionVoiceStore(0, 0))
+
+	tests := []struct {
+		name  string
+		voice models.VoiceSelectionModel
+		valid bool
+	}{
+		{"nothing chosen", models.VoiceSelectionModel{}, true},
+		{"known voice", models.VoiceSelectionModel{VoiceID: toneVoiceID}, true},
+		{"unknown voice", models.VoiceSelectionModel{VoiceID: "nobody"}, false},
+		{"speed in range", models.VoiceSelectionModel{Speed: setting(1.5)}, true},
+		{"speed at the bounds", models.VoiceSelectionModel{Speed: setting(0.5), Pitch: setting(12)}, true},
+		{"speed too slow", models.VoiceSelectionModel{Speed: setting(0.49)}, false},
+		{"pitch too high", models.VoiceSelectionModel{Pitch: setting(12.5)}, false},
+		{"not a number", models.VoiceSelectionModel{Speed: setting(math.NaN())}, false},
+		{"setting the provider lacks", models.VoiceSelectionModel{Stability: setting(0.5)}, false},
+		{"other setting the provider lacks", models.VoiceSelectionModel{SimilarityBoost: setting(0.5)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := voices.Validate(context.Background(), tt.voice)
+			if tt.valid && err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidVoice) {
+				t.Errorf("Validate error = %v, want ErrInvalidVoice", err)
+			}
+		})
+	}
+}
+
+func TestForSpeechPrefersRequest(t *testing.T) {
+	ctx := context.Background()
+	voices := NewVoiceController(NewToneTTSProvider(), services.NewMemorySessionVoiceStore(0, 0))
+
+	if _, err := voices.SetSessionVoice(ctx, "session", models.VoiceSelectionModel{Speed: setting(0.8), Pitch: setting(-3)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The request's speed wins, the session's pitch stays
+	voice, err := voices.ForSpeech(ctx, "session", models.VoiceSelectionModel{Speed: setting(1.2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if voice.Speed == nil || *voice.Speed != 1.2 {
+		t.Errorf("speed = %v, want the request's 1.2", voice.Speed)
+	}
+	if voice.Pitch == nil || *voice.Pitch != -3 {
+		t.Errorf("pitch = %v, want the session's -3", voice.Pitch)
+	}
+
+	// Another session has only what was requested
+	voice, err = voices.ForSpeech(ctx, "other", models.VoiceSelectionModel{Speed: setting(1.2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if voice.Pitch != nil {
+		t.Errorf("other session got pitch %v", *voice.Pitch)
+	}
+
+	// An invalid request is refused even though the session's voice is fine
+	if _, err := voices.ForSpeech(ctx, "session", models.VoiceSelectionModel{Speed: setting(5)}); !errors.Is(err, ErrInvalidVoice) {
+		t.Errorf("ForSpeech error = %v, want ErrInvalidVoice", err)
+	}
+}
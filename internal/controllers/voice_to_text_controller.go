@@ -4,196 +4,461 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
 
 	speech "cloud.google.com/go/speech/apiv1"
 	"cloud.google.com/go/speech/apiv1/speechpb"
-	"github.com/go-audio/audio"
-	"github.com/go-audio/wav"
+	"golang.org/x/text/language"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// Synchronous Recognize takes about a minute of audio at most
+	defaultMaxChunkDuration = 55 * time.Second
+	defaultChunkWorkers     = 4
+	defaultMaxAlternatives  = 3
+	defaultLanguageCode     = "en-US"
+	// Google takes at most three alternative languages
+	maxAlternativeLanguages = 3
 )
 
-type VoiceToTextController struct{}
+type VoiceToTextController struct {
+	// SampleRate is the rate decoded audio is resampled to, 16 kHz when zero
+	SampleRate int
+	// VAD trims silence and rejects uploads without speech, when set
+	VAD *services.VADConfig
+	// Audio longer than MaxChunkDuration (55s when zero) is split at pauses
+	// and up to ChunkWorkers chunks (4 when zero) are recognized at once
+	MaxChunkDuration time.Duration
+	ChunkWorkers     int
+	// MaxAlternatives is the N-best length asked for, 3 when zero
+	MaxAlternatives int
+	// Language is spoken unless a request says otherwise, en-US when empty.
+	// Google also listens for AlternativeLanguages and reports which one
+	// it heard.
+	Language             string
+	AlternativeLanguages []string
+}
 
 func (v *VoiceToTextController) ConvertVoiceToText(audioFilePath string) (string, error) {
-	// Open the audio file
-	file, err := os.Open(audioFilePath)
+	result, err := v.TranscribeVoiceToText(audioFilePath, models.TranscriptionOptions{})
+	return result.Text, err
+}
+
+// TranscribeVoiceToText transcribes the upload, whatever its format and
+// length, and reports the format as uploaded and as sent to Google. Google
+// separates speakers itself, by channel or by diarization.
+func (v *VoiceToTextController) TranscribeVoiceToText(audioFilePath string, options models.TranscriptionOptions) (models.VoiceToTextModel, error) {
+	result := models.VoiceToTextModel{AudioFilePath: audioFilePath}
+
+	switch options.SpeakerMode {
+	case "", models.SpeakerModeChannels, models.SpeakerModeDiarize:
+	default:
+		return result, fmt.Errorf("%w: %q", ErrSpeakerModeUnsupported, options.SpeakerMode)
+	}
+
+	prepared, err := services.PrepareAudio(audioFilePath, v.sampleRate(), v.VAD, options.SpeakerMode == models.SpeakerModeChannels)
 	if err != nil {
-		return "", fmt.Errorf("failed to open audio file: %v", err)
+		return result, err
 	}
-	defer file.Close()
+	prepared.Report(&result)
 
-	// Decode the WAV file
-	decoder := wav.NewDecoder(file)
+	// Opus is sent whole, so refuse what Google would only reject after the upload
+	if audio := prepared.Audio; !audio.IsPCM() && audio.Duration() > v.maxChunkDuration() {
+		return result, fmt.Errorf("%w: %s audio is %v long, at most %v can be recognized at once; send WAV, FLAC or MP3 to have it split",
+			ErrAudioTooLong, audio.Format, audio.Duration().Round(time.Second), v.maxChunkDuration())
+	}
 
-	// Check if the file is valid
-	if !decoder.IsValidFile() {
-		return "", fmt.Errorf("invalid WAV file")
+	ctx := context.Background()
+	client, err := newSpeechClient(ctx)
+	if err != nil {
+		return result, err
 	}
+	defer client.Close()
 
-	// Read the WAV file header and decode the audio data
-	buf, err := decoder.FullPCMBuffer()
+	result.Segments, err = v.recognizeChunks(ctx, client, prepared, v.planChunks(prepared), options)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode WAV file: %v", err)
+		return result, err
 	}
 
-	// Check if it's stereo (2 channels)
-	if buf.Format.NumChannels != 2 {
-		return v.ConvertVoiceToTextNormal(audioFilePath)
+	// Channels are recognized side by side, put their segments in turn order
+	sort.SliceStable(result.Segments, func(i, j int) bool {
+		return result.Segments[i].Start < result.Segments[j].Start
+	})
+
+	result.Summarize()
+
+	return result, nil
+}
+
+// planChunks cuts decoded audio that is too long for one Recognize call at
+// the pauses between speech. Chunks are relative to the original upload.
+func (v *VoiceToTextController) planChunks(prepared *services.PreparedAudio) []services.SpeechSegment {
+	audio := prepared.Audio
+	start := prepared.Offset
+	end := start + audio.Duration()
+
+	maxDuration := v.maxChunkDuration()
+
+	// Opus stays encoded and can only be sent whole
+	if !audio.IsPCM() || end-start <= maxDuration {
+		return []services.SpeechSegment{{Start: start, End: end}}
 	}
 
-	// Convert stereo to mono by averaging the left and right channels
-	monoData := make([]int16, len(buf.Data)/2)
-	for i := 0; i < len(monoData); i++ {
-		// Average left and right channels
-		monoData[i] = (int16(buf.Data[i*2]) + int16(buf.Data[i*2+1])) / 2
+	segments := prepared.Segments
+	if segments == nil {
+		// Detection is off, but pauses are still the best place to cut
+		segments = services.DetectSpeech(audio.Mono(), audio.SampleRate, services.DefaultVADConfig())
 	}
 
-	monoDataInt := make([]int, len(monoData))
-	for i, v := range monoData {
-		monoDataInt[i] = int(v)
+	return services.PlanChunks(segments, start, end, maxDuration)
+}
+
+// recognizeChunks transcribes the chunks concurrently and returns their
+// transcript segments in order. The first failure cancels the rest.
+// Diarization runs per chunk, so in audio longer than one chunk the same
+// voice may get a different speaker number in each.
+func (v *VoiceToTextController) recognizeChunks(
+	ctx context.Context,
+	client *speech.Client,
+	prepared *services.PreparedAudio,
+	chunks []services.SpeechSegment,
+	options models.TranscriptionOptions,
+) ([]models.TranscriptSegmentModel, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := v.ChunkWorkers
+	if workers <= 0 {
+		workers = defaultChunkWorkers
 	}
 
-	// Create a new file for the mono WAV file
-	monoFilePath := "mono_" + audioFilePath
-	monoFile, err := os.Create(monoFilePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create mono file: %v", err)
+	results := make([][]models.TranscriptSegmentModel, len(chunks))
+	slots := make(chan struct{}, workers)
+	var firstErr error
+	var failOnce sync.Once
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk services.SpeechSegment) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			audio := prepared.Audio
+			if audio.IsPCM() {
+				audio = audio.Slice(chunk.Start-prepared.Offset, chunk.End-prepared.Offset)
+			}
+
+			config, content := recognitionInput(audio)
+			config.EnableWordTimeOffsets = true
+			config.EnableWordConfidence = true
+			config.MaxAlternatives = int32(v.maxAlternatives())
+			v.applyLanguage(config, options)
+			applySpeakerMode(config, options)
+			applyVocabulary(config, options)
+			segments, err := recognizeSegments(ctx, client, config, content, chunk.Start)
+			if err != nil {
+				failOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = segments
+		}(i, chunk)
 	}
-	defer monoFile.Close()
+	wg.Wait()
 
-	// Create a new WAV encoder for mono file
-	encoder := wav.NewEncoder(monoFile, int(decoder.SampleRate), 16, 1, 1) // Convert SampleRate to int
+	if firstErr != nil {
+		return nil, firstErr
+	}
 
-	monoBuffer := &audio.IntBuffer{Data: monoDataInt, Format: &audio.Format{SampleRate: int(decoder.SampleRate), NumChannels: 1}}
+	var segments []models.TranscriptSegmentModel
+	for _, chunkSegments := range results {
+		segments = append(segments, chunkSegments...)
+	}
+	return segments, nil
+}
 
-	// Write the mono data to the encoder
-	if err := encoder.Write(monoBuffer); err != nil {
-		return "", fmt.Errorf("failed to write mono WAV file: %v", err)
+func (v *VoiceToTextController) maxChunkDuration() time.Duration {
+	if v.MaxChunkDuration > 0 {
+		return v.MaxChunkDuration
 	}
+	return defaultMaxChunkDuration
+}
 
-	// Use the mono file for speech-to-text
-	return v.transcribeMonoFile(monoFilePath)
+func (v *VoiceToTextController) maxAlternatives() int {
+	if v.MaxAlternatives > 0 {
+		return v.MaxAlternatives
+	}
+	return defaultMaxAlternatives
 }
 
-func (v *VoiceToTextController) transcribeMonoFile(monoFilePath string) (string, error) {
-	ctx := context.Background()
+func (v *VoiceToTextController) sampleRate() int {
+	if v.SampleRate > 0 {
+		return v.SampleRate
+	}
+	return services.DefaultRecognitionSampleRate
+}
 
-	// Read credentials from environment variable
-	credsJSON := os.Getenv("CRED_JSON")
-	if credsJSON == "" {
-		return "", fmt.Errorf("credentials not configured")
+// applyLanguage sets the language asked for, or the default, and the
+// alternatives Google may detect instead
+func (v *VoiceToTextController) applyLanguage(config *speechpb.RecognitionConfig, options models.TranscriptionOptions) {
+	config.LanguageCode = options.Language
+	if config.LanguageCode == "" {
+		config.LanguageCode = v.Language
+	}
+	if config.LanguageCode == "" {
+		config.LanguageCode = defaultLanguageCode
 	}
 
-	// Save credentials to a temporary file
-	credsByteSlice := []byte(credsJSON)
-	tmpFile, err := os.CreateTemp("", "creds-*.json")
-	if err != nil {
-		return "", fmt.Errorf("temp file creation failed: %v", err)
+	config.AlternativeLanguageCodes = nil
+	for _, alternative := range v.AlternativeLanguages {
+		if len(config.AlternativeLanguageCodes) == maxAlternativeLanguages {
+			break
+		}
+		if !strings.EqualFold(alternative, config.LanguageCode) {
+			config.AlternativeLanguageCodes = append(config.AlternativeLanguageCodes, alternative)
+		}
 	}
-	defer os.Remove(tmpFile.Name())
+}
 
-	// Write credentials to temp file
-	if _, err := tmpFile.Write(credsByteSlice); err != nil {
-		return "", fmt.Errorf("credential write failed: %v", err)
+// canonicalLanguage turns the lower case codes Google reports, like es-us,
+// into BCP 47 as in es-US
+func canonicalLanguage(code string) string {
+	if tag, err := language.Parse(code); err == nil {
+		return tag.String()
 	}
-	tmpFile.Close()
+	return code
+}
 
-	// Create a new Speech client
-	client, err := speech.NewClient(ctx, option.WithCredentialsFile(tmpFile.Name()))
-	if err != nil {
-		return "", fmt.Errorf("speech client creation failed: %v", err)
+// applyVocabulary biases recognition towards the tenant's phrases, one
+// speech context per boost
+func applyVocabulary(config *speechpb.RecognitionConfig, options models.TranscriptionOptions) {
+	config.SpeechContexts = nil
+	if options.Vocabulary == nil {
+		return
 	}
-	defer client.Close()
 
-	// Read the mono WAV file into memory
-	data, err := os.ReadFile(monoFilePath)
-	if err != nil {
-		return "", fmt.Errorf("audio file read failed: %v", err)
+	contexts := make(map[float32]*speechpb.SpeechContext)
+	for _, hint := range options.Vocabulary.Phrases {
+		speechContext, ok := contexts[hint.Boost]
+		if !ok {
+			speechContext = &speechpb.SpeechContext{Boost: hint.Boost}
+			contexts[hint.Boost] = speechContext
+			config.SpeechContexts = append(config.SpeechContexts, speechContext)
+		}
+		speechContext.Phrases = append(speechContext.Phrases, hint.Phrase)
+	}
+}
+
+// applySpeakerMode asks Google to separate speakers the way options say
+func applySpeakerMode(config *speechpb.RecognitionConfig, options models.TranscriptionOptions) {
+	switch options.SpeakerMode {
+	case models.SpeakerModeChannels:
+		config.EnableSeparateRecognitionPerChannel = true
+	case models.SpeakerModeDiarize:
+		config.DiarizationConfig = &speechpb.SpeakerDiarizationConfig{
+			EnableSpeakerDiarization: true,
+			MinSpeakerCount:          int32(options.MinSpeakers),
+			MaxSpeakerCount:          int32(options.MaxSpeakers),
+		}
+	}
+}
+
+// recognitionInput describes prepared audio with the matching Speech
+// encoding. Decoded audio is sent as LINEAR16, Opus as it was uploaded.
+func recognitionInput(audio *services.Audio) (*speechpb.RecognitionConfig, []byte) {
+	config := &speechpb.RecognitionConfig{
+		SampleRateHertz:   int32(audio.SampleRate),
+		AudioChannelCount: int32(audio.Channels),
 	}
 
-	// Configure the request to Google Cloud Speech API
+	switch audio.Format {
+	case services.AudioFormatOggOpus:
+		config.Encoding = speechpb.RecognitionConfig_OGG_OPUS
+		return config, audio.Encoded
+	case services.AudioFormatWebMOpus:
+		config.Encoding = speechpb.RecognitionConfig_WEBM_OPUS
+		return config, audio.Encoded
+	}
+
+	config.Encoding = speechpb.RecognitionConfig_LINEAR16
+	return config, audio.PCM16()
+}
+
+// recognizeSegments transcribes one piece of audio. Each result becomes a
+// segment, with its times shifted by where the piece starts in the upload.
+func recognizeSegments(
+	ctx context.Context,
+	client *speech.Client,
+	config *speechpb.RecognitionConfig,
+	content []byte,
+	offset time.Duration,
+) ([]models.TranscriptSegmentModel, error) {
 	req := &speechpb.RecognizeRequest{
-		Config: &speechpb.RecognitionConfig{
-			Encoding:          speechpb.RecognitionConfig_LINEAR16,
-			LanguageCode:      "en-US",
-			AudioChannelCount: 1, // mono
-		},
+		Config: config,
 		Audio: &speechpb.RecognitionAudio{
-			AudioSource: &speechpb.RecognitionAudio_Content{Content: data},
+			AudioSource: &speechpb.RecognitionAudio_Content{Content: content},
 		},
 	}
 
-	// Send the request to Google Cloud Speech API
 	resp, err := client.Recognize(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("speech recognition failed: %v", err)
+		return nil, fmt.Errorf("speech recognition failed: %v", err)
 	}
 
-	// Collect all transcriptions from the response
-	var resultText string
+	if config.DiarizationConfig.GetEnableSpeakerDiarization() {
+		return diarizedSegments(resp.Results, offset), nil
+	}
+
+	// Results cover consecutive stretches of audio, of each channel when
+	// channels are recognized separately
+	var segments []models.TranscriptSegmentModel
+	starts := make(map[int32]time.Duration)
 	for _, result := range resp.Results {
-		for _, alt := range result.Alternatives {
-			resultText += alt.Transcript + " "
+		start, ok := starts[result.ChannelTag]
+		if !ok {
+			start = offset
+		}
+		end := offset + result.ResultEndTime.AsDuration()
+		if end < start {
+			end = start
 		}
+		if len(result.Alternatives) > 0 {
+			segment := transcriptSegment(result, start, end, offset)
+			if config.EnableSeparateRecognitionPerChannel {
+				segment.Speaker = fmt.Sprintf("channel_%d", result.ChannelTag)
+			}
+			segments = append(segments, segment)
+		}
+		starts[result.ChannelTag] = end
 	}
 
-	// Return the result text (transcribed speech)
-	return resultText, nil
+	return segments, nil
 }
 
-func (v *VoiceToTextController) ConvertVoiceToTextNormal(audioFilePath string) (string, error) {
-	ctx := context.Background()
+// diarizedSegments turns each run of words by one speaker into a segment.
+// Google tags the words of the whole request in its last result only.
+func diarizedSegments(results []*speechpb.SpeechRecognitionResult, offset time.Duration) []models.TranscriptSegmentModel {
+	if len(results) == 0 || len(results[len(results)-1].Alternatives) == 0 {
+		return nil
+	}
+	last := results[len(results)-1]
 
-	credsJSON := os.Getenv("CRED_JSON")
-	if credsJSON == "" {
-		return "", fmt.Errorf("credentials not configured")
+	var segments []models.TranscriptSegmentModel
+	var texts []string
+	var confidence float32
+	speaker := int32(-1)
+
+	closeSegment := func() {
+		if n := len(segments); n > 0 {
+			segments[n-1].Text = strings.Join(texts, " ")
+			segments[n-1].Confidence = confidence / float32(len(texts))
+		}
+		texts, confidence = nil, 0
 	}
 
-	credsByteSlice := []byte(credsJSON)
-	tmpFile, err := os.CreateTemp("", "creds-*.json")
-	if err != nil {
-		return "", fmt.Errorf("temp file creation failed: %v", err)
+	for _, word := range last.Alternatives[0].Words {
+		if word.SpeakerTag != speaker {
+			closeSegment()
+			speaker = word.SpeakerTag
+			segments = append(segments, models.TranscriptSegmentModel{
+				Start:    (offset + word.StartTime.AsDuration()).Seconds(),
+				Language: canonicalLanguage(last.LanguageCode),
+				Speaker:  fmt.Sprintf("speaker_%d", speaker),
+			})
+		}
+
+		segment := &segments[len(segments)-1]
+		segment.End = (offset + word.EndTime.AsDuration()).Seconds()
+		segment.Words = append(segment.Words, models.WordModel{
+			Word:       word.Word,
+			Start:      (offset + word.StartTime.AsDuration()).Seconds(),
+			End:        segment.End,
+			Confidence: word.Confidence,
+		})
+		texts = append(texts, word.Word)
+		confidence += word.Confidence
 	}
-	defer os.Remove(tmpFile.Name())
+	closeSegment()
+
+	return segments
+}
 
-	if _, err := tmpFile.Write(credsByteSlice); err != nil {
-		return "", fmt.Errorf("credential write failed: %v", err)
+// transcriptSegment converts a result. Google only times the words of the
+// top alternative.
+func transcriptSegment(result *speechpb.SpeechRecognitionResult, start, end, offset time.Duration) models.TranscriptSegmentModel {
+	best := result.Alternatives[0]
+	segment := models.TranscriptSegmentModel{
+		Start:      start.Seconds(),
+		End:        end.Seconds(),
+		Text:       strings.TrimSpace(best.Transcript),
+		Confidence: best.Confidence,
+		Language:   canonicalLanguage(result.LanguageCode),
 	}
-	tmpFile.Close()
 
-	client, err := speech.NewClient(ctx, option.WithCredentialsFile(tmpFile.Name()))
-	if err != nil {
-		return "", fmt.Errorf("speech client creation failed: %v", err)
+	for _, word := range best.Words {
+		segment.Words = append(segment.Words, models.WordModel{
+			Word:       word.Word,
+			Start:      (offset + word.StartTime.AsDuration()).Seconds(),
+			End:        (offset + word.EndTime.AsDuration()).Seconds(),
+			Confidence: word.Confidence,
+		})
 	}
-	defer client.Close()
 
-	data, err := os.ReadFile(audioFilePath)
-	if err != nil {
-		return "", fmt.Errorf("audio file read failed: %v", err)
+	for _, alternative := range result.Alternatives {
+		segment.Alternatives = append(segment.Alternatives, models.TranscriptAlternativeModel{
+			Text:       strings.TrimSpace(alternative.Transcript),
+			Confidence: alternative.Confidence,
+		})
 	}
 
-	req := &speechpb.RecognizeRequest{
-		Config: &speechpb.RecognitionConfig{
-			Encoding:          speechpb.RecognitionConfig_LINEAR16,
-			LanguageCode:      "en-US",
-			AudioChannelCount: 1,
-		},
-		Audio: &speechpb.RecognitionAudio{
-			AudioSource: &speechpb.RecognitionAudio_Content{Content: data},
-		},
+	return segment
+}
+
+// newSpeechClient creates a Speech client from the CRED_JSON service account.
+// When SPEECH_ENDPOINT is set the client instead dials that address over
+// plaintext gRPC without credentials, which is how a local fake of the
+// Speech service is reached.
+func newSpeechClient(ctx context.Context) (*speech.Client, error) {
+	if endpoint := os.Getenv("SPEECH_ENDPOINT"); endpoint != "" {
+		client, err := speech.NewClient(ctx,
+			option.WithEndpoint(endpoint),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("speech client creation failed: %v", err)
+		}
+		return client, nil
 	}
 
-	resp, err := client.Recognize(ctx, req)
-	if err != nil {
-		return "", fmt.Errorf("speech recognition failed: %v", err)
+	// Read credentials from environment variable
+	credsJSON := os.Getenv("CRED_JSON")
+	if credsJSON == "" {
+		return nil, fmt.Errorf("credentials not configured")
 	}
 
-	var resultText string
-	for _, result := range resp.Results {
-		for _, alt := range result.Alternatives {
-			resultText += alt.Transcript
-		}
+	client, err := speech.NewClient(ctx, option.WithCredentialsJSON([]byte(credsJSON)))
+	if err != nil {
+		return nil, fmt.Errorf("speech client creation failed: %v", err)
 	}
 
-	return resultText, nil
+	return client, nil
 }
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/speech/apiv1/speechpb"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestTranscribeRejectsOpusOverChunkLimit(t *testing.T) {
+	// No speech service is configured, so anything past the check fails
+	t.Setenv("SPEECH_ENDPOINT", "")
+	t.Setenv("CRED_JSON", "")
+
+	for _, file := range []string{"silence_mono.opus", "silence_mono.webm"} {
+		t.Run(file, func(t *testing.T) {
+			path := filepath.Join("..", "services", "testdata", file)
+
+			controller := &VoiceToTextController{MaxChunkDuration: 50 * time.Millisecond}
+			_, err := controller.TranscribeVoiceToText(path, models.TranscriptionOptions{})
+			if !errors.Is(err, ErrAudioTooLong) {
+				t.Fatalf("error = %v, want ErrAudioTooLong", err)
+			}
+			if !strings.Contains(err.Error(), "50ms") {
+				t.Errorf("error %q does not name the limit", err)
+			}
+
+			controller.MaxChunkDuration = time.Second
+			if _, err := controller.TranscribeVoiceToText(path, models.TranscriptionOptions{}); errors.Is(err, ErrAudioTooLong) {
+				t.Fatalf("audio under the limit rejected: %v", err)
+			}
+		})
+	}
+}
+
+// speechWithPauses writes a 16 kHz WAV of one second tones, standing in for
+// speech, with a second of silence between them
+func speechWithPauses(t *testing.T, tones int) string {
+	t.Helper()
+	layout := strings.Repeat("s_", tones)
+	return writeTestWAV(t, layout[:len(layout)-1])
+}
+
+// writeTestWAV writes a 16 kHz WAV with a second of audio for each letter
+// of layout: s for a tone standing in for speech, n for background hiss and _
+// for silence
+func writeTestWAV(t *testing.T, layout string) string {
+	t.Helper()
+	const rate = 16000
+	rng := rand.New(rand.NewSource(1))
+	var pcm []byte
+	for _, second := range layout {
+		for n := 0; n < rate; n++ {
+			var sample int16
+			switch second {
+			case 's':
+				sample = int16(3000 * math.Sin(2*math.Pi*300*float64(n)/rate))
+			case 'n':
+				sample = int16(100 * (2*rng.Float64() - 1))
+			}
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(sample))
+		}
+	}
+	path := filepath.Join(t.TempDir(), "speech.wav")
+	if err := os.WriteFile(path, services.EncodeWAV(pcm, rate, 1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTranscribeChunksAtPauses(t *testing.T) {
+	// Each chunk is answered with its length, and a word 100 ms in. Earlier
+	// requests take longer, so chunks finish out of order.
+	var requests atomic.Int32
+	serveFakeSpeech(t, &fakeSpeechServer{
+		recognize: func(ctx context.Context, req *speechpb.RecognizeRequest) (*speechpb.RecognizeResponse, error) {
+			time.Sleep(time.Duration(3-requests.Add(1)) * 30 * time.Millisecond)
+			length := time.Duration(len(req.Audio.GetContent())/2) * time.Second / time.Duration(req.Config.SampleRateHertz)
+			return &speechpb.RecognizeResponse{
+				Results: []*speechpb.SpeechRecognitionResult{{
+					Alternatives: []*speechpb.SpeechRecognitionAlternative{{
+						Transcript: fmt.Sprintf("%dms", length.Milliseconds()),
+						Words: []*speechpb.WordInfo{{
+							Word:      "word",
+							StartTime: durationpb.New(100 * time.Millisecond),
+							EndTime:   durationpb.New(200 * time.Millisecond),
+						}},
+					}},
+					ResultEndTime: durationpb.New(length),
+				}},
+			}, nil
+		},
+	})
+
+	// Five seconds of audio, split into chunks of at most 2.5 s
+	controller := &VoiceToTextController{MaxChunkDuration: 2500 * time.Millisecond, ChunkWorkers: 3}
+	result, err := controller.TranscribeVoiceToText(speechWithPauses(t, 3), models.TranscriptionOptions{})
+	if err != nil {
+		t.Fatalf("TranscribeVoiceToText: %v", err)
+	}
+
+	segments := result.Segments
+	if len(segments) != 3 {
+		t.Fatalf("got %d segments, want one per chunk: %+v", len(segments), segments)
+	}
+	if segments[0].Start != 0 || segments[2].End != 5 {
+		t.Errorf("segments cover %v s to %v s, want the whole 5 s", segments[0].Start, segments[2].End)
+	}
+	for i, segment := range segments {
+		if i > 0 && segment.Start != segments[i-1].End {
+			t.Errorf("segment %d starts at %v s, the one before ends at %v s", i, segment.Start, segments[i-1].End)
+		}
+		// Cuts fall in the pauses, from 1 s to 2 s and from 3 s to 4 s
+		if i > 0 && !(segment.Start > float64(2*i-1) && segment.Start < float64(2*i)) {
+			t.Errorf("cut %d at %v s is not in a pause", i, segment.Start)
+		}
+		if want := fmt.Sprintf("%.0fms", (segment.End-segment.Start)*1000); segment.Text != want {
+			t.Errorf("segment %d %v-%v s has the text of a %s chunk", i, segment.Start, segment.End, segment.Text)
+		}
+		if len(segment.Words) != 1 || math.Abs(segment.Words[0].Start-(segment.Start+0.1)) > 1e-9 {
+			t.Errorf("segment %d at %v s has words %+v, want one 100 ms in", i, segment.Start, segment.Words)
+		}
+	}
+	if result.Text != strings.Join([]string{segments[0].Text, segments[1].Text, segments[2].Text}, " ") {
+		t.Errorf("text %q is not the chunks in order", result.Text)
+	}
+}
+
+// recognizeLength answers each Recognize call with the length of its audio,
+// and a word 100 ms in, counting the calls
+func recognizeLength(calls *atomic.Int32) func(context.Context, *speechpb.RecognizeRequest) (*speechpb.RecognizeResponse, error) {
+	return func(ctx context.Context, req *speechpb.RecognizeRequest) (*speechpb.RecognizeResponse, error) {
+		calls.Add(1)
+		length := time.Duration(len(req.Audio.GetContent())/2) * time.Second / time.Duration(req.Config.SampleRateHertz)
+		return &speechpb.RecognizeResponse{
+			Results: []*speechpb.SpeechRecognitionResult{{
+				Alternatives: []*speechpb.SpeechRecognitionAlternative{{
+					Transcript: fmt.Sprintf("%dms", length.Milliseconds()),
+					Words: []*speechpb.WordInfo{{
+						Word:      "word",
+						StartTime: durationpb.New(100 * time.Millisecond),
+						EndTime:   durationpb.New(200 * time.Millisecond),
+					}},
+				}},
+				ResultEndTime: durationpb.New(length),
+			}},
+		}, nil
+	}
+}
+
+func TestTranscribeWithoutSpeech(t *testing.T) {
+	var calls atomic.Int32
+	serveFakeSpeech(t, &fakeSpeechServer{recognize: recognizeLength(&calls)})
+	vad := services.DefaultVADConfig()
+	controller := &VoiceToTextController{VAD: &vad}
+
+	for name, layout := range map[string]string{"silence": "___", "noise": "nnn"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := controller.TranscribeVoiceToText(writeTestWAV(t, layout), models.TranscriptionOptions{})
+			if !errors.Is(err, services.ErrNoSpeechDetected) {
+				t.Errorf("error = %v, want ErrNoSpeechDetected", err)
+			}
+		})
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("recognizer called %d times for audio without speech", n)
+	}
+}
+
+func TestTranscribeTrimsSilence(t *testing.T) {
+	var calls atomic.Int32
+	serveFakeSpeech(t, &fakeSpeechServer{recognize: recognizeLength(&calls)})
+	vad := services.DefaultVADConfig()
+	controller := &VoiceToTextController{VAD: &vad}
+
+	// Speech from 2 s to 3 s of 5 s
+	result, err := controller.TranscribeVoiceToText(writeTestWAV(t, "__s__"), models.TranscriptionOptions{})
+	if err != nil {
+		t.Fatalf("TranscribeVoiceToText: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("recognizer called %d times, want 1", n)
+	}
+
+	// What was sent is the speech and its padding on either side
+	near := func(got, want float64) bool { return math.Abs(got-want) <= vad.FrameDuration.Seconds() }
+	padding := vad.Padding.Seconds()
+	if len(result.SpeechSegments) != 1 || !near(result.SpeechSegments[0].Start, 2-padding) || !near(result.SpeechSegments[0].End, 3+padding) {
+		t.Fatalf("speech segments = %+v, want one from 2 s to 3 s with padding", result.SpeechSegments)
+	}
+	speech := result.SpeechSegments[0]
+	if want := fmt.Sprintf("%.0fms", (speech.End-speech.Start)*1000); result.Text != want {
+		t.Errorf("recognizer heard %s, want the %s of trimmed audio", result.Text, want)
+	}
+
+	// Times are reported against the upload, offset by the trimmed silence
+	if len(result.Segments) != 1 {
+		t.Fatalf("got %d segments, want 1", len(result.Segments))
+	}
+	segment := result.Segments[0]
+	if math.Abs(segment.Start-speech.Start) > 1e-9 || math.Abs(segment.End-speech.End) > 1e-9 {
+		t.Errorf("segment %v-%v s, want the speech at %v-%v s", segment.Start, segment.End, speech.Start, speech.End)
+	}
+	if len(segment.Words) != 1 || math.Abs(segment.Words[0].Start-(speech.Start+0.1)) > 1e-9 {
+		t.Errorf("words %+v, want one 100 ms into the speech", segment.Words)
+	}
+}
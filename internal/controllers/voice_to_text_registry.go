@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	VoiceToTextProviderGoogle  = "google"
+	VoiceToTextProviderWhisper = "whisper"
+	VoiceToTextProviderFake    = "fake"
+)
+
+// VoiceToTextRegistry resolves speech-to-text providers by name
+type VoiceToTextRegistry struct {
+	mu          sync.RWMutex
+	providers   map[string]interfaces.VoiceToTextInterface
+	defaultName string
+	vad         *services.VADConfig
+}
+
+func NewVoiceToTextRegistry(defaultName string) *VoiceToTextRegistry {
+	return &VoiceToTextRegistry{
+		providers:   make(map[string]interfaces.VoiceToTextInterface),
+		defaultName: strings.ToLower(defaultName),
+	}
+}
+
+// NewVoiceToTextRegistryFromEnv registers every provider that can be built
+// from the environment. STT_PROVIDER selects the default one and
+// STT_SAMPLE_RATE the rate uploads are resampled to (16000 by default).
+// Silence is trimmed unless STT_VAD=off. Google splits audio longer than
+// STT_CHUNK_SECONDS (55) and recognizes STT_CHUNK_WORKERS (4) chunks at once,
+// asking for STT_MAX_ALTERNATIVES (3) hypotheses of each result. It expects
+// STT_LANGUAGE (en-US) unless a request names another language, and detects
+// up to three STT_ALTERNATIVE_LANGUAGES, e.g. es-ES,fr-FR. The fake provider
+// is only offered when STT_PROVIDER=fake or FAKE_STT_TRANSCRIPT is set.
+func NewVoiceToTextRegistryFromEnv() (*VoiceToTextRegistry, error) {
+	defaultName := os.Getenv("STT_PROVIDER")
+	if defaultName == "" {
+		defaultName = VoiceToTextProviderGoogle
+	}
+
+	sampleRate := services.DefaultRecognitionSampleRate
+	if value := os.Getenv("STT_SAMPLE_RATE"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 8000 || parsed > 48000 {
+			return nil, fmt.Errorf("invalid STT_SAMPLE_RATE %q: must be between 8000 and 48000", value)
+		}
+		sampleRate = parsed
+	}
+
+	vad, err := newVADConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	google := &VoiceToTextController{SampleRate: sampleRate, VAD: vad}
+	if value := os.Getenv("STT_CHUNK_SECONDS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 5 || parsed > 60 {
+			return nil, fmt.Errorf("invalid STT_CHUNK_SECONDS %q: must be between 5 and 60", value)
+		}
+		google.MaxChunkDuration = time.Duration(parsed) * time.Second
+	}
+	if value := os.Getenv("STT_CHUNK_WORKERS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid STT_CHUNK_WORKERS %q", value)
+		}
+		google.ChunkWorkers = parsed
+	}
+	if value := os.Getenv("STT_MAX_ALTERNATIVES"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 30 {
+			return nil, fmt.Errorf("invalid STT_MAX_ALTERNATIVES %q: must be between 1 and 30", value)
+		}
+		google.MaxAlternatives = parsed
+	}
+
+	if value := os.Getenv("STT_LANGUAGE"); value != "" {
+		tag, err := language.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STT_LANGUAGE %q: %v", value, err)
+		}
+		google.Language = tag.String()
+	}
+	if value := os.Getenv("STT_ALTERNATIVE_LANGUAGES"); value != "" {
+		for _, code := range strings.Split(value, ",") {
+			tag, err := language.Parse(strings.TrimSpace(code))
+			if err != nil {
+				return nil, fmt.Errorf("invalid STT_ALTERNATIVE_LANGUAGES entry %q: %v", code, err)
+			}
+			google.AlternativeLanguages = append(google.AlternativeLanguages, tag.String())
+		}
+		if len(google.AlternativeLanguages) > maxAlternativeLanguages {
+			return nil, fmt.Errorf("invalid STT_ALTERNATIVE_LANGUAGES %q: at most %d languages", value, maxAlternativeLanguages)
+		}
+	}
+
+	registry := NewVoiceToTextRegistry(defaultName)
+	registry.vad = vad
+	registry.Register(VoiceToTextProviderGoogle, google)
+
+	// Never let callers pick canned transcripts unless asked for
+	fakeTranscript := os.Getenv("FAKE_STT_TRANSCRIPT")
+	if strings.EqualFold(defaultName, VoiceToTextProviderFake) || fakeTranscript != "" {
+		registry.Register(VoiceToTextProviderFake, NewFakeVoiceToTextController(fakeTranscript))
+	}
+
+	if baseURL := os.Getenv("WHISPER_BASE_URL"); baseURL != "" {
+		whisper := NewWhisperVoiceToTextController(
+			baseURL,
+			os.Getenv("WHISPER_API_KEY"),
+			os.Getenv("WHISPER_MODEL"),
+			sampleRate,
+		)
+		whisper.VAD = vad
+		registry.Register(VoiceToTextProviderWhisper, whisper)
+	}
+
+	return registry, nil
+}
+
+// newVADConfigFromEnv returns the voice activity detection settings, or nil
+// when STT_VAD=off. STT_VAD_THRESHOLD_DB sets the speech level in dBFS.
+func newVADConfigFromEnv() (*services.VADConfig, error) {
+	if strings.EqualFold(os.Getenv("STT_VAD"), "off") {
+		return nil, nil
+	}
+
+	config := services.DefaultVADConfig()
+	if value := os.Getenv("STT_VAD_THRESHOLD_DB"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed >= 0 {
+			return nil, fmt.Errorf("invalid STT_VAD_THRESHOLD_DB %q: must be negative", value)
+		}
+		config.ThresholdDB = parsed
+	}
+
+	return &config, nil
+}
+
+// Register adds or replaces a provider under the given name
+func (r *VoiceToTextRegistry) Register(name string, provider interfaces.VoiceToTextInterface) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[strings.ToLower(name)] = provider
+}
+
+// Resolve returns the named provider, or the default one when name is empty
+func (r *VoiceToTextRegistry) Resolve(name string) (interfaces.VoiceToTextInterface, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name == "" {
+		name = r.defaultName
+	}
+
+	provider, ok := r.providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown speech-to-text provider %q", name)
+	}
+
+	return provider, nil
+}
+
+// ErrSpeakerModeUnsupported is returned when a provider cannot separate
+// speakers the way the request asks
+var ErrSpeakerModeUnsupported = errors.New("speaker mode not supported")
+
+// ErrAudioTooLong is returned for audio longer than a provider takes in one
+// request that it cannot split either
+var ErrAudioTooLong = errors.New("audio too long")
+
+// TranscribeAudio asks for the detailed result when the provider offers one,
+// and applies the replacements of the vocabulary in options
+func TranscribeAudio(provider interfaces.VoiceToTextInterface, audioFilePath string, options models.TranscriptionOptions) (models.VoiceToTextModel, error) {
+	var result models.VoiceToTextModel
+	var err error
+	if detailed, ok := provider.(interfaces.DetailedVoiceToTextInterface); ok {
+		result, err = detailed.TranscribeVoiceToText(audioFilePath, options)
+	} else if options.SpeakerMode != "" {
+		return models.VoiceToTextModel{}, ErrSpeakerModeUnsupported
+	} else {
+		result.AudioFilePath = audioFilePath
+		result.Text, err = provider.ConvertVoiceToText(audioFilePath)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if options.Vocabulary != nil {
+		services.NewReplacer(options.Vocabulary.Replacements).ReplaceTranscript(&result)
+	}
+	return result, nil
+}
+
+// VADConfig returns the voice activity detection settings shared by the
+// providers, or nil when detection is off
+func (r *VoiceToTextRegistry) VADConfig() *services.VADConfig {
+	return r.vad
+}
+
+// Names lists the registered providers in alphabetical order
+func (r *VoiceToTextRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import "testing"
+
+func TestFakeVoiceToTextProviderIsOptIn(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		transcript string
+		want       bool
+	}{
+		{name: "default", want: false},
+		{name: "google", provider: VoiceToTextProviderGoogle, want: false},
+		{name: "fake default", provider: "Fake", want: true},
+		{name: "fake transcript", transcript: "turn on the lights", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STT_PROVIDER", tt.provider)
+			t.Setenv("FAKE_STT_TRANSCRIPT", tt.transcript)
+
+			registry, err := NewVoiceToTextRegistryFromEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = registry.Resolve(VoiceToTextProviderFake)
+			if got := err == nil; got != tt.want {
+				t.Errorf("fake provider registered = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrWebhookNotAllowed is returned for webhook URLs that point into the
+// private network the server runs in
+var ErrWebhookNotAllowed = errors.New("webhook destination not allowed")
+
+// checkWebhookURL refuses webhooks to private, loopback and link-local
+// addresses, unless their host is allowed. Delivery checks again, since the
+// name may resolve differently by then.
+func checkWebhookURL(ctx context.Context, webhookURL string, allowedHosts []string) error {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: %q is not an absolute http or https URL", ErrWebhookNotAllowed, webhookURL)
+	}
+
+	host := parsed.Hostname()
+	if webhookHostAllowed(host, allowedHosts) {
+		return nil
+	}
+	_, err = publicAddresses(ctx, host)
+	return err
+}
+
+// newWebhookClient returns a client that only connects to public addresses,
+// or to the allowed hosts. Redirects go through the same check.
+func newWebhookClient(allowedHosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if webhookHostAllowed(host, allowedHosts) {
+			return dialer.DialContext(ctx, network, address)
+		}
+
+		// Dial the address that was checked, not a fresh lookup of the name
+		ips, err := publicAddresses(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var dialErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+
+	return &http.Client{Timeout: webhookTimeout, Transport: transport}
+}
+
+func webhookHostAllowed(host string, allowedHosts []string) bool {
+	for _, allowed := range allowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicAddresses resolves host and fails if any of its addresses is not
+// public, so a name cannot mix a public address with an internal one
+func publicAddresses(ctx context.Context, host string) ([]net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve webhook host: %v", err)
+		}
+		for _, address := range addresses {
+			ips = append(ips, address.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", ErrWebhookNotAllowed, host, ip)
+		}
+	}
+	return ips, nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || isSharedAddress(ip))
+}
+
+// isSharedAddress reports carrier-grade NAT space, 100.64.0.0/10, which
+// net.IP does not count as private
+func isSharedAddress(ip net.IP) bool {
+	ip4 := ip.To4()
+	return ip4 != nil && ip4[0] == 100 && ip4[1]&0xC0 == 64
+}
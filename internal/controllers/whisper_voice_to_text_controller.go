@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/sashabaranov/go-openai"
+	"golang.org/x/text/language"
+)
+
+// maxWhisperPromptBytes keeps prompts within the 224 tokens Whisper reads
+const maxWhisperPromptBytes = 800
+
+// WhisperVoiceToTextController transcribes audio through any server exposing
+// the OpenAI compatible /v1/audio/transcriptions endpoint (faster-whisper-server,
+// whisper.cpp, LocalAI, ...)
+type WhisperVoiceToTextController struct {
+	client *openai.Client
+	model  string
+	// SampleRate is the rate decoded audio is resampled to, 16 kHz when zero
+	SampleRate int
+	// VAD trims silence and rejects uploads without speech, when set
+	VAD *services.VADConfig
+}
+
+func NewWhisperVoiceToTextController(baseURL, apiKey, model string, sampleRate int) *WhisperVoiceToTextController {
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = strings.TrimRight(baseURL, "/") + "/v1"
+
+	if model == "" {
+		model = openai.Whisper1
+	}
+
+	return &WhisperVoiceToTextController{
+		client:     openai.NewClientWithConfig(config),
+		model:      model,
+		SampleRate: sampleRate,
+	}
+}
+
+func (w *WhisperVoiceToTextController) ConvertVoiceToText(audioFilePath string) (string, error) {
+	result, err := w.TranscribeVoiceToText(audioFilePath, models.TranscriptionOptions{})
+	return result.Text, err
+}
+
+// TranscribeVoiceToText uploads decodable audio as normalized WAV, which is
+// smaller than most uploads. MP4 and Opus, which Whisper reads itself, are
+// sent as they are. Whisper cannot diarize; to separate channels each one
+// is uploaded on its own.
+func (w *WhisperVoiceToTextController) TranscribeVoiceToText(audioFilePath string, options models.TranscriptionOptions) (models.VoiceToTextModel, error) {
+	result := models.VoiceToTextModel{AudioFilePath: audioFilePath}
+	perChannel := options.SpeakerMode == models.SpeakerModeChannels
+	if options.SpeakerMode != "" && !perChannel {
+		return result, fmt.Errorf("%w: whisper cannot %s", ErrSpeakerModeUnsupported, options.SpeakerMode)
+	}
+
+	format, err := services.SniffAudioFile(audioFilePath)
+	if err != nil {
+		return result, err
+	}
+
+	if format == services.AudioFormatMP4 {
+		if perChannel {
+			return result, fmt.Errorf("%w: channels of %s audio cannot be separated", ErrSpeakerModeUnsupported, format)
+		}
+		result.OriginalFormat = &models.AudioFormatModel{Format: string(format)}
+		result.FinalFormat = result.OriginalFormat
+		return w.transcribeInto(result, w.request(audioFilePath, nil, options), 0, 0)
+	}
+
+	sampleRate := w.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = services.DefaultRecognitionSampleRate
+	}
+
+	prepared, err := services.PrepareAudio(audioFilePath, sampleRate, w.VAD, perChannel)
+	if err != nil {
+		return result, err
+	}
+	prepared.Report(&result)
+
+	audio := prepared.Audio
+	if !audio.IsPCM() {
+		if perChannel {
+			return result, fmt.Errorf("%w: channels of %s audio cannot be separated", ErrSpeakerModeUnsupported, format)
+		}
+		return w.transcribeInto(result, w.request(audioFilePath, nil, options), prepared.Offset, 0)
+	}
+
+	result.FinalFormat.Format = string(services.AudioFormatWAV)
+	wavName := strings.TrimSuffix(filepath.Base(audioFilePath), filepath.Ext(audioFilePath)) + ".wav"
+
+	if !perChannel {
+		wav := services.EncodeWAV(audio.PCM16(), audio.SampleRate, audio.Channels)
+		return w.transcribeInto(result, w.request(wavName, wav, options), prepared.Offset, audio.Duration())
+	}
+
+	for channel := 0; channel < audio.Channels; channel++ {
+		mono := audio.Channel(channel)
+		wav := services.EncodeWAV(mono.PCM16(), mono.SampleRate, 1)
+		segments, err := w.transcribe(w.request(wavName, wav, options), prepared.Offset, mono.Duration())
+		if err != nil {
+			return result, err
+		}
+		for _, segment := range segments {
+			segment.Speaker = fmt.Sprintf("channel_%d", channel+1)
+			result.Segments = append(result.Segments, segment)
+		}
+	}
+
+	// Put the channels' segments in turn order
+	sort.SliceStable(result.Segments, func(i, j int) bool {
+		return result.Segments[i].Start < result.Segments[j].Start
+	})
+	result.Summarize()
+
+	return result, nil
+}
+
+func (w *WhisperVoiceToTextController) transcribeInto(result models.VoiceToTextModel, req openai.AudioRequest, offset, duration time.Duration) (models.VoiceToTextModel, error) {
+	segments, err := w.transcribe(req, offset, duration)
+	if err != nil {
+		return result, err
+	}
+	result.Segments = segments
+	result.Summarize()
+	return result, nil
+}
+
+// request uploads the named file, or wav under that name when given. Without
+// a language in options Whisper detects it. Whisper has no phrase hints; the
+// vocabulary's phrases are given as the prompt, whose style and spelling it
+// tends to follow.
+func (w *WhisperVoiceToTextController) request(filePath string, wav []byte, options models.TranscriptionOptions) openai.AudioRequest {
+	req := openai.AudioRequest{
+		Model:    w.model,
+		FilePath: filePath,
+		Format:   openai.AudioResponseFormatVerboseJSON,
+		TimestampGranularities: []openai.TranscriptionTimestampGranularity{
+			openai.TranscriptionTimestampGranularityWord,
+			openai.TranscriptionTimestampGranularitySegment,
+		},
+	}
+	if wav != nil {
+		req.Reader = bytes.NewReader(wav)
+	}
+	if tag, err := language.Parse(options.Language); err == nil {
+		// Whisper takes ISO 639-1 codes only
+		base, _ := tag.Base()
+		req.Language = base.String()
+	}
+	req.Prompt = whisperPrompt(options.Vocabulary.PhraseTexts())
+	return req
+}
+
+// transcribe runs one request. Times from Whisper start where trimmed
+// silence ended, at offset; duration is how long the audio sent is, when
+// known.
+func (w *WhisperVoiceToTextController) transcribe(req openai.AudioRequest, offset, duration time.Duration) ([]models.TranscriptSegmentModel, error) {
+	resp, err := w.client.CreateTranscription(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("whisper transcription failed: %v", err)
+	}
+
+	resp.Language = whisperLanguage(resp.Language)
+
+	if len(resp.Segments) == 0 && strings.TrimSpace(resp.Text) != "" {
+		// The server ignored verbose_json, all there is is the text
+		return []models.TranscriptSegmentModel{{
+			Start:    offset.Seconds(),
+			End:      (offset + duration).Seconds(),
+			Text:     strings.TrimSpace(resp.Text),
+			Language: resp.Language,
+		}}, nil
+	}
+
+	return whisperSegments(resp, offset), nil
+}
+
+// whisperPrompt lists phrases up to the length of prompt Whisper reads
+func whisperPrompt(phrases []string) string {
+	var prompt strings.Builder
+	for _, phrase := range phrases {
+		if prompt.Len()+len(phrase)+2 > maxWhisperPromptBytes {
+			break
+		}
+		if prompt.Len() > 0 {
+			prompt.WriteString(", ")
+		}
+		prompt.WriteString(phrase)
+	}
+	return prompt.String()
+}
+
+// whisperSegments converts Whisper's segments, giving each the words that
+// start inside it. A segment's confidence is the probability of its average
+// token.
+func whisperSegments(resp openai.AudioResponse, offset time.Duration) []models.TranscriptSegmentModel {
+	segments := make([]models.TranscriptSegmentModel, 0, len(resp.Segments))
+	for _, s := range resp.Segments {
+		segments = append(segments, models.TranscriptSegmentModel{
+			Start:      whisperTime(s.Start, offset),
+			End:        whisperTime(s.End, offset),
+			Text:       strings.TrimSpace(s.Text),
+			Confidence: float32(math.Exp(s.AvgLogprob)),
+			Language:   resp.Language,
+		})
+	}
+
+	next := 0
+	for _, w := range resp.Words {
+		word := models.WordModel{
+			Word:  strings.TrimSpace(w.Word),
+			Start: whisperTime(w.Start, offset),
+			End:   whisperTime(w.End, offset),
+		}
+		for next < len(segments)-1 && word.Start >= segments[next].End {
+			next++
+		}
+		if next < len(segments) {
+			segments[next].Words = append(segments[next].Words, word)
+		}
+	}
+
+	return segments
+}
+
+// whisperTime moves seconds from Whisper into the upload, to the millisecond
+func whisperTime(seconds float64, offset time.Duration) float64 {
+	return (offset + time.Duration(seconds*float64(time.Second))).Round(time.Millisecond).Seconds()
+}
+
+// whisperLanguages maps the language names OpenAI reports to their codes.
+// Self-hosted servers mostly report the code already.
+var whisperLanguages = map[string]string{
+	"arabic": "ar", "bengali": "bn", "catalan": "ca", "chinese": "zh",
+	"czech": "cs", "danish": "da", "dutch": "nl", "english": "en",
+	"finnish": "fi", "french": "fr", "german": "de", "greek": "el",
+	"hebrew": "he", "hindi": "hi", "hungarian": "hu", "indonesian": "id",
+	"italian": "it", "japanese": "ja", "korean": "ko", "malay": "ms",
+	"norwegian": "no", "persian": "fa", "polish": "pl", "portuguese": "pt",
+	"romanian": "ro", "russian": "ru", "spanish": "es", "swedish": "sv",
+	"tagalog": "tl", "tamil": "ta", "thai": "th", "turkish": "tr",
+	"ukrainian": "uk", "urdu": "ur", "vietnamese": "vi",
+}
+
+// whisperLanguage reports a detected language as a BCP 47 tag where possible
+func whisperLanguage(name string) string {
+	if code, ok := whisperLanguages[strings.ToLower(name)]; ok {
+		return code
+	}
+	return canonicalLanguage(name)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+const languageFormField = "language"
+
+// requestedLanguage reads the BCP 47 tag in the language form field or query
+// parameter, language.Und when there is none. It writes a 400 response and
+// returns false when the tag is invalid.
+func requestedLanguage(c *gin.Context) (language.Tag, bool) {
+	value := formValue(c, languageFormField)
+	if value == "" {
+		return language.Und, true
+	}
+
+	tag, err := language.Parse(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid language " + value + ": " + err.Error()})
+		return language.Und, false
+	}
+	return tag, true
+}
+
+// acceptedLanguage is the client's first choice in Accept-Language,
+// language.Und when it has none
+func acceptedLanguage(c *gin.Context) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	if err != nil {
+		return language.Und
+	}
+	for _, tag := range tags {
+		if tag != language.Und {
+			return tag
+		}
+	}
+	return language.Und
+}
+
+// replyLanguage is the language the request names, else the session's, else
+// the first of Accept-Language. Like requestedLanguage, it writes a 400
+// response and returns false when the named tag is invalid.
+func (h *VoiceAssistantHandler) replyLanguage(c *gin.Context, sessionID string) (language.Tag, bool) {
+	tag, ok := requestedLanguage(c)
+	if !ok || tag != language.Und {
+		return tag, ok
+	}
+
+	tag, err := h.chatController.SessionLanguage(c.Request.Context(), sessionID)
+	if err != nil {
+		log.Printf("Failed to load session language: %v", err)
+	}
+	if tag != language.Und {
+		return tag, true
+	}
+	return acceptedLanguage(c), true
+}
+
+// transcribeTurn transcribes an upload for the conversation, recognizing it
+// in the expected language when there is one. The language heard, or else
+// the one expected, becomes the conversation's.
+func transcribeTurn(
+	provider interfaces.VoiceToTextInterface,
+	filePath string,
+	options models.TranscriptionOptions,
+	expected language.Tag,
+) (models.VoiceToTextModel, services.ConversationInput, error) {
+	if expected != language.Und {
+		options.Language = expected.String()
+	}
+
+	transcript, err := controllers.TranscribeAudio(provider, filePath, options)
+	if err != nil {
+		return transcript, services.ConversationInput{}, err
+	}
+
+	input := services.TranscriptInput(transcript)
+	if input.Language == language.Und {
+		input.Language = expected
+	}
+	return transcript, input, nil
+}
+
+// transcriptionErrorMessage explains a failed transcription in lang
+func transcriptionErrorMessage(lang language.Tag, err error) string {
+	if errors.Is(err, services.ErrNoSpeechDetected) {
+		return services.Localize(lang, services.MsgNoSpeech)
+	}
+	return services.Localize(lang, services.MsgTranscriptionFailed, err)
+}
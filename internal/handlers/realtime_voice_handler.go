@@ -0,0 +1,641 @@
+package handlers
+
+// Realtime voice protocol for GET /v1/voice-assistant/ws
+//
+// The connection is full duplex. Text frames carry JSON control messages
+// (models.RealtimeMessage), binary frames carry audio.
+//
+// Client to server:
+//
+//	{"type":"start","sample_rate":16000,"session_id":"...","stt_provider":"...","language":"es-ES",
+//	 "voice":{"voice_id":"...","speed":1.1}}
+//	    Begins an utterance. Every field except type is optional; sample_rate
+//	    is 8000 to 48000 and defaults to 16000, session_id defaults to the
+//	    one issued on connect. The language defaults to the language query
+//	    parameter, then the session's, then Accept-Language. The voice
+//	    settings given add to those of earlier starts and of the query
+//	    parameters, and take precedence over the session's voice. A start
+//	    while the assistant is still answering interrupts that answer.
+//	<binary>  little-endian 16-bit mono PCM at sample_rate, any frame size
+//	{"type":"stop"}    ends the utterance and asks for an answer
+//	{"type":"cancel"}  drops buffered audio and interrupts the current answer
+//	{"type":"reset"}   clears the conversation history and voice of the session
+//
+// Server to client, for every answered utterance:
+//
+//	{"type":"ready","session_id":"..."}                  once, after connect
+//	{"type":"speech_start","offset_ms":N}                while recording, when
+//	{"type":"speech_end","offset_ms":N}                  voice activity changes
+//	{"type":"partial_transcript","text":"..."}           zero or more
+//	{"type":"final_transcript","text":"...","language":"es-ES"}
+//	{"type":"assistant_delta","text":"..."}              one or more
+//	{"type":"audio_start","content_type":"audio/mpeg"}   before the first audio
+//	<binary>  one complete clip per sentence, in order
+//	{"type":"assistant_done","text":"<full reply>"}
+//	{"type":"audio_end"}                                 if audio_start was sent
+//	{"type":"error","error":"..."}                       whenever a step fails
+//
+// An utterance in which no speech was detected is not answered; the server
+// replies with an error saying so instead. Errors about the audio are in the
+// language of the utterance, and one in which nothing was recognized is
+// answered with a spoken request to repeat it.
+//
+// Speech is synthesized while the reply is generated, so audio_start and the
+// first clips arrive interleaved with assistant_delta messages.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"golang.org/x/text/language"
+)
+
+const (
+	realtimeDefaultSampleRate = 16000
+	realtimeMinSampleRate     = 8000
+	realtimeMaxSampleRate     = 48000
+	realtimeMaxFrameBytes     = 1 << 20
+	// About five minutes of 16 kHz mono audio
+	realtimeMaxUtteranceBytes = 10 << 20
+)
+
+var realtimeUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// Same policy as the REST endpoints, which allow all origins
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func (h *VoiceAssistantHandler) RealtimeVoiceAssistantHandler(c *gin.Context) {
+	sessionID := sessionID(c)
+	requested, ok := requestedLanguage(c)
+	if !ok {
+		return
+	}
+	vocabulary, err := h.vocabularies.ForTranscription(c.Request.Context(), tenantID(c, h.vocabularies))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load vocabulary: " + err.Error()})
+		return
+	}
+	voice, ok := requestedVoice(c)
+	if !ok {
+		return
+	}
+	if err := h.voices.Validate(c.Request.Context(), voice); err != nil {
+		c.JSON(voiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ws, err := realtimeUpgrader.Upgrade(c.Writer, c.Request, c.Writer.Header())
+	if err != nil {
+		// The upgrader has already replied with an HTTP error
+		log.Printf("Failed to upgrade realtime connection: %v", err)
+		return
+	}
+	defer ws.Close()
+	ws.SetReadLimit(realtimeMaxFrameBytes)
+
+	session := &realtimeSession{
+		handler:    h,
+		conn:       &realtimeConn{ws: ws},
+		sessionID:  sessionID,
+		sampleRate: realtimeDefaultSampleRate,
+		language:   requested,
+		accepted:   acceptedLanguage(c),
+		vocabulary: vocabulary,
+		voice:      voice,
+	}
+	session.run(c.Request.Context())
+}
+
+// realtimeConn serializes writes, which gorilla/websocket requires
+type realtimeConn struct {
+	mu sync.Mutex
+	ws *websocket.Conn
+}
+
+func (rc *realtimeConn) sendJSON(message models.RealtimeMessage) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.ws.WriteJSON(message)
+}
+
+func (rc *realtimeConn) sendBinary(data []byte) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.ws.WriteMessage(websocket.BinaryMessage, data)
+}
+
+func (rc *realtimeConn) sendError(err error) {
+	if sendErr := rc.sendJSON(models.RealtimeMessage{Type: models.RealtimeTypeError, Error: err.Error()}); sendErr != nil {
+		log.Printf("Failed to send realtime error: %v", sendErr)
+	}
+}
+
+// realtimeSession is the state of one connection. Only the read loop touches
+// it; answers run in their own goroutine so the client can keep talking or
+// interrupt while the assistant responds.
+type realtimeSession struct {
+	handler     *VoiceAssistantHandler
+	conn        *realtimeConn
+	sessionID   string
+	sampleRate  int
+	sttProvider string
+	// language is the one the client asked for, accepted its Accept-Language
+	// and expected what the current utterance is recognized in
+	language language.Tag
+	accepted language.Tag
+	expected language.Tag
+	// vocabulary is the tenant's, nil when it has none
+	vocabulary *models.VocabularyModel
+	// voice is what the client chose over the session's voice
+	voice models.VoiceSelectionModel
+
+	// Utterance audio goes to stream when the provider recognizes while
+	// recording, and is buffered in audio otherwise
+	audio      bytes.Buffer
+	audioBytes int
+	stream     *realtimeStream
+	vad        *services.VoiceActivityDetector
+	recording  bool
+	cancelTurn context.CancelFunc
+	// turnDone is closed once the latest turn has returned
+	turnDone chan struct{}
+	turns    sync.WaitGroup
+}
+
+// realtimeStream feeds one utterance to a streaming recognizer and collects
+// its final results
+type realtimeStream struct {
+	writer *io.PipeWriter
+	cancel context.CancelFunc
+	done   chan struct{}
+	input  services.ConversationInput
+	err    error
+}
+
+func (s *realtimeSession) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		s.abortStream()
+		cancel()
+		s.turns.Wait()
+	}()
+
+	if err := s.conn.sendJSON(models.RealtimeMessage{Type: models.RealtimeTypeReady, SessionID: s.sessionID}); err != nil {
+		return
+	}
+
+	for {
+		messageType, data, err := s.conn.ws.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("Realtime connection closed: %v", err)
+			}
+			return
+		}
+
+		switch messageType {
+		case websocket.BinaryMessage:
+			s.handleAudio(data)
+		case websocket.TextMessage:
+			var message models.RealtimeMessage
+			if err := json.Unmarshal(data, &message); err != nil {
+				s.conn.sendError(fmt.Errorf("invalid control message: %v", err))
+				continue
+			}
+			s.handleControl(ctx, message)
+		}
+	}
+}
+
+func (s *realtimeSession) handleAudio(data []byte) {
+	if !s.recording {
+		s.conn.sendError(fmt.Errorf("send a start message before audio"))
+		return
+	}
+	if s.audioBytes+len(data) > realtimeMaxUtteranceBytes {
+		s.abortStream()
+		s.recording = false
+		s.audio.Reset()
+		// 16-bit mono PCM
+		seconds := realtimeMaxUtteranceBytes / (2 * s.sampleRate)
+		s.conn.sendError(errors.New(services.Localize(s.expected, services.MsgUtteranceTooLong, seconds)))
+		return
+	}
+	s.audioBytes += len(data)
+	s.detectSpeech(data)
+
+	if s.stream == nil {
+		s.audio.Write(data)
+		return
+	}
+
+	// Fails only once the recognizer has stopped; its error surfaces at stop
+	s.stream.writer.Write(data)
+}
+
+func (s *realtimeSession) handleControl(ctx context.Context, message models.RealtimeMessage) {
+	switch message.Type {
+	case models.RealtimeTypeStart:
+		s.interrupt()
+		s.abortStream()
+		if message.SampleRate != 0 && (message.SampleRate < realtimeMinSampleRate || message.SampleRate > realtimeMaxSampleRate) {
+			s.conn.sendError(fmt.Errorf("invalid sample_rate %d: must be between %d and %d", message.SampleRate, realtimeMinSampleRate, realtimeMaxSampleRate))
+			return
+		}
+		if message.SampleRate > 0 {
+			s.sampleRate = message.SampleRate
+		}
+		if message.SessionID != "" {
+			s.sessionID = message.SessionID
+		}
+		if message.STTProvider != "" {
+			s.sttProvider = message.STTProvider
+		}
+		if message.Language != "" {
+			tag, err := language.Parse(message.Language)
+			if err != nil {
+				s.conn.sendError(fmt.Errorf("invalid language %q: %v", message.Language, err))
+				return
+			}
+			s.language = tag
+		}
+		if message.Voice != nil {
+			voice := s.voice.Override(*message.Voice)
+			if err := s.handler.voices.Validate(ctx, voice); err != nil {
+				s.conn.sendError(err)
+				return
+			}
+			s.voice = voice
+		}
+		s.expected = s.expectedLanguage(ctx)
+		s.audio.Reset()
+		s.audioBytes = 0
+		s.recording = true
+		s.vad = nil
+		if config := s.handler.sttRegistry.VADConfig(); config != nil {
+			s.vad = services.NewVoiceActivityDetector(s.sampleRate, *config)
+		}
+		s.openStream(ctx)
+
+	case models.RealtimeTypeStop:
+		if !s.recording {
+			s.conn.sendError(fmt.Errorf("stop received without start"))
+			return
+		}
+		s.recording = false
+		if s.vad != nil {
+			s.sendSpeechEvents(s.vad.Flush())
+			if !s.vad.SpeechSeen() {
+				// Nothing to answer, and no need to pay for recognition
+				s.abortStream()
+				s.audio.Reset()
+				s.conn.sendError(errors.New(services.Localize(s.expected, services.MsgNoSpeech)))
+				return
+			}
+		}
+		s.startTurn(ctx, s.finishUtterance())
+
+	case models.RealtimeTypeCancel:
+		s.interrupt()
+		s.abortStream()
+		s.audio.Reset()
+		s.recording = false
+
+	case models.RealtimeTypeReset:
+		// Queued behind the running turn, which would otherwise save the
+		// history again after it was reset
+		previous := s.turnDone
+		done := make(chan struct{})
+		s.turnDone = done
+		s.turns.Add(1)
+		go func() {
+			defer s.turns.Done()
+			defer close(done)
+			if previous != nil {
+				<-previous
+			}
+			if err := s.handler.chatController.ResetConversation(s.sessionID); err != nil {
+				s.conn.sendError(err)
+				return
+			}
+			if err := s.handler.voices.DeleteSessionVoice(ctx, s.sessionID); err != nil {
+				s.conn.sendError(err)
+			}
+		}()
+
+	default:
+		s.conn.sendError(fmt.Errorf("unknown message type %q", message.Type))
+	}
+}
+
+// transcriptionOptions asks for the expected language, or detection when
+// there is none, with the tenant's vocabulary
+func (s *realtimeSession) transcriptionOptions() models.TranscriptionOptions {
+	options := models.TranscriptionOptions{Vocabulary: s.vocabulary}
+	if s.expected != language.Und {
+		options.Language = s.expected.String()
+	}
+	return options
+}
+
+// expectedLanguage is the language asked for, else the session's, else the
+// Accept-Language of the connection
+func (s *realtimeSession) expectedLanguage(ctx context.Context) language.Tag {
+	if s.language != language.Und {
+		return s.language
+	}
+
+	tag, err := s.handler.chatController.SessionLanguage(ctx, s.sessionID)
+	if err != nil {
+		log.Printf("Failed to load session language: %v", err)
+	}
+	if tag != language.Und {
+		return tag
+	}
+	return s.accepted
+}
+
+// detectSpeech runs voice activity detection over incoming PCM and tells the
+// client where speech starts and ends
+func (s *realtimeSession) detectSpeech(pcm []byte) {
+	if s.vad == nil {
+		return
+	}
+	s.sendSpeechEvents(s.vad.Push(services.SamplesFromPCM16(pcm)))
+}
+
+func (s *realtimeSession) sendSpeechEvents(events []services.VADEvent) {
+	for _, event := range events {
+		messageType := models.RealtimeTypeSpeechEnd
+		if event.Speaking {
+			messageType = models.RealtimeTypeSpeechStart
+		}
+		offset := event.At.Milliseconds()
+		s.conn.sendJSON(models.RealtimeMessage{Type: messageType, OffsetMs: &offset})
+	}
+}
+
+// openStream starts recognizing the utterance while it is being recorded,
+// if the provider supports it. Partial transcripts are forwarded as they come.
+func (s *realtimeSession) openStream(ctx context.Context) {
+	provider, err := s.handler.sttRegistry.Resolve(s.sttProvider)
+	if err != nil {
+		// Reported again when the utterance is transcribed
+		return
+	}
+	streamingProvider, ok := provider.(interfaces.StreamingVoiceToTextInterface)
+	if !ok {
+		return
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	reader, writer := io.Pipe()
+
+	results, err := streamingProvider.StreamVoiceToText(streamCtx, reader, s.sampleRate, s.transcriptionOptions())
+	if err != nil {
+		cancel()
+		log.Printf("Falling back to buffered recognition: %v", err)
+		return
+	}
+
+	stream := &realtimeStream{
+		writer: writer,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.stream = stream
+
+	// The read loop changes these for the next utterance while this one is
+	// still being recognized
+	heard := s.expected
+	var replacer *services.Replacer
+	if s.vocabulary != nil {
+		replacer = services.NewReplacer(s.vocabulary.Replacements)
+	}
+
+	go func() {
+		defer close(stream.done)
+		// Unblock the read loop if the recognizer stops reading early
+		defer reader.Close()
+
+		var finals []string
+		for result := range results {
+			if result.Err != nil {
+				stream.err = fmt.Errorf("failed to transcribe audio: %v", result.Err)
+				reader.CloseWithError(result.Err)
+				continue
+			}
+
+			result.Text = replacer.Replace(result.Text)
+			text := strings.Join(append(finals, result.Text), " ")
+			if result.IsFinal {
+				finals = append(finals, result.Text)
+				if tag, err := language.Parse(result.Language); err == nil {
+					heard = tag
+				}
+			}
+			if streamCtx.Err() == nil {
+				s.conn.sendJSON(models.RealtimeMessage{Type: models.RealtimeTypePartialTranscript, Text: text})
+			}
+		}
+		stream.input = services.ConversationInput{Text: strings.Join(finals, " "), Language: heard}
+	}()
+}
+
+// finishUtterance ends recording and returns how to obtain the transcript
+func (s *realtimeSession) finishUtterance() func() (services.ConversationInput, error) {
+	if stream := s.stream; stream != nil {
+		s.stream = nil
+		stream.writer.Close()
+		return func() (services.ConversationInput, error) {
+			<-stream.done
+			stream.cancel()
+			return stream.input, stream.err
+		}
+	}
+
+	pcm := make([]byte, s.audio.Len())
+	copy(pcm, s.audio.Bytes())
+	s.audio.Reset()
+
+	turn := realtimeTurn{session: s, sampleRate: s.sampleRate, sttProvider: s.sttProvider, options: s.transcriptionOptions(), expected: s.expected}
+	return func() (services.ConversationInput, error) {
+		return turn.transcribe(pcm)
+	}
+}
+
+// abortStream discards the utterance being recognized, if any
+func (s *realtimeSession) abortStream() {
+	if s.stream != nil {
+		s.stream.cancel()
+		s.stream.writer.CloseWithError(context.Canceled)
+		s.stream = nil
+	}
+}
+
+// interrupt cancels the answer in flight, if any
+func (s *realtimeSession) interrupt() {
+	if s.cancelTurn != nil {
+		s.cancelTurn()
+		s.cancelTurn = nil
+	}
+}
+
+// startTurn answers the utterance once the interrupted turn, if any, has
+// returned, so two answers never write to the conversation or the client at
+// the same time
+func (s *realtimeSession) startTurn(ctx context.Context, transcribe func() (services.ConversationInput, error)) {
+	s.interrupt()
+
+	turnCtx, cancel := context.WithCancel(ctx)
+	s.cancelTurn = cancel
+	previous := s.turnDone
+	done := make(chan struct{})
+	s.turnDone = done
+
+	turn := realtimeTurn{
+		session:     s,
+		sessionID:   s.sessionID,
+		sampleRate:  s.sampleRate,
+		sttProvider: s.sttProvider,
+		options:     s.transcriptionOptions(),
+		expected:    s.expected,
+		voice:       s.voice,
+	}
+
+	s.turns.Add(1)
+	go func() {
+		defer s.turns.Done()
+		defer close(done)
+		defer cancel()
+		if previous != nil {
+			<-previous
+		}
+		if err := turn.respond(turnCtx, transcribe); err != nil && turnCtx.Err() == nil {
+			s.conn.sendError(err)
+		}
+	}()
+}
+
+// realtimeTurn answers one utterance with the settings captured at stop time
+type realtimeTurn struct {
+	session     *realtimeSession
+	sessionID   string
+	sampleRate  int
+	sttProvider string
+	options     models.TranscriptionOptions
+	expected    language.Tag
+	voice       models.VoiceSelectionModel
+}
+
+func (t realtimeTurn) respond(ctx context.Context, transcribe func() (services.ConversationInput, error)) error {
+	conn := t.session.conn
+	h := t.session.handler
+
+	input, err := transcribe()
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	final := models.RealtimeMessage{Type: models.RealtimeTypeFinalTranscript, Text: input.Text}
+	if input.Language != language.Und {
+		final.Language = input.Language.String()
+	}
+	if err := conn.sendJSON(final); err != nil {
+		return err
+	}
+
+	voice, err := h.voices.ForSpeech(ctx, t.sessionID, t.voice)
+	if err != nil {
+		return err
+	}
+
+	// Audio for each sentence follows as soon as it is synthesized, so
+	// audio_start usually arrives before the reply is complete
+	contentType := h.ttsPipeline.Provider().ContentType()
+	audioStarted := false
+	assistantResponse, err := h.chatController.ProcessConversationSpoken(ctx, t.sessionID, input, h.ttsPipeline, voice,
+		func(delta string) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return conn.sendJSON(models.RealtimeMessage{Type: models.RealtimeTypeAssistantDelta, Text: delta})
+		},
+		func(audio []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !audioStarted {
+				if err := conn.sendJSON(models.RealtimeMessage{Type: models.RealtimeTypeAudioStart, ContentType: contentType}); err != nil {
+					return err
+				}
+				audioStarted = true
+			}
+			return conn.sendBinary(audio)
+		})
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := conn.sendJSON(models.RealtimeMessage{Type: models.RealtimeTypeAssistantDone, Text: assistantResponse}); err != nil {
+		return err
+	}
+	if !audioStarted {
+		return nil
+	}
+
+	return conn.sendJSON(models.RealtimeMessage{Type: models.RealtimeTypeAudioEnd})
+}
+
+// transcribe hands the buffered PCM to the speech-to-text provider as a WAV file
+func (t realtimeTurn) transcribe(pcm []byte) (services.ConversationInput, error) {
+	provider, err := t.session.handler.sttRegistry.Resolve(t.sttProvider)
+	if err != nil {
+		return services.ConversationInput{}, err
+	}
+
+	tmpFile, err := os.CreateTemp("", "realtime-*.wav")
+	if err != nil {
+		return services.ConversationInput{}, fmt.Errorf("failed to save audio: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(services.EncodeWAV(pcm, t.sampleRate, 1)); err != nil {
+		tmpFile.Close()
+		return services.ConversationInput{}, fmt.Errorf("failed to save audio: %v", err)
+	}
+	tmpFile.Close()
+
+	result, err := controllers.TranscribeAudio(provider, tmpFile.Name(), t.options)
+	if err != nil {
+		return services.ConversationInput{}, fmt.Errorf("failed to transcribe audio: %v", err)
+	}
+
+	input := services.TranscriptInput(result)
+	if input.Language == language.Und {
+		input.Language = t.expected
+	}
+	return input, nil
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sashabaranov/go-openai"
+)
+
+// newTestRealtime serves the realtime endpoint with the fake recognizer,
+// which always hears transcript, llm and the tone voice
+func newTestRealtime(t *testing.T, llm interfaces.LLMProvider, transcript string) (*websocket.Conn, *controllers.ChatGPTController) {
+	t.Helper()
+
+	registry := controllers.NewVoiceToTextRegistry(controllers.VoiceToTextProviderFake)
+	registry.Register(controllers.VoiceToTextProviderFake, controllers.NewFakeVoiceToTextController(transcript))
+	tts := controllers.NewToneTTSProvider()
+	chat := controllers.NewChatGPTControllerWithProvider(llm, services.NewSessionManager(llm.Model(), 0, 0, nil))
+
+	handler := &VoiceAssistantHandler{
+		sttRegistry:    registry,
+		vocabularies:   controllers.NewVocabularyController(services.NewMemoryVocabularyStore()),
+		voices:         controllers.NewVoiceController(tts, services.NewMemorySessionVoiceStore(0, 0)),
+		chatController: chat,
+		ttsPipeline:    controllers.NewTTSPipeline(tts, 2),
+	}
+
+	router := gin.New()
+	router.GET("/ws", handler.RealtimeVoiceAssistantHandler)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?session_id=realtime", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() {
+		ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		ws.Close()
+	})
+
+	if ready := readRealtime(t, ws); ready.message.Type != models.RealtimeTypeReady || ready.message.SessionID != "realtime" {
+		t.Fatalf("first message = %+v, want ready for the session", ready.message)
+	}
+	return ws, chat
+}
+
+// realtimeFrame is a control message, or audio when binary is set
+type realtimeFrame struct {
+	message models.RealtimeMessage
+	binary  []byte
+}
+
+func readRealtime(t *testing.T, ws *websocket.Conn) realtimeFrame {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	messageType, data, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if messageType == websocket.BinaryMessage {
+		return realtimeFrame{binary: data}
+	}
+	var frame realtimeFrame
+	if err := json.Unmarshal(data, &frame.message); err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+// readRealtimeUntil returns the frames up to and including the first
+// control message of type last
+func readRealtimeUntil(t *testing.T, ws *websocket.Conn, last string) []realtimeFrame {
+	t.Helper()
+	var frames []realtimeFrame
+	for {
+		frame := readRealtime(t, ws)
+		frames = append(frames, frame)
+		if frame.binary == nil && frame.message.Type == last {
+			return frames
+		}
+	}
+}
+
+func sendRealtime(t *testing.T, ws *websocket.Conn, message models.RealtimeMessage) {
+	t.Helper()
+	if err := ws.WriteJSON(message); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// speak sends an utterance of a second of audio in 100 ms frames
+func speak(t *testing.T, ws *websocket.Conn) {
+	t.Helper()
+	sendRealtime(t, ws, models.RealtimeMessage{Type: models.RealtimeTypeStart, SampleRate: 16000})
+	for i := 0; i < 10; i++ {
+		if err := ws.WriteMessage(websocket.BinaryMessage, make([]byte, 3200)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sendRealtime(t, ws, models.RealtimeMessage{Type: models.RealtimeTypeStop})
+}
+
+func TestRealtimeTurn(t *testing.T) {
+	ws, chat := newTestRealtime(t, controllers.NewScriptedLLMProvider("The lights are on now."), "turn on the lights")
+
+	speak(t, ws)
+	frames := readRealtimeUntil(t, ws, models.RealtimeTypeAudioEnd)
+
+	var types []string
+	var deltas strings.Builder
+	clips := 0
+	for _, frame := range frames {
+		if frame.binary != nil {
+			clips++
+			continue
+		}
+		switch frame.message.Type {
+		case models.RealtimeTypeFinalTranscript:
+			if frame.message.Text != "turn on the lights" {
+				t.Errorf("final transcript = %q", frame.message.Text)
+			}
+		case models.RealtimeTypeAssistantDelta:
+			deltas.WriteString(frame.message.Text)
+			continue
+		case models.RealtimeTypeAudioStart:
+			if frame.message.ContentType != "audio/wav" {
+				t.Errorf("audio content type = %q, want the tone provider's", frame.message.ContentType)
+			}
+		case models.RealtimeTypeAssistantDone:
+			if frame.message.Text != "The lights are on now." {
+				t.Errorf("assistant_done text = %q", frame.message.Text)
+			}
+		case models.RealtimeTypeError:
+			t.Fatalf("error: %s", frame.message.Error)
+		}
+		if len(types) == 0 || types[len(types)-1] != frame.message.Type {
+			types = append(types, frame.message.Type)
+		}
+	}
+
+	want := []string{
+		models.RealtimeTypePartialTranscript,
+		models.RealtimeTypeFinalTranscript,
+		models.RealtimeTypeAudioStart,
+		models.RealtimeTypeAssistantDone,
+		models.RealtimeTypeAudioEnd,
+	}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("messages = %v, want %v", types, want)
+	}
+	if deltas.String() != "The lights are on now." {
+		t.Errorf("deltas add up to %q", deltas.String())
+	}
+	if clips == 0 {
+		t.Error("no audio clips sent")
+	}
+
+	history, err := chat.GetConversationHistory("realtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(history.Messages); n != 3 {
+		t.Errorf("history has %d messages, want the prompt and one turn", n)
+	}
+}
+
+// stallingLLMProvider holds its first reply until the turn is cancelled
+type stallingLLMProvider struct {
+	*controllers.ScriptedLLMProvider
+	once sync.Once
+	// stalled is closed once the first reply is being held
+	stalled chan struct{}
+}
+
+func (p *stallingLLMProvider) StreamChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage, onDelta func(delta string) error) (string, openai.Usage, error) {
+	first := false
+	p.once.Do(func() { first = true })
+	if first {
+		close(p.stalled)
+		<-ctx.Done()
+		return "", openai.Usage{}, ctx.Err()
+	}
+	return p.ScriptedLLMProvider.StreamChatCompletion(ctx, messages, onDelta)
+}
+
+func TestRealtimeInterrupt(t *testing.T) {
+	llm := &stallingLLMProvider{ScriptedLLMProvider: controllers.NewScriptedLLMProvider("Second answer."), stalled: make(chan struct{})}
+	ws, chat := newTestRealtime(t, llm, "hello there")
+
+	speak(t, ws)
+	readRealtimeUntil(t, ws, models.RealtimeTypeFinalTranscript)
+	select {
+	case <-llm.stalled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first turn never reached the model")
+	}
+
+	// Cancel the stalled answer, then ask again
+	sendRealtime(t, ws, models.RealtimeMessage{Type: models.RealtimeTypeCancel})
+	speak(t, ws)
+
+	for _, frame := range readRealtimeUntil(t, ws, models.RealtimeTypeAudioEnd) {
+		switch frame.message.Type {
+		case models.RealtimeTypeAssistantDone:
+			if frame.message.Text != "Second answer." {
+				t.Errorf("assistant_done text = %q, want the second answer", frame.message.Text)
+			}
+		case models.RealtimeTypeError:
+			t.Errorf("error: %s", frame.message.Error)
+		}
+	}
+
+	// The interrupted turn left nothing behind
+	history, err := chat.GetConversationHistory("realtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, message := range history.Messages[1:] {
+		contents = append(contents, message.Role+": "+message.Content)
+	}
+	if want := "user: hello there|assistant: Second answer."; strings.Join(contents, "|") != want {
+		t.Errorf("history = %q, want %q", contents, want)
+	}
+}
+
+func TestRealtimeRejectsSampleRate(t *testing.T) {
+	ws, _ := newTestRealtime(t, controllers.NewScriptedLLMProvider("Hi."), "hello")
+
+	for _, rate := range []int{-1, 1, 7999, 48001, 10000000} {
+		sendRealtime(t, ws, models.RealtimeMessage{Type: models.RealtimeTypeStart, SampleRate: rate})
+		if frame := readRealtime(t, ws); frame.message.Type != models.RealtimeTypeError || !strings.Contains(frame.message.Error, "sample_rate") {
+			t.Errorf("start at %d Hz answered %+v, want a sample_rate error", rate, frame.message)
+		}
+	}
+
+	// Audio is refused, since none of those starts began an utterance
+	if err := ws.WriteMessage(websocket.BinaryMessage, make([]byte, 320)); err != nil {
+		t.Fatal(err)
+	}
+	if frame := readRealtime(t, ws); frame.message.Type != models.RealtimeTypeError {
+		t.Errorf("audio after rejected start answered %+v, want an error", frame.message)
+	}
+}
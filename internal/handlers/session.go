@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	sessionHeader    = "X-Session-ID"
+	sessionCookie    = "session_id"
+	sessionFormField = "session_id"
+	sessionCookieAge = 24 * 60 * 60
+)
+
+// sessionID identifies the caller's conversation. It is taken from the
+// X-Session-ID header, the session_id cookie, the session_id form field or
+// the session_id query parameter, in that order. A new ID is issued when
+// none is present, and the ID is always echoed back so clients can keep
+// using it.
+func sessionID(c *gin.Context) string {
+	id := c.GetHeader(sessionHeader)
+	if id == "" {
+		id, _ = c.Cookie(sessionCookie)
+	}
+	if id == "" {
+		id = c.PostForm(sessionFormField)
+	}
+	if id == "" {
+		id = c.Query(sessionFormField)
+	}
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	c.Header(sessionHeader, id)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, id, sessionCookieAge, "/", "", false, true)
+
+	return id
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"golang-gin-boilerplate/internal/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tenantHeader     = "X-Tenant-ID"
+	tenantQueryParam = "tenant_id"
+)
+
+// tenantID identifies whose vocabulary applies. Callers name the tenant
+// themselves, so without authentication in front of this server any caller
+// can read or change any tenant's vocabulary. Deployments with more than one
+// tenant should put a proxy in front that authenticates callers and sets
+// the header configured as the vocabularies' TenantHeader, which is then the
+// only source. Otherwise the X-Tenant-ID header or the tenant_id query
+// parameter names the tenant, and requests naming none share the default
+// one.
+func tenantID(c *gin.Context, vocabularies *controllers.VocabularyController) string {
+	var id string
+	if vocabularies.TenantHeader != "" {
+		id = c.GetHeader(vocabularies.TenantHeader)
+	} else {
+		id = c.GetHeader(tenantHeader)
+		if id == "" {
+			id = c.Query(tenantQueryParam)
+		}
+	}
+	if id == "" {
+		id = controllers.DefaultTenantID
+	}
+	return id
+}
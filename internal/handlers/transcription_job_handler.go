@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"golang-gin-boilerplate/internal/controllers"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+type TranscriptionJobHandler struct {
+	sttRegistry  *controllers.VoiceToTextRegistry
+	jobs         *controllers.TranscriptionJobController
+	vocabularies *controllers.VocabularyController
+}
+
+func NewTranscriptionJobHandler(
+	sttRegistry *controllers.VoiceToTextRegistry,
+	jobs *controllers.TranscriptionJobController,
+	vocabularies *controllers.VocabularyController,
+) *TranscriptionJobHandler {
+	return &TranscriptionJobHandler{
+		sttRegistry:  sttRegistry,
+		jobs:         jobs,
+		vocabularies: vocabularies,
+	}
+}
+
+// CreateTranscriptionJobHandler queues the audio_file upload and answers 202
+// with the job at once. Poll GET /v1/transcriptions/:id, or pass webhook_url
+// to have the finished job POSTed there. Webhooks to private addresses are
+// refused unless their host is allowed.
+func (h *TranscriptionJobHandler) CreateTranscriptionJobHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	if _, ok := resolveVoiceToTextProvider(c, h.sttRegistry); !ok {
+		return
+	}
+
+	options, ok := transcriptionOptions(c, h.vocabularies)
+	if !ok {
+		return
+	}
+	if lang := acceptedLanguage(c); options.Language == "" && lang != language.Und {
+		options.Language = lang.String()
+	}
+
+	filePath, ok := saveUploadedAudio(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.jobs.Submit(c.Request.Context(), filePath, sttProviderName(c), c.PostForm("webhook_url"), options)
+	if err != nil {
+		removeUploadedAudio(filePath)
+		status := http.StatusInternalServerError
+		if errors.Is(err, controllers.ErrTranscriptionQueueFull) {
+			status = http.StatusServiceUnavailable
+		} else if errors.Is(err, controllers.ErrWebhookNotAllowed) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Location", "/v1/transcriptions/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *TranscriptionJobHandler) GetTranscriptionJobHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	job, ok, err := h.jobs.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transcription job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
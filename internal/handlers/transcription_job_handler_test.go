@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateTranscriptionJobWebhook(t *testing.T) {
+	registry := controllers.NewVoiceToTextRegistry(controllers.VoiceToTextProviderFake)
+	registry.Register(controllers.VoiceToTextProviderFake, controllers.NewFakeVoiceToTextController("turn on the lights"))
+	jobs, err := controllers.NewTranscriptionJobController(registry, services.NewMemoryTranscriptionJobStore(), controllers.TranscriptionJobConfig{
+		QueueSize: 1,
+		SpoolDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vocabularies := controllers.NewVocabularyController(services.NewMemoryVocabularyStore())
+
+	router := gin.New()
+	router.POST("/v1/transcriptions", NewTranscriptionJobHandler(registry, jobs, vocabularies).CreateTranscriptionJobHandler)
+
+	// Refused by the job controller, before anything is queued
+	for _, webhookURL := range []string{"ftp://example.com/hook", "/relative", "http://127.0.0.1/hook", "http://10.0.0.1/hook"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, uploadRequest(t, "/v1/transcriptions", "recording.wav", silentWAV(), map[string]string{"webhook_url": webhookURL}))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("webhook %q: status %d, want 400: %s", webhookURL, recorder.Code, recorder.Body)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, uploadRequest(t, "/v1/transcriptions", "recording.wav", silentWAV(), nil))
+	if recorder.Code != http.StatusAccepted {
+		t.Errorf("without a webhook: status %d, want 202: %s", recorder.Code, recorder.Body)
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+// saveUploadedAudio stores the audio_file form upload under a unique
+// temporary name, so concurrent uploads of "recording.wav" never clash. It
+// writes the error response itself and returns false on failure; on success
+// the caller must call removeUploadedAudio.
+func saveUploadedAudio(c *gin.Context) (string, bool) {
+	// Parse file from the form
+	file, err := c.FormFile("audio_file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to retrieve audio file",
+		})
+		return "", false
+	}
+
+	tmpFile, err := os.CreateTemp("", "upload-*"+filepath.Ext(file.Filename))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save audio file",
+		})
+		return "", false
+	}
+	tmpFile.Close()
+
+	// Save the uploaded file locally
+	if err := c.SaveUploadedFile(file, tmpFile.Name()); err != nil {
+		removeUploadedAudio(tmpFile.Name())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save audio file",
+		})
+		return "", false
+	}
+
+	return tmpFile.Name(), true
+}
+
+func removeUploadedAudio(filePath string) {
+	if err := os.Remove(filePath); err != nil {
+		log.Printf("Failed to remove temporary file: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VocabularyHandler manages the custom vocabulary of the caller's tenant.
+// Its phrases bias recognition and its replacements rewrite every
+// transcript:
+//
+//	PUT /v1/vocabulary
+//	{"phrases":[{"phrase":"Acme Cloud","boost":15}],
+//	 "replacements":[{"from":"acne cloud","to":"Acme Cloud"}]}
+//
+// When the vocabularies have a WriteToken, PUT and DELETE need it in an
+// Authorization: Bearer header.
+type VocabularyHandler struct {
+	vocabularies *controllers.VocabularyController
+}
+
+func NewVocabularyHandler(vocabularies *controllers.VocabularyController) *VocabularyHandler {
+	return &VocabularyHandler{vocabularies: vocabularies}
+}
+
+func (h *VocabularyHandler) GetVocabularyHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	vocabulary, err := h.vocabularies.Get(c.Request.Context(), tenantID(c, h.vocabularies))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, vocabulary)
+}
+
+// PutVocabularyHandler replaces the whole vocabulary
+func (h *VocabularyHandler) PutVocabularyHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	if !h.vocabularies.CanWrite(c.GetHeader("Authorization")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Changing vocabularies needs the write token"})
+		return
+	}
+
+	var vocabulary models.VocabularyModel
+	if err := c.ShouldBindJSON(&vocabulary); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vocabulary: " + err.Error()})
+		return
+	}
+
+	vocabulary, err := h.vocabularies.Put(c.Request.Context(), tenantID(c, h.vocabularies), vocabulary)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, controllers.ErrInvalidVocabulary) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, vocabulary)
+}
+
+func (h *VocabularyHandler) DeleteVocabularyHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	if !h.vocabularies.CanWrite(c.GetHeader("Authorization")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Changing vocabularies needs the write token"})
+		return
+	}
+
+	if err := h.vocabularies.Delete(c.Request.Context(), tenantID(c, h.vocabularies)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestVocabularyRouter(vocabularies *controllers.VocabularyController) *gin.Engine {
+	handler := NewVocabularyHandler(vocabularies)
+	router := gin.New()
+	router.GET("/v1/vocabulary", handler.GetVocabularyHandler)
+	router.PUT("/v1/vocabulary", handler.PutVocabularyHandler)
+	router.DELETE("/v1/vocabulary", handler.DeleteVocabularyHandler)
+	return router
+}
+
+func serveVocabulary(router *gin.Engine, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	var body *strings.Reader
+	if method == http.MethodPut {
+		body = strings.NewReader(`{"phrases":[{"phrase":"Acme Cloud","boost":15}]}`)
+	} else {
+		body = strings.NewReader("")
+	}
+	req := httptest.NewRequest(method, target, body)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestVocabularyWriteToken(t *testing.T) {
+	vocabularies := controllers.NewVocabularyController(services.NewMemoryVocabularyStore())
+	vocabularies.WriteToken = "s3cret"
+	router := newTestVocabularyRouter(vocabularies)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		if code := serveVocabulary(router, method, "/v1/vocabulary", nil).Code; code != http.StatusUnauthorized {
+			t.Errorf("%s without the token: status %d, want 401", method, code)
+		}
+	}
+	if code := serveVocabulary(router, http.MethodPut, "/v1/vocabulary", map[string]string{"Authorization": "Bearer s3cret"}).Code; code != http.StatusOK {
+		t.Errorf("PUT with the token: status %d, want 200", code)
+	}
+	if code := serveVocabulary(router, http.MethodGet, "/v1/vocabulary", nil).Code; code != http.StatusOK {
+		t.Errorf("GET: status %d, want 200", code)
+	}
+	if code := serveVocabulary(router, http.MethodDelete, "/v1/vocabulary", map[string]string{"Authorization": "Bearer s3cret"}).Code; code != http.StatusNoContent {
+		t.Errorf("DELETE with the token: status %d, want 204", code)
+	}
+}
+
+func TestVocabularyTenantHeader(t *testing.T) {
+	vocabularies := controllers.NewVocabularyController(services.NewMemoryVocabularyStore())
+	vocabularies.TenantHeader = "X-Authenticated-Tenant"
+	router := newTestVocabularyRouter(vocabularies)
+
+	// Only the proxy's header names the tenant; the caller's own claims are ignored
+	serveVocabulary(router, http.MethodPut, "/v1/vocabulary?tenant_id=victim", map[string]string{
+		"X-Authenticated-Tenant": "caller",
+		tenantHeader:             "victim",
+	})
+
+	got := serveVocabulary(router, http.MethodGet, "/v1/vocabulary", map[string]string{"X-Authenticated-Tenant": "caller"})
+	if !strings.Contains(got.Body.String(), "Acme Cloud") {
+		t.Errorf("caller's vocabulary = %s, want the one just saved", got.Body)
+	}
+	victim := serveVocabulary(router, http.MethodGet, "/v1/vocabulary", map[string]string{"X-Authenticated-Tenant": "victim"})
+	if strings.Contains(victim.Body.String(), "Acme Cloud") {
+		t.Errorf("another tenant's vocabulary was changed: %s", victim.Body)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestedVoice reads the voice_id, speed, pitch, stability and
+// similarity_boost form fields or query parameters. It writes a 400
+// response and returns false when a setting is not a number.
+func requestedVoice(c *gin.Context) (models.VoiceSelectionModel, bool) {
+	voice := models.VoiceSelectionModel{VoiceID: formValue(c, "voice_id")}
+
+	settings := []struct {
+		name  string
+		value **float64
+	}{
+		{"speed", &voice.Speed},
+		{"pitch", &voice.Pitch},
+		{"stability", &voice.Stability},
+		{"similarity_boost", &voice.SimilarityBoost},
+	}
+	for _, setting := range settings {
+		value := formValue(c, setting.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + setting.name + " " + value})
+			return voice, false
+		}
+		*setting.value = &parsed
+	}
+	return voice, true
+}
+
+// speechVoice is the voice the reply to this request is spoken in. It
+// writes an error response and returns false when the request asks for a
+// voice or setting the provider lacks.
+func speechVoice(c *gin.Context, voices *controllers.VoiceController, sessionID string) (models.VoiceSelectionModel, bool) {
+	requested, ok := requestedVoice(c)
+	if !ok {
+		return requested, false
+	}
+
+	voice, err := voices.ForSpeech(c.Request.Context(), sessionID, requested)
+	if err != nil {
+		c.JSON(voiceErrorStatus(err), gin.H{"error": err.Error()})
+		return voice, false
+	}
+	return voice, true
+}
+
+func voiceErrorStatus(err error) int {
+	if errors.Is(err, controllers.ErrInvalidVoice) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
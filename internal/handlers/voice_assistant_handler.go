@@ -1,149 +1,153 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"time"
 
 	"golang-gin-boilerplate/internal/controllers"
 	"golang-gin-boilerplate/internal/services"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sashabaranov/go-openai"
+	"golang.org/x/text/language"
 )
 
 type VoiceAssistantHandler struct {
-	voiceController  *controllers.VoiceToTextController
-	chatController   *controllers.ChatGPTController
-	context          *services.ConversationContext
-	elevenLabsAPIKey string
+	sttRegistry    *controllers.VoiceToTextRegistry
+	vocabularies   *controllers.VocabularyController
+	voices         *controllers.VoiceController
+	chatController *controllers.ChatGPTController
+	ttsPipeline    *controllers.TTSPipeline
 }
 
-func NewVoiceAssistantHandler(elevenLabsAPIKey string) *VoiceAssistantHandler {
+func NewVoiceAssistantHandler(
+	sttRegistry *controllers.VoiceToTextRegistry,
+	vocabularies *controllers.VocabularyController,
+	voices *controllers.VoiceController,
+	ttsPipeline *controllers.TTSPipeline,
+) *VoiceAssistantHandler {
 	return &VoiceAssistantHandler{
-		voiceController:  &controllers.VoiceToTextController{},
-		chatController:   controllers.NewChatGPTController(),
-		context:          services.NewConversationContext(openai.GPT3Dot5Turbo),
-		elevenLabsAPIKey: elevenLabsAPIKey,
+		sttRegistry:    sttRegistry,
+		vocabularies:   vocabularies,
+		voices:         voices,
+		chatController: controllers.NewChatGPTController(),
+		ttsPipeline:    ttsPipeline,
 	}
 }
 
 func (h *VoiceAssistantHandler) VoiceAssistantHandler(c *gin.Context) {
 	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
 
-	// Parse file from the form
-	file, err := c.FormFile("audio_file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Failed to retrieve audio file",
-		})
+	// Pick the speech-to-text provider for this request
+	voiceProvider, ok := resolveVoiceToTextProvider(c, h.sttRegistry)
+	if !ok {
 		return
 	}
-
-	// Save the uploaded file locally
-	filePath := "/tmp/" + file.Filename
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save audio file",
-		})
+	options, ok := transcriptionOptions(c, h.vocabularies)
+	if !ok {
 		return
 	}
-	defer func() {
-		if err := os.Remove(filePath); err != nil {
-			log.Printf("Failed to remove temporary file: %v", err)
-		}
-	}()
 
-	// Convert voice to text
-	transcribedText, err := h.voiceController.ConvertVoiceToText(filePath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to transcribe audio: " + err.Error(),
-		})
+	// Save the uploaded audio locally
+	filePath, ok := saveUploadedAudio(c)
+	if !ok {
 		return
 	}
+	defer removeUploadedAudio(filePath)
 
-	// Process transcribed text with ChatGPT
-	assistantResponse, err := h.chatController.ProcessConversation(transcribedText)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process conversation: " + err.Error(),
-		})
+	sessionID := sessionID(c)
+	lang, ok := h.replyLanguage(c, sessionID)
+	if !ok {
+		return
+	}
+	voice, ok := speechVoice(c, h.voices, sessionID)
+	if !ok {
 		return
 	}
 
-	// Convert text to speech using Eleven Labs
-	audioData, err := h.convertTextToSpeechLocal(assistantResponse)
+	// Convert voice to text
+	_, input, err := transcribeTurn(voiceProvider, filePath, options, lang)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to convert text to speech: " + err.Error(),
+		c.JSON(transcriptionErrorStatus(err), gin.H{
+			"error": transcriptionErrorMessage(lang, err),
 		})
 		return
 	}
-
-	// Save the audio file temporarily
-	audioFilePath := filepath.Join("/tmp", "assistant_response.mp3")
-	if err := os.WriteFile(audioFilePath, audioData, 0644); err != nil {
+	lang = input.Language
+
+	// Speak the reply sentence by sentence while it is being generated. The
+	// audio is sent with chunked transfer as soon as the first sentence is
+	// synthesized.
+	contentType := h.ttsPipeline.Provider().ContentType()
+	var audioStream *services.AudioStreamWriter
+	_, err = h.chatController.ProcessConversationSpoken(c.Request.Context(), sessionID, input, h.ttsPipeline, voice, nil, func(audio []byte) error {
+		if audioStream == nil {
+			c.Header("Content-Type", contentType)
+			c.Header("Content-Disposition", "inline; filename=assistant_response"+audioFileExtension(contentType))
+			c.Status(http.StatusOK)
+			audioStream = services.NewAudioStreamWriter(c.Writer, contentType)
+		}
+		if err := audioStream.WriteClip(audio); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		if audioStream != nil {
+			// Too late for an error response, the client sees the audio end early
+			log.Printf("Audio response interrupted: %v", err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save audio response",
+			"error": services.Localize(lang, services.MsgRespondFailed, err),
 		})
 		return
 	}
-	defer func() {
-		if err := os.Remove(audioFilePath); err != nil {
-			log.Printf("Failed to remove temporary audio file: %v", err)
-		}
-	}()
-
-	// Set the headers for audio response
-	c.Header("Content-Type", "audio/mpeg")
-	c.Header("Content-Disposition", "inline; filename=assistant_response.mp3")
 
-	// Return transcribed text, AI response, and audio file
-	c.File(audioFilePath)
+	if audioStream == nil {
+		// The reply had nothing to speak
+		c.Status(http.StatusNoContent)
+	}
 }
 
 func (h *VoiceAssistantHandler) VoiceAssistantHandlerWithoutSpeech(c *gin.Context) {
 	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
 
-	// Parse file from the form
-	file, err := c.FormFile("audio_file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Failed to retrieve audio file",
-		})
+	// Pick the speech-to-text provider for this request
+	voiceProvider, ok := resolveVoiceToTextProvider(c, h.sttRegistry)
+	if !ok {
+		return
+	}
+	options, ok := transcriptionOptions(c, h.vocabularies)
+	if !ok {
 		return
 	}
 
-	// Save the uploaded file locally
-	filePath := "/tmp/" + file.Filename
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save audio file",
-		})
+	// Save the uploaded audio locally
+	filePath, ok := saveUploadedAudio(c)
+	if !ok {
+		return
+	}
+	defer removeUploadedAudio(filePath)
+
+	sessionID := sessionID(c)
+	lang, ok := h.replyLanguage(c, sessionID)
+	if !ok {
 		return
 	}
-	defer func() {
-		if err := os.Remove(filePath); err != nil {
-			log.Printf("Failed to remove temporary file: %v", err)
-		}
-	}()
 
 	// Convert voice to text
 	start := time.Now() // Record the start time
-	transcribedText, err := h.voiceController.ConvertVoiceToText(filePath)
+	transcript, input, err := transcribeTurn(voiceProvider, filePath, options, lang)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to transcribe audio: " + err.Error(),
+		c.JSON(transcriptionErrorStatus(err), gin.H{
+			"error": transcriptionErrorMessage(lang, err),
 		})
 		return
 	}
+	lang = input.Language
 	duration := time.Since(start) // Calculate the elapsed time
 
 	fmt.Printf("Execution time of Voice to text: %v\n", duration)
@@ -151,133 +155,108 @@ func (h *VoiceAssistantHandler) VoiceAssistantHandlerWithoutSpeech(c *gin.Contex
 	// Process transcribed text with ChatGPT
 	start = time.Now() // Record the start time
 
-	assistantResponse, err := h.chatController.ProcessConversation(transcribedText)
+	assistantResponse, err := h.chatController.ProcessConversation(c.Request.Context(), sessionID, input)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process conversation: " + err.Error(),
+			"error": services.Localize(lang, services.MsgConversationFailed, err),
 		})
 		return
 	}
 	duration = time.Since(start) // Calculate the elapsed time
 	fmt.Printf("Execution time of Conversation Processing: %v\n", duration)
 
-	// Return both transcribed text and AI response
-	c.JSON(http.StatusOK, gin.H{
-		"transcribed_text":     transcribedText,
-		"assistant_response":   assistantResponse,
-		"total_context_tokens": h.context.CalculateTotalTokens(),
-	})
-}
-
-func (h *VoiceAssistantHandler) convertTextToSpeech(text string) ([]byte, error) {
-	// Eleven Labs API endpoint (adjust the voice ID as needed)
-	url := "https://api.elevenlabs.io/v1/text-to-speech/21m00Tcm4TlvDq8ikWAM"
-
-	// Prepare the request body
-	payload := map[string]interface{}{
-		"text": text,
-		"voice_settings": map[string]interface{}{
-			"stability":        0.5,
-			"similarity_boost": 0.5,
-		},
-	}
-
-	// Create JSON payload
-	jsonPayload, err := json.Marshal(payload)
+	totalTokens, err := h.chatController.GetCurrentTokenCount(sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create JSON payload: %v", err)
-	}
-
-	// Create HTTP request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": services.Localize(lang, services.MsgLoadConversationFailed, err),
+		})
+		return
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("xi-api-key", h.elevenLabsAPIKey)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+	// Return both transcribed text and AI response
+	response := gin.H{
+		"transcribed_text":     transcript.Text,
+		"assistant_response":   assistantResponse,
+		"session_id":           sessionID,
+		"total_context_tokens": totalTokens,
 	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned non-200 status: %d, body: %s", resp.StatusCode, string(body))
+	if input.Speakers != nil {
+		response["speakers"] = input.Speakers
 	}
-
-	// Read the audio response
-	audioData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+	if input.Language != language.Und {
+		response["language"] = input.Language.String()
 	}
-
-	return audioData, nil
+	c.JSON(http.StatusOK, response)
 }
 
-func (h *VoiceAssistantHandler) convertTextToSpeechLocal(text string) ([]byte, error) {
-	// Local FastAPI server endpoint
-	url := fmt.Sprintf("%s/generate", "https://coqui-service-75777829797.us-central1.run.app")
-
-	// Prepare the request body
-	payload := map[string]interface{}{
-		"text": text,
+// Reset, token and history handlers operate on the caller's session
+func (h *VoiceAssistantHandler) ResetConversationHandler(c *gin.Context) {
+	sessionID := sessionID(c)
+	lang, ok := h.replyLanguage(c, sessionID)
+	if !ok {
+		return
 	}
-
-	// Create JSON payload
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create JSON payload: %v", err)
+	if err := h.chatController.ResetConversation(sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": services.Localize(lang, services.MsgResetFailed, err),
+		})
+		return
 	}
-
-	// Create HTTP request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	// The session's voice goes with its history
+	if err := h.voices.DeleteSessionVoice(c.Request.Context(), sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": services.Localize(lang, services.MsgResetFailed, err),
+		})
+		return
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
+	c.JSON(http.StatusOK, gin.H{
+		"message":    services.Localize(lang, services.MsgConversationReset),
+		"session_id": sessionID,
+	})
+}
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (h *VoiceAssistantHandler) GetContextTokensHandler(c *gin.Context) {
+	sessionID := sessionID(c)
+	totalTokens, err := h.chatController.GetCurrentTokenCount(sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": services.Localize(acceptedLanguage(c), services.MsgLoadConversationFailed, err),
+		})
+		return
 	}
-	defer resp.Body.Close()
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned non-200 status: %d, body: %s", resp.StatusCode, string(body))
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"session_id":   sessionID,
+		"total_tokens": totalTokens,
+	})
+}
 
-	// Read the audio response (MP3 file)
-	audioData, err := io.ReadAll(resp.Body)
+func (h *VoiceAssistantHandler) GetConversationHistoryHandler(c *gin.Context) {
+	history, err := h.chatController.GetConversationHistory(sessionID(c))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": services.Localize(acceptedLanguage(c), services.MsgLoadConversationFailed, err),
+		})
+		return
 	}
 
-	return audioData, nil
+	c.JSON(http.StatusOK, history)
 }
 
-// Existing reset and token handlers remain the same
-func (h *VoiceAssistantHandler) ResetConversationHandler(c *gin.Context) {
-	h.context.ResetContext()
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Conversation context reset successfully",
-	})
+// GetTTSCacheStatsHandler reports the hit ratio and size of the speech cache
+func (h *VoiceAssistantHandler) GetTTSCacheStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.ttsPipeline.CacheStats())
 }
 
-func (h *VoiceAssistantHandler) GetContextTokensHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"total_tokens": h.context.CalculateTotalTokens(),
-	})
+// audioFileExtension maps a TTS content type to a file extension
+func audioFileExtension(contentType string) string {
+	switch contentType {
+	case "audio/wav", "audio/x-wav":
+		return ".wav"
+	case "audio/ogg":
+		return ".ogg"
+	default:
+		return ".mp3"
+	}
 }
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sashabaranov/go-openai"
+)
+
+// newTestAssistant serves the conversation endpoints with the fake
+// recognizer, which always hears transcript, and the scripted model
+func newTestAssistant(llm *controllers.ScriptedLLMProvider, transcript string) *gin.Engine {
+	registry := controllers.NewVoiceToTextRegistry(controllers.VoiceToTextProviderFake)
+	registry.Register(controllers.VoiceToTextProviderFake, controllers.NewFakeVoiceToTextController(transcript))
+
+	handler := &VoiceAssistantHandler{
+		sttRegistry:    registry,
+		vocabularies:   controllers.NewVocabularyController(services.NewMemoryVocabularyStore()),
+		voices:         controllers.NewVoiceController(controllers.NewToneTTSProvider(), services.NewMemorySessionVoiceStore(0, 0)),
+		chatController: controllers.NewChatGPTControllerWithProvider(llm, services.NewSessionManager(llm.Model(), 0, 0, nil)),
+	}
+
+	router := gin.New()
+	router.POST("/v1/voice-assistant-without-speech", handler.VoiceAssistantHandlerWithoutSpeech)
+	router.POST("/v1/conversation/reset", handler.ResetConversationHandler)
+	router.GET("/v1/conversation/tokens", handler.GetContextTokensHandler)
+	router.GET("/v1/conversation/history", handler.GetConversationHistoryHandler)
+	return router
+}
+
+// serveJSON runs the request for the session and decodes the JSON response
+func serveJSON(t *testing.T, router *gin.Engine, req *http.Request, session string, response interface{}) {
+	t.Helper()
+
+	req.Header.Set(sessionHeader, session)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("%s %s: status = %d: %s", req.Method, req.URL, recorder.Code, recorder.Body)
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func conversationTurn(t *testing.T, router *gin.Engine, session string) (reply string, contextTokens int) {
+	t.Helper()
+
+	var response struct {
+		AssistantResponse  string `json:"assistant_response"`
+		TotalContextTokens int    `json:"total_context_tokens"`
+	}
+	serveJSON(t, router, uploadRequest(t, "/v1/voice-assistant-without-speech", "turn.wav", silentWAV(), nil), session, &response)
+	return response.AssistantResponse, response.TotalContextTokens
+}
+
+func conversationHistory(t *testing.T, router *gin.Engine, session string) models.ConversationHistoryModel {
+	t.Helper()
+
+	var history models.ConversationHistoryModel
+	serveJSON(t, router, httptest.NewRequest(http.MethodGet, "/v1/conversation/history", nil), session, &history)
+	return history
+}
+
+func conversationTokens(t *testing.T, router *gin.Engine, session string) int {
+	t.Helper()
+
+	var response struct {
+		TotalTokens int `json:"total_tokens"`
+	}
+	serveJSON(t, router, httptest.NewRequest(http.MethodGet, "/v1/conversation/tokens", nil), session, &response)
+	return response.TotalTokens
+}
+
+// historyOf converts what the model was sent into history messages
+func historyOf(messages []openai.ChatCompletionMessage) []models.ConversationMessageModel {
+	history := make([]models.ConversationMessageModel, len(messages))
+	for i, message := range messages {
+		history[i] = models.ConversationMessageModel{Role: message.Role, Name: message.Name, Content: message.Content}
+	}
+	return history
+}
+
+func assertMessages(t *testing.T, got, want []models.ConversationMessageModel) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d messages, want %d:\ngot  %+v\nwant %+v", len(got), len(want), got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConversationEndpointsReportWhatTheModelIsSent(t *testing.T) {
+	llm := controllers.NewScriptedLLMProvider("It is noon.", "Still noon.")
+	router := newTestAssistant(llm, "What time is it?")
+
+	reply, contextTokens := conversationTurn(t, router, "alice")
+	if reply != "It is noon." {
+		t.Fatalf("reply = %q", reply)
+	}
+
+	// The history is the request of the turn followed by the reply
+	requests := llm.Requests()
+	history := conversationHistory(t, router, "alice")
+	want := append(historyOf(requests[0]), models.ConversationMessageModel{Role: "assistant", Content: "It is noon."})
+	assertMessages(t, history.Messages, want)
+	if history.SessionID != "alice" {
+		t.Errorf("session_id = %q, want alice", history.SessionID)
+	}
+
+	tokens := conversationTokens(t, router, "alice")
+	if tokens != history.TotalTokens || tokens != contextTokens {
+		t.Errorf("tokens endpoint = %d, history = %d, turn = %d, want them equal", tokens, history.TotalTokens, contextTokens)
+	}
+
+	// The next turn sends exactly that history, and the token count reported
+	// for it is the count of those messages
+	conversationTurn(t, router, "alice")
+	requests = llm.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("model called %d times, want 2", len(requests))
+	}
+	sent := requests[1]
+	assertMessages(t, historyOf(sent[:len(sent)-1]), history.Messages)
+	if last := sent[len(sent)-1]; last.Role != "user" || last.Content != "What time is it?" {
+		t.Errorf("last message sent = %+v, want the new user turn", last)
+	}
+	if want := services.CountMessagesTokens(llm.Model(), sent[:len(sent)-1]); tokens != want {
+		t.Errorf("reported %d tokens, the messages sent count %d", tokens, want)
+	}
+
+	history = conversationHistory(t, router, "alice")
+	if want := services.CountMessagesTokens(llm.Model(), append(sent, openai.ChatCompletionMessage{Role: "assistant", Content: "Still noon."})); history.TotalTokens != want {
+		t.Errorf("history total_tokens = %d, want %d", history.TotalTokens, want)
+	}
+}
+
+func TestConversationEndpointsKeepSessionsApart(t *testing.T) {
+	llm := controllers.NewScriptedLLMProvider("Hello.")
+	router := newTestAssistant(llm, "Hi")
+
+	conversationTurn(t, router, "alice")
+
+	history := conversationHistory(t, router, "bob")
+	if len(history.Messages) != 0 || history.TotalTokens != 0 {
+		t.Errorf("bob's history = %+v, want it empty", history)
+	}
+	if tokens := conversationTokens(t, router, "bob"); tokens != 0 {
+		t.Errorf("bob's tokens = %d, want 0", tokens)
+	}
+	if tokens := conversationTokens(t, router, "alice"); tokens == 0 {
+		t.Error("alice's tokens = 0, want her turn counted")
+	}
+}
+
+func TestInvalidLanguageIsRefused(t *testing.T) {
+	router := newTestAssistant(controllers.NewScriptedLLMProvider("Hi."), "hello")
+	conversationTurn(t, router, "session")
+
+	for _, target := range []string{"/v1/voice-assistant-without-speech", "/v1/conversation/reset"} {
+		req := uploadRequest(t, target+"?language=not-a-language!", "recording.wav", silentWAV(), nil)
+		req.Header.Set(sessionHeader, "session")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400: %s", target, recorder.Code, recorder.Body)
+		}
+	}
+
+	// The refused reset left the conversation alone
+	if history := conversationHistory(t, router, "session"); len(history.Messages) != 3 {
+		t.Errorf("history has %d messages after a refused reset, want 3", len(history.Messages))
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// VoiceAssistantHandlerWithoutSpeechStream answers like
+// VoiceAssistantHandlerWithoutSpeech but streams the reply as Server-Sent
+// Events:
+//
+//	event: transcript  data: {"text":"...","language":"es-ES","speakers":[...]}   speakers only when separated
+//	event: delta       data: {"text":"..."}   once per generated piece
+//	event: done        data: {"assistant_response":"...","usage":{...},"total_context_tokens":N,"session_id":"..."}
+//	event: error       data: {"error":"..."}  instead of done when a step fails
+//
+// Upload and transcription errors happen before the stream starts and are
+// returned as regular JSON errors.
+func (h *VoiceAssistantHandler) VoiceAssistantHandlerWithoutSpeechStream(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	// Pick the speech-to-text provider for this request
+	voiceProvider, ok := resolveVoiceToTextProvider(c, h.sttRegistry)
+	if !ok {
+		return
+	}
+	options, ok := transcriptionOptions(c, h.vocabularies)
+	if !ok {
+		return
+	}
+
+	// Save the uploaded audio locally
+	filePath, ok := saveUploadedAudio(c)
+	if !ok {
+		return
+	}
+	defer removeUploadedAudio(filePath)
+
+	sessionID := sessionID(c)
+	lang, ok := h.replyLanguage(c, sessionID)
+	if !ok {
+		return
+	}
+
+	// Convert voice to text
+	transcript, input, err := transcribeTurn(voiceProvider, filePath, options, lang)
+	if err != nil {
+		c.JSON(transcriptionErrorStatus(err), gin.H{
+			"error": transcriptionErrorMessage(lang, err),
+		})
+		return
+	}
+	lang = input.Language
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Keep proxies from buffering the stream
+
+	sendEvent := func(name string, data gin.H) error {
+		c.SSEvent(name, data)
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	}
+
+	transcriptEvent := gin.H{"text": transcript.Text}
+	if input.Speakers != nil {
+		transcriptEvent["speakers"] = input.Speakers
+	}
+	if input.Language != language.Und {
+		transcriptEvent["language"] = input.Language.String()
+	}
+	sendEvent("transcript", transcriptEvent)
+
+	// Stream the reply from the model as it is generated
+	assistantResponse, usage, err := h.chatController.ProcessConversationStream(
+		c.Request.Context(),
+		sessionID,
+		input,
+		func(delta string) error {
+			return sendEvent("delta", gin.H{"text": delta})
+		},
+	)
+	if err != nil {
+		sendEvent("error", gin.H{"error": services.Localize(lang, services.MsgConversationFailed, err)})
+		return
+	}
+
+	totalTokens, err := h.chatController.GetCurrentTokenCount(sessionID)
+	if err != nil {
+		sendEvent("error", gin.H{"error": services.Localize(lang, services.MsgLoadConversationFailed, err)})
+		return
+	}
+
+	sendEvent("done", gin.H{
+		"assistant_response": assistantResponse,
+		"usage": gin.H{
+			"prompt_tokens":     usage.PromptTokens,
+			"completion_tokens": usage.CompletionTokens,
+			"total_tokens":      usage.TotalTokens,
+		},
+		"total_context_tokens": totalTokens,
+		"session_id":           sessionID,
+	})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VoiceHandler lists the voices replies can be spoken in and keeps the one
+// each session chose. Requests to the voice assistant can override the
+// session's choice with the same fields as form fields or query parameters:
+//
+//	GET /v1/voices?language=es
+//	PUT /v1/conversation/voice
+//	{"voice_id":"EXAVITQu4vr4xnSDxMaL","speed":1.1,"stability":0.3}
+type VoiceHandler struct {
+	voices *controllers.VoiceController
+}
+
+func NewVoiceHandler(voices *controllers.VoiceController) *VoiceHandler {
+	return &VoiceHandler{voices: voices}
+}
+
+// GetVoicesHandler returns the catalog, narrowed to the language parameter
+// when there is one
+func (h *VoiceHandler) GetVoicesHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	lang, ok := requestedLanguage(c)
+	if !ok {
+		return
+	}
+
+	catalog, err := h.voices.Catalog(c.Request.Context(), lang)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to list voices: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, catalog)
+}
+
+func (h *VoiceHandler) GetSessionVoiceHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	voice, err := h.voices.SessionVoice(c.Request.Context(), sessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, voice)
+}
+
+// PutSessionVoiceHandler replaces the session's voice and settings
+func (h *VoiceHandler) PutSessionVoiceHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	var voice models.VoiceSelectionModel
+	if err := c.ShouldBindJSON(&voice); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid voice: " + err.Error()})
+		return
+	}
+
+	sessionVoice, err := h.voices.SetSessionVoice(c.Request.Context(), sessionID(c), voice)
+	if err != nil {
+		c.JSON(voiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessionVoice)
+}
+
+// DeleteSessionVoiceHandler goes back to the configured voice
+func (h *VoiceHandler) DeleteSessionVoiceHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*") // Allow all origins, change "*" to specific origin if needed
+
+	if err := h.voices.DeleteSessionVoice(c.Request.Context(), sessionID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
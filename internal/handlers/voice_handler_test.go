@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multilingualTTSProvider is the tone provider with voices for a few
+// languages
+type multilingualTTSProvider struct {
+	*controllers.ToneTTSProvider
+}
+
+func (p multilingualTTSProvider) Voices(ctx context.Context) ([]models.VoiceModel, error) {
+	return []models.VoiceModel{
+		{ID: "emma", Name: "Emma", Languages: []string{"en-US", "en-GB"}},
+		{ID: "lucia", Name: "Lucía", Languages: []string{"es-ES"}},
+		{ID: "tone", Name: "Sine tone"},
+	}, nil
+}
+
+func (p multilingualTTSProvider) DefaultVoice(lang string) string {
+	if strings.HasPrefix(lang, "es") {
+		return "lucia"
+	}
+	return "emma"
+}
+
+func TestGetVoicesFiltersByLanguage(t *testing.T) {
+	voices := controllers.NewVoiceController(multilingualTTSProvider{controllers.NewToneTTSProvider()}, services.NewMemorySessionVoiceStore(0, 0))
+	router := gin.New()
+	router.GET("/v1/voices", NewVoiceHandler(voices).GetVoicesHandler)
+
+	tests := []struct {
+		query       string
+		wantVoices  string
+		wantDefault string
+	}{
+		{"", "emma,lucia,tone", "emma"},
+		{"?language=es-MX", "lucia,tone", "lucia"},
+		{"?language=en", "emma,tone", "emma"},
+		{"?language=fr", "tone", "emma"},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/voices"+tt.query, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%q: status %d: %s", tt.query, recorder.Code, recorder.Body)
+		}
+
+		var catalog models.VoiceCatalogModel
+		if err := json.Unmarshal(recorder.Body.Bytes(), &catalog); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, voice := range catalog.Voices {
+			ids = append(ids, voice.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.wantVoices {
+			t.Errorf("%q: voices %s, want %s", tt.query, got, tt.wantVoices)
+		}
+		if catalog.DefaultVoiceID != tt.wantDefault {
+			t.Errorf("%q: default %s, want %s", tt.query, catalog.DefaultVoiceID, tt.wantDefault)
+		}
+		// The tone provider changes speed and pitch only
+		if capabilities := catalog.Capabilities; capabilities.Speed == nil || capabilities.Pitch == nil || capabilities.Stability != nil {
+			t.Errorf("%q: capabilities %+v, want the tone provider's", tt.query, capabilities)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/voices?language=not-a-language!", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid language: status %d, want 400", recorder.Code)
+	}
+}
+
+func TestResetForgetsSessionVoice(t *testing.T) {
+	ctx := context.Background()
+	llm := controllers.NewScriptedLLMProvider("Hi.")
+	voices := controllers.NewVoiceController(controllers.NewToneTTSProvider(), services.NewMemorySessionVoiceStore(0, 0))
+	handler := &VoiceAssistantHandler{
+		voices:         voices,
+		chatController: controllers.NewChatGPTControllerWithProvider(llm, services.NewSessionManager(llm.Model(), 0, 0, nil)),
+	}
+	router := gin.New()
+	router.POST("/v1/conversation/reset", handler.ResetConversationHandler)
+
+	speed := 1.5
+	if _, err := voices.SetSessionVoice(ctx, "session", models.VoiceSelectionModel{Speed: &speed}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/conversation/reset", nil)
+	req.Header.Set(sessionHeader, "session")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	voice, err := voices.SessionVoice(ctx, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !voice.Empty() {
+		t.Errorf("voice after reset = %+v, want none", voice.VoiceSelectionModel)
+	}
+}
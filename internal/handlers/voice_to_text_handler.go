@@ -1,39 +1,194 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/interfaces"
+	"golang-gin-boilerplate/internal/models"
+	"golang-gin-boilerplate/internal/services"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
 )
 
-func VoiceToTextHandler(c *gin.Context) {
-	// Parse file from the form
-	file, err := c.FormFile("audio_file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to retrieve audio file"})
+type VoiceToTextHandler struct {
+	sttRegistry  *controllers.VoiceToTextRegistry
+	vocabularies *controllers.VocabularyController
+}
+
+func NewVoiceToTextHandler(sttRegistry *controllers.VoiceToTextRegistry, vocabularies *controllers.VocabularyController) *VoiceToTextHandler {
+	return &VoiceToTextHandler{
+		sttRegistry:  sttRegistry,
+		vocabularies: vocabularies,
+	}
+}
+
+func (h *VoiceToTextHandler) VoiceToTextHandler(c *gin.Context) {
+	// Pick the speech-to-text provider for this request
+	provider, ok := resolveVoiceToTextProvider(c, h.sttRegistry)
+	if !ok {
+		return
+	}
+
+	// Save the uploaded audio locally
+	filePath, ok := saveUploadedAudio(c)
+	if !ok {
 		return
 	}
+	defer removeUploadedAudio(filePath) // Clean up after processing
 
-	// Save the uploaded file locally
-	filePath := "/tmp/" + file.Filename
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save audio file"})
+	options, ok := transcriptionOptions(c, h.vocabularies)
+	if !ok {
 		return
 	}
-	defer os.Remove(filePath) // Clean up after processing
+	if lang := acceptedLanguage(c); options.Language == "" && lang != language.Und {
+		options.Language = lang.String()
+	}
 
-	// Process the file using the controller
-	controller := controllers.VoiceToTextController{}
-	text, err := controller.ConvertVoiceToText(filePath)
+	// Process the file using the provider
+	result, err := controllers.TranscribeAudio(provider, filePath, options)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(transcriptionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return the recognized text
-	c.JSON(http.StatusOK, gin.H{
-		"recognized_text": text,
-	})
+	// detail=full returns the whole transcription: words, confidence,
+	// alternatives and language
+	if requestedDetail(c) == "full" {
+		result.AudioFilePath = ""
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	// Return the recognized text, and the audio formats when known
+	response := gin.H{
+		"recognized_text": result.Text,
+	}
+	if result.OriginalFormat != nil {
+		response["original_format"] = result.OriginalFormat
+		response["final_format"] = result.FinalFormat
+	}
+	if result.SpeechSegments != nil {
+		response["speech_segments"] = result.SpeechSegments
+	}
+	if result.Segments != nil {
+		segments := make([]models.TranscriptSegmentModel, len(result.Segments))
+		for i, segment := range result.Segments {
+			segments[i] = segment.Basic()
+		}
+		response["segments"] = segments
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// transcriptionErrorStatus tells what the client can fix, a recording
+// without speech or too long to send, or a speaker mode the provider lacks,
+// apart from a failure
+func transcriptionErrorStatus(err error) int {
+	if errors.Is(err, services.ErrNoSpeechDetected) {
+		return http.StatusUnprocessableEntity
+	}
+	if errors.Is(err, controllers.ErrAudioTooLong) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if errors.Is(err, controllers.ErrSpeakerModeUnsupported) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// resolveVoiceToTextProvider looks up the provider named by the stt_provider
+// form field or query parameter, falling back to the registry default. It
+// writes a 400 response and returns false when the name is unknown.
+func resolveVoiceToTextProvider(c *gin.Context, registry *controllers.VoiceToTextRegistry) (interfaces.VoiceToTextInterface, bool) {
+	provider, err := registry.Resolve(sttProviderName(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     err.Error(),
+			"providers": registry.Names(),
+		})
+		return nil, false
+	}
+
+	return provider, true
+}
+
+// transcriptionOptions reads language, speakers (channels or diarize) and
+// the min_speakers and max_speakers bounds of diarization, and adds the
+// tenant's vocabulary. It writes a 400 response and returns false when they
+// are invalid.
+func transcriptionOptions(c *gin.Context, vocabularies *controllers.VocabularyController) (models.TranscriptionOptions, bool) {
+	options := models.TranscriptionOptions{SpeakerMode: strings.ToLower(formValue(c, "speakers"))}
+
+	lang, ok := requestedLanguage(c)
+	if !ok {
+		return options, false
+	}
+	if lang != language.Und {
+		options.Language = lang.String()
+	}
+
+	switch options.SpeakerMode {
+	case "", models.SpeakerModeChannels, models.SpeakerModeDiarize:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "speakers must be channels or diarize"})
+		return options, false
+	}
+
+	var err error
+	if options.MinSpeakers, err = positiveFormInt(c, "min_speakers"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return options, false
+	}
+	if options.MaxSpeakers, err = positiveFormInt(c, "max_speakers"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return options, false
+	}
+	if options.MaxSpeakers > 0 && options.MinSpeakers > options.MaxSpeakers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_speakers is larger than max_speakers"})
+		return options, false
+	}
+
+	if options.Vocabulary, err = vocabularies.ForTranscription(c.Request.Context(), tenantID(c, vocabularies)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load vocabulary: " + err.Error()})
+		return options, false
+	}
+
+	return options, true
+}
+
+// positiveFormInt reads an optional positive number, 0 when it is absent
+func positiveFormInt(c *gin.Context, key string) (int, error) {
+	value := formValue(c, key)
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return 0, fmt.Errorf("%s must be a positive number", key)
+	}
+	return parsed, nil
+}
+
+// requestedDetail reads the detail form field or query parameter
+func requestedDetail(c *gin.Context) string {
+	return strings.ToLower(formValue(c, "detail"))
+}
+
+// sttProviderName is the provider the request asks for, empty for the default
+func sttProviderName(c *gin.Context) string {
+	return formValue(c, "stt_provider")
+}
+
+// formValue reads a form field, falling back to the query parameter
+func formValue(c *gin.Context, key string) string {
+	if value := c.PostForm(key); value != "" {
+		return value
+	}
+	return c.Query(key)
 }
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-gin-boilerplate/internal/controllers"
+	"golang-gin-boilerplate/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// uploadRequest builds a multipart POST with audio as audio_file and the
+// given form fields
+func uploadRequest(t *testing.T, target, filename string, audio []byte, fields map[string]string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for key, value := range fields {
+		if err := form.WriteField(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := form.CreateFormFile("audio_file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(audio)
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+// silentWAV is a short mono recording that needs no decoding beyond WAV
+func silentWAV() []byte {
+	return services.EncodeWAV(make([]byte, 16000), 16000, 1)
+}
+
+func TestVoiceToTextHandlerWithFakeProvider(t *testing.T) {
+	registry := controllers.NewVoiceToTextRegistry(controllers.VoiceToTextProviderFake)
+	registry.Register(controllers.VoiceToTextProviderFake, controllers.NewFakeVoiceToTextController("turn on the lights"))
+	vocabularies := controllers.NewVocabularyController(services.NewMemoryVocabularyStore())
+
+	router := gin.New()
+	router.POST("/v1/voice-to-text", NewVoiceToTextHandler(registry, vocabularies).VoiceToTextHandler)
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		status int
+		text   string
+	}{
+		{name: "default provider", status: http.StatusOK, text: "turn on the lights"},
+		{name: "named provider", fields: map[string]string{"stt_provider": "fake"}, status: http.StatusOK, text: "turn on the lights"},
+		{name: "unknown provider", fields: map[string]string{"stt_provider": "nope"}, status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, uploadRequest(t, "/v1/voice-to-text", "recording.wav", silentWAV(), test.fields))
+
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			var response struct {
+				RecognizedText string `json:"recognized_text"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.RecognizedText != test.text {
+				t.Errorf("recognized_text = %q, want %q", response.RecognizedText, test.text)
+			}
+		})
+	}
+}
+
+func TestVoiceToTextHandlerWithoutUpload(t *testing.T) {
+	registry := controllers.NewVoiceToTextRegistry(controllers.VoiceToTextProviderFake)
+	registry.Register(controllers.VoiceToTextProviderFake, controllers.NewFakeVoiceToTextController(""))
+	vocabularies := controllers.NewVocabularyController(services.NewMemoryVocabularyStore())
+
+	router := gin.New()
+	router.POST("/v1/voice-to-text", NewVoiceToTextHandler(registry, vocabularies).VoiceToTextHandler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/voice-to-text", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTranscriptionErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: services.ErrNoSpeechDetected, want: http.StatusUnprocessableEntity},
+		{err: fmt.Errorf("%w: ogg_opus audio is 2m0s long", controllers.ErrAudioTooLong), want: http.StatusRequestEntityTooLarge},
+		{err: fmt.Errorf("%w: \"channels\"", controllers.ErrSpeakerModeUnsupported), want: http.StatusBadRequest},
+		{err: errors.New("recognition failed"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := transcriptionErrorStatus(tt.err); got != tt.want {
+			t.Errorf("transcriptionErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestVoiceToTextHandlerWithoutSpeech(t *testing.T) {
+	// Nothing answers recognition, so a call to the provider would fail
+	t.Setenv("SPEECH_ENDPOINT", "")
+	t.Setenv("CRED_JSON", "")
+
+	vad := services.DefaultVADConfig()
+	registry := controllers.NewVoiceToTextRegistry(controllers.VoiceToTextProviderGoogle)
+	registry.Register(controllers.VoiceToTextProviderGoogle, &controllers.VoiceToTextController{VAD: &vad})
+	vocabularies := controllers.NewVocabularyController(services.NewMemoryVocabularyStore())
+
+	router := gin.New()
+	router.POST("/v1/voice-to-text", NewVoiceToTextHandler(registry, vocabularies).VoiceToTextHandler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, uploadRequest(t, "/v1/voice-to-text", "recording.wav", silentWAV(), nil))
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusUnprocessableEntity, recorder.Body)
+	}
+}
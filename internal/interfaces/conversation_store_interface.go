@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ConversationStore persists conversation history outside the process so
+// sessions survive restarts and can be shared between instances
+type ConversationStore interface {
+	// Load returns the stored messages and false when the session is unknown
+	Load(ctx context.Context, sessionID string) ([]openai.ChatCompletionMessage, bool, error)
+	// Save replaces the stored messages for the session
+	Save(ctx context.Context, sessionID string, messages []openai.ChatCompletionMessage) error
+	Delete(ctx context.Context, sessionID string) error
+	Close() error
+}
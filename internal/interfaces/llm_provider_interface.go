@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// LLMProvider is implemented by every chat model backend
+type LLMProvider interface {
+	Name() string
+	Model() string
+	CreateChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error)
+	// StreamChatCompletion calls onDelta for every piece of the reply as it is
+	// generated and returns the full reply with the usage reported by the
+	// backend, which is zero when the backend does not report it
+	StreamChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage, onDelta func(delta string) error) (string, openai.Usage, error)
+}
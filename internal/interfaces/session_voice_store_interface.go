@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// SessionVoiceStore persists the voice each session chose
+type SessionVoiceStore interface {
+	// Load returns the session's voice and false when it chose none
+	Load(ctx context.Context, sessionID string) (models.SessionVoiceModel, bool, error)
+	// Save creates the session's voice or replaces it
+	Save(ctx context.Context, voice models.SessionVoiceModel) error
+	Delete(ctx context.Context, sessionID string) error
+	Close() error
+}
@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Summarizer condenses conversation turns that are about to leave the
+// context window into a short memory of names, facts and decisions
+type Summarizer interface {
+	Summarize(ctx context.Context, previousSummary string, messages []openai.ChatCompletionMessage) (string, error)
+}
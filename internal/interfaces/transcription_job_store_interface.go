@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// TranscriptionJobStore persists asynchronous transcription jobs
+type TranscriptionJobStore interface {
+	// Load returns the job and false when the ID is unknown
+	Load(ctx context.Context, id string) (models.TranscriptionJobModel, bool, error)
+	// Save creates the job or replaces it
+	Save(ctx context.Context, job models.TranscriptionJobModel) error
+	// ListUnfinished returns queued and running jobs, oldest first, so they
+	// can be picked up again after a restart
+	ListUnfinished(ctx context.Context) ([]models.TranscriptionJobModel, error)
+	// DeleteFinishedBefore drops jobs that completed before cutoff
+	DeleteFinishedBefore(ctx context.Context, cutoff time.Time) error
+	Close() error
+}
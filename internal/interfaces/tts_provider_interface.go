@@ -0,0 +1,33 @@
+package interfaces
+
+import (
+	"context"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// TTSProvider is implemented by every text-to-speech backend
+type TTSProvider interface {
+	Name() string
+	// ContentType is the MIME type of the audio returned by ConvertTextToSpeech
+	ContentType() string
+	// ConvertTextToSpeech speaks text in options.Voice, or else in a voice
+	// for options.Language where the provider has one
+	ConvertTextToSpeech(ctx context.Context, text string, options models.SpeechOptions) ([]byte, error)
+	// Voices lists the voices options.Voice.VoiceID may name
+	Voices(ctx context.Context) ([]models.VoiceModel, error)
+	// DefaultVoice is the voice text in lang is spoken in when none is chosen
+	DefaultVoice(lang string) string
+	// Capabilities tells which voice settings are honored, with their
+	// configured defaults
+	Capabilities() models.VoiceCapabilitiesModel
+}
+
+// CacheableTTSProvider is implemented by providers whose audio depends only
+// on the text and on what VoiceKey describes, so it can be cached
+type CacheableTTSProvider interface {
+	TTSProvider
+	// VoiceKey identifies the voice and settings options select, e.g. the
+	// voice ID, model and voice settings
+	VoiceKey(options models.SpeechOptions) string
+}
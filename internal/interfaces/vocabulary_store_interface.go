@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// VocabularyStore persists custom vocabularies by tenant
+type VocabularyStore interface {
+	// Load returns the vocabulary and false when the tenant has none
+	Load(ctx context.Context, tenantID string) (models.VocabularyModel, bool, error)
+	// Save creates the tenant's vocabulary or replaces it
+	Save(ctx context.Context, vocabulary models.VocabularyModel) error
+	Delete(ctx context.Context, tenantID string) error
+	Close() error
+}
@@ -1,5 +1,29 @@
 package interfaces
 
+import (
+	"context"
+	"io"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// VoiceToTextInterface is implemented by every speech-to-text provider
 type VoiceToTextInterface interface {
 	ConvertVoiceToText(audioFilePath string) (string, error)
 }
+
+// DetailedVoiceToTextInterface is implemented by providers that report more
+// than the transcript, such as the audio format they sent, and can take
+// options like speaker separation
+type DetailedVoiceToTextInterface interface {
+	TranscribeVoiceToText(audioFilePath string, options models.TranscriptionOptions) (models.VoiceToTextModel, error)
+}
+
+// StreamingVoiceToTextInterface is implemented by providers that can
+// recognize audio while it is still arriving. audio carries little-endian
+// 16-bit mono PCM. Results are sent until audio hits EOF and the recognizer
+// has flushed, then the channel is closed. Of options, the language and
+// vocabulary apply to a stream; speaker separation does not.
+type StreamingVoiceToTextInterface interface {
+	StreamVoiceToText(ctx context.Context, audio io.Reader, sampleRate int, options models.TranscriptionOptions) (<-chan models.StreamingTranscript, error)
+}
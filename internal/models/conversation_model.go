@@ -0,0 +1,15 @@
+package models
+
+type ConversationMessageModel struct {
+	Role string `json:"role"`
+	// Name is the speaker of a user message taken from a recording with
+	// several speakers
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+}
+
+type ConversationHistoryModel struct {
+	SessionID   string                     `json:"session_id"`
+	Messages    []ConversationMessageModel `json:"messages"`
+	TotalTokens int                        `json:"total_tokens"`
+}
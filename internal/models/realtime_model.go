@@ -0,0 +1,39 @@
+package models
+
+// Realtime message types exchanged over /v1/voice-assistant/ws
+const (
+	// Client to server
+	RealtimeTypeStart  = "start"
+	RealtimeTypeStop   = "stop"
+	RealtimeTypeCancel = "cancel"
+	RealtimeTypeReset  = "reset"
+
+	// Server to client
+	RealtimeTypeReady             = "ready"
+	RealtimeTypeSpeechStart       = "speech_start"
+	RealtimeTypeSpeechEnd         = "speech_end"
+	RealtimeTypePartialTranscript = "partial_transcript"
+	RealtimeTypeFinalTranscript   = "final_transcript"
+	RealtimeTypeAssistantDelta    = "assistant_delta"
+	RealtimeTypeAssistantDone     = "assistant_done"
+	RealtimeTypeAudioStart        = "audio_start"
+	RealtimeTypeAudioEnd          = "audio_end"
+	RealtimeTypeError             = "error"
+)
+
+// RealtimeMessage is the JSON control message of the realtime protocol.
+// Audio never travels inside it; PCM and synthesized audio use binary frames.
+type RealtimeMessage struct {
+	Type        string `json:"type"`
+	SessionID   string `json:"session_id,omitempty"`
+	SampleRate  int    `json:"sample_rate,omitempty"`
+	STTProvider string `json:"stt_provider,omitempty"`
+	Language    string `json:"language,omitempty"`
+	Text        string `json:"text,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// Voice chooses the voice of the answers, in start messages
+	Voice *VoiceSelectionModel `json:"voice,omitempty"`
+	// OffsetMs places speech_start and speech_end within the utterance
+	OffsetMs *int64 `json:"offset_ms,omitempty"`
+}
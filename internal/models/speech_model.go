@@ -0,0 +1,9 @@
+package models
+
+// SpeechOptions steer text-to-speech for one reply
+type SpeechOptions struct {
+	// Language is the BCP 47 tag of the text, empty when unknown
+	Language string `json:"language,omitempty"`
+	// Voice is validated against the provider's catalog and capabilities
+	Voice VoiceSelectionModel `json:"voice"`
+}
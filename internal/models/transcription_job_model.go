@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Transcription job states, in the order a job goes through them
+const (
+	TranscriptionJobQueued    = "queued"
+	TranscriptionJobRunning   = "running"
+	TranscriptionJobSucceeded = "succeeded"
+	TranscriptionJobFailed    = "failed"
+)
+
+// TranscriptionJobModel is an asynchronous transcription, as returned by
+// /v1/transcriptions and posted to its webhook
+type TranscriptionJobModel struct {
+	ID          string               `json:"id"`
+	Status      string               `json:"status"`
+	STTProvider string               `json:"stt_provider,omitempty"`
+	WebhookURL  string               `json:"webhook_url,omitempty"`
+	Options     TranscriptionOptions `json:"options"`
+	CreatedAt   time.Time            `json:"created_at"`
+	StartedAt   *time.Time           `json:"started_at,omitempty"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	Result      *VoiceToTextModel    `json:"result,omitempty"`
+	Error       string               `json:"error,omitempty"`
+
+	// AudioPath is the spooled upload, kept until the job finishes
+	AudioPath string `json:"-"`
+}
+
+func (j TranscriptionJobModel) Finished() bool {
+	return j.Status == TranscriptionJobSucceeded || j.Status == TranscriptionJobFailed
+}
@@ -0,0 +1,20 @@
+package models
+
+// TTSCacheStatsModel reports how well the TTS cache is doing
+type TTSCacheStatsModel struct {
+	Enabled    bool               `json:"enabled"`
+	MemoryHits uint64             `json:"memory_hits"`
+	DiskHits   uint64             `json:"disk_hits"`
+	Misses     uint64             `json:"misses"`
+	HitRatio   float64            `json:"hit_ratio"`
+	Memory     *TTSCacheTierModel `json:"memory,omitempty"`
+	Disk       *TTSCacheTierModel `json:"disk,omitempty"`
+}
+
+// TTSCacheTierModel is how full one tier of the cache is
+type TTSCacheTierModel struct {
+	Entries       int    `json:"entries"`
+	Bytes         int64  `json:"bytes"`
+	CapacityBytes int64  `json:"capacity_bytes"`
+	Evictions     uint64 `json:"evictions"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// VocabularyModel is a tenant's custom vocabulary: phrases recognition is
+// biased towards and replacements applied to every transcript
+type VocabularyModel struct {
+	TenantID     string             `json:"tenant_id"`
+	Phrases      []PhraseHintModel  `json:"phrases"`
+	Replacements []ReplacementModel `json:"replacements"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// PhraseHintModel is a word or phrase likely to be spoken. A higher boost
+// makes it more likely to be recognized; zero leaves it to the provider.
+type PhraseHintModel struct {
+	Phrase string  `json:"phrase"`
+	Boost  float32 `json:"boost,omitempty"`
+}
+
+// ReplacementModel rewrites From, matched as whole words regardless of
+// case, to To
+type ReplacementModel struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Empty tells whether the vocabulary would change nothing
+func (v *VocabularyModel) Empty() bool {
+	return v == nil || (len(v.Phrases) == 0 && len(v.Replacements) == 0)
+}
+
+// PhraseTexts lists the phrases without their boosts
+func (v *VocabularyModel) PhraseTexts() []string {
+	if v == nil {
+		return nil
+	}
+	phrases := make([]string, len(v.Phrases))
+	for i, hint := range v.Phrases {
+		phrases[i] = hint.Phrase
+	}
+	return phrases
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// VoiceModel is one voice of the text-to-speech provider
+type VoiceModel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Languages are BCP 47 tags, empty when the voice speaks any language
+	Languages []string `json:"languages,omitempty"`
+}
+
+// VoiceSettingRangeModel bounds a voice setting
+type VoiceSettingRangeModel struct {
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Default float64 `json:"default"`
+}
+
+// VoiceCapabilitiesModel lists the settings a provider honors, nil for
+// those it cannot change
+type VoiceCapabilitiesModel struct {
+	// Speed multiplies the speaking rate
+	Speed *VoiceSettingRangeModel `json:"speed,omitempty"`
+	// Pitch shifts the voice, in semitones
+	Pitch           *VoiceSettingRangeModel `json:"pitch,omitempty"`
+	Stability       *VoiceSettingRangeModel `json:"stability,omitempty"`
+	SimilarityBoost *VoiceSettingRangeModel `json:"similarity_boost,omitempty"`
+}
+
+// VoiceCatalogModel describes what can be chosen in a VoiceSelectionModel
+type VoiceCatalogModel struct {
+	Provider       string                 `json:"provider"`
+	DefaultVoiceID string                 `json:"default_voice_id"`
+	Voices         []VoiceModel           `json:"voices"`
+	Capabilities   VoiceCapabilitiesModel `json:"capabilities"`
+}
+
+// VoiceSelectionModel picks a voice and tunes it. Fields left empty keep
+// the session's choice, or else the configured default.
+type VoiceSelectionModel struct {
+	VoiceID         string   `json:"voice_id,omitempty"`
+	Speed           *float64 `json:"speed,omitempty"`
+	Pitch           *float64 `json:"pitch,omitempty"`
+	Stability       *float64 `json:"stability,omitempty"`
+	SimilarityBoost *float64 `json:"similarity_boost,omitempty"`
+}
+
+// SessionVoiceModel is the voice a session's replies are spoken in
+type SessionVoiceModel struct {
+	SessionID string `json:"session_id"`
+	VoiceSelectionModel
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Empty tells whether the selection changes nothing
+func (v VoiceSelectionModel) Empty() bool {
+	return v == VoiceSelectionModel{}
+}
+
+// Override returns v with the fields set in override replaced
+func (v VoiceSelectionModel) Override(override VoiceSelectionModel) VoiceSelectionModel {
+	if override.VoiceID != "" {
+		v.VoiceID = override.VoiceID
+	}
+	if override.Speed != nil {
+		v.Speed = override.Speed
+	}
+	if override.Pitch != nil {
+		v.Pitch = override.Pitch
+	}
+	if override.Stability != nil {
+		v.Stability = override.Stability
+	}
+	if override.SimilarityBoost != nil {
+		v.SimilarityBoost = override.SimilarityBoost
+	}
+	return v
+}
+
+// Setting returns the value of a set setting, else the range's default
+func (r *VoiceSettingRangeModel) Setting(value *float64) float64 {
+	if value != nil {
+		return *value
+	}
+	return r.Default
+}
@@ -1,6 +1,144 @@
 package models
 
+import "strings"
+
 type VoiceToTextModel struct {
-	AudioFilePath string `json:"audio_file_path"`
+	AudioFilePath string `json:"audio_file_path,omitempty"`
 	Text          string `json:"text"`
+	// Language is the language detected in most of the audio, as the
+	// provider names it
+	Language string `json:"language,omitempty"`
+	// Confidence averages the segments' confidence over their duration
+	Confidence     float32           `json:"confidence,omitempty"`
+	OriginalFormat *AudioFormatModel `json:"original_format,omitempty"`
+	FinalFormat    *AudioFormatModel `json:"final_format,omitempty"`
+	// SpeechSegments are where voice activity detection found speech
+	SpeechSegments []SpeechSegmentModel `json:"speech_segments,omitempty"`
+	// Segments place the transcript in the upload, in order
+	Segments []TranscriptSegmentModel `json:"segments,omitempty"`
+}
+
+// Summarize fills Text, Language and Confidence in from the segments
+func (m *VoiceToTextModel) Summarize() {
+	texts := make([]string, 0, len(m.Segments))
+	languages := make(map[string]float64)
+	var weighted, weights float64
+
+	for _, segment := range m.Segments {
+		texts = append(texts, segment.Text)
+
+		// Zero length segments still count a little
+		duration := segment.End - segment.Start + 0.001
+		if segment.Language != "" {
+			languages[segment.Language] += duration
+		}
+		if segment.Confidence > 0 {
+			weighted += float64(segment.Confidence) * duration
+			weights += duration
+		}
+	}
+
+	m.Text = strings.Join(texts, " ")
+	m.Confidence = 0
+	if weights > 0 {
+		m.Confidence = float32(weighted / weights)
+	}
+
+	m.Language = ""
+	longest := 0.0
+	for language, duration := range languages {
+		if duration > longest || (duration == longest && language < m.Language) {
+			m.Language, longest = language, duration
+		}
+	}
+}
+
+// TranscriptSegmentModel is a stretch of the transcript, with its bounds in
+// seconds from the start of the upload
+type TranscriptSegmentModel struct {
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Text       string  `json:"text"`
+	Confidence float32 `json:"confidence,omitempty"`
+	Language   string  `json:"language,omitempty"`
+	// Speaker labels the segment as channel_N or speaker_N when speakers
+	// were separated
+	Speaker string `json:"speaker,omitempty"`
+	// Words time the words of Text
+	Words []WordModel `json:"words,omitempty"`
+	// Alternatives are the recognizer's hypotheses, most likely first.
+	// The first one is Text.
+	Alternatives []TranscriptAlternativeModel `json:"alternatives,omitempty"`
+}
+
+// Basic keeps only the bounds, text and speaker
+func (s TranscriptSegmentModel) Basic() TranscriptSegmentModel {
+	return TranscriptSegmentModel{Start: s.Start, End: s.End, Text: s.Text, Speaker: s.Speaker}
+}
+
+// WordModel is a recognized word, with its bounds in seconds from the start
+// of the upload
+type WordModel struct {
+	Word       string  `json:"word"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float32 `json:"confidence,omitempty"`
+}
+
+// TranscriptAlternativeModel is one hypothesis of an N-best list
+type TranscriptAlternativeModel struct {
+	Text       string  `json:"text"`
+	Confidence float32 `json:"confidence,omitempty"`
+}
+
+// SpeechSegmentModel bounds a stretch of speech, in seconds from the start
+// of the upload
+type SpeechSegmentModel struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// AudioFormatModel describes audio as uploaded or as sent to the recognizer
+type AudioFormatModel struct {
+	Format          string  `json:"format"`
+	SampleRate      int     `json:"sample_rate"`
+	Channels        int     `json:"channels,omitempty"`
+	BitDepth        int     `json:"bit_depth,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+// StreamingTranscript is one interim or final result of a streaming recognizer
+type StreamingTranscript struct {
+	Text      string  `json:"text"`
+	IsFinal   bool    `json:"is_final"`
+	Stability float32 `json:"stability,omitempty"`
+	// Language is the BCP 47 tag detected in a final result, when known
+	Language string `json:"language,omitempty"`
+	// Err is set on the last value sent when recognition fails
+	Err error `json:"-"`
+}
+
+// Speaker separation modes of a transcription
+const (
+	// SpeakerModeChannels recognizes each channel on its own, one speaker
+	// per channel, as in call recordings
+	SpeakerModeChannels = "channels"
+	// SpeakerModeDiarize tells speakers apart by their voices
+	SpeakerModeDiarize = "diarize"
+)
+
+// TranscriptionOptions are what a client may ask of a transcription
+type TranscriptionOptions struct {
+	// Language is the BCP 47 tag of the language spoken, empty for the
+	// provider's default or its own detection
+	Language string `json:"language,omitempty"`
+	// SpeakerMode is empty for a single speaker
+	SpeakerMode string `json:"speaker_mode,omitempty"`
+	// MinSpeakers and MaxSpeakers bound diarization, the provider decides
+	// when zero
+	MinSpeakers int `json:"min_speakers,omitempty"`
+	MaxSpeakers int `json:"max_speakers,omitempty"`
+	// Vocabulary is the tenant's, if it has one. Jobs keep the one they were
+	// submitted with.
+	Vocabulary *VocabularyModel `json:"vocabulary,omitempty"`
 }
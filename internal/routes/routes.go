@@ -1,15 +1,44 @@
 package routes
 
 import (
+	"golang-gin-boilerplate/internal/controllers"
 	"golang-gin-boilerplate/internal/handlers"
-	"os"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRouter() *gin.Engine {
 	router := gin.Default()
-	voiceAssistantHandler := handlers.NewVoiceAssistantHandler(os.Getenv("ELEVEN_LABS_API_KEY"))
+	sttRegistry, err := controllers.NewVoiceToTextRegistryFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure speech-to-text: %v", err)
+	}
+	vocabularies, err := controllers.NewVocabularyControllerFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure vocabularies: %v", err)
+	}
+	vocabularyHandler := handlers.NewVocabularyHandler(vocabularies)
+	voiceToTextHandler := handlers.NewVoiceToTextHandler(sttRegistry, vocabularies)
+	transcriptionJobs, err := controllers.NewTranscriptionJobControllerFromEnv(sttRegistry)
+	if err != nil {
+		log.Fatalf("Failed to configure transcription jobs: %v", err)
+	}
+	transcriptionJobHandler := handlers.NewTranscriptionJobHandler(sttRegistry, transcriptionJobs, vocabularies)
+	ttsProvider, err := controllers.NewTTSProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure text-to-speech: %v", err)
+	}
+	ttsPipeline, err := controllers.NewTTSPipelineFromEnv(ttsProvider)
+	if err != nil {
+		log.Fatalf("Failed to configure text-to-speech: %v", err)
+	}
+	voices, err := controllers.NewVoiceControllerFromEnv(ttsProvider)
+	if err != nil {
+		log.Fatalf("Failed to configure voices: %v", err)
+	}
+	voiceHandler := handlers.NewVoiceHandler(voices)
+	voiceAssistantHandler := handlers.NewVoiceAssistantHandler(sttRegistry, vocabularies, voices, ttsPipeline)
 
 	// Hello World routes
 	helloGroup := router.Group("/hello")
@@ -27,9 +56,24 @@ func SetupRouter() *gin.Engine {
 
 	v1 := router.Group("/v1")
 	{
-		v1.POST("/voice-to-text", handlers.VoiceToTextHandler)
+		v1.POST("/voice-to-text", voiceToTextHandler.VoiceToTextHandler)
+		v1.POST("/transcriptions", transcriptionJobHandler.CreateTranscriptionJobHandler)
+		v1.GET("/transcriptions/:id", transcriptionJobHandler.GetTranscriptionJobHandler)
+		v1.GET("/vocabulary", vocabularyHandler.GetVocabularyHandler)
+		v1.PUT("/vocabulary", vocabularyHandler.PutVocabularyHandler)
+		v1.DELETE("/vocabulary", vocabularyHandler.DeleteVocabularyHandler)
 		v1.POST("/voice-assistant", voiceAssistantHandler.VoiceAssistantHandler)
+		v1.GET("/voice-assistant/ws", voiceAssistantHandler.RealtimeVoiceAssistantHandler)
 		v1.POST("/voice-assistant-without-speech", voiceAssistantHandler.VoiceAssistantHandlerWithoutSpeech)
+		v1.POST("/voice-assistant-without-speech/stream", voiceAssistantHandler.VoiceAssistantHandlerWithoutSpeechStream)
+		v1.POST("/conversation/reset", voiceAssistantHandler.ResetConversationHandler)
+		v1.GET("/conversation/tokens", voiceAssistantHandler.GetContextTokensHandler)
+		v1.GET("/conversation/history", voiceAssistantHandler.GetConversationHistoryHandler)
+		v1.GET("/conversation/voice", voiceHandler.GetSessionVoiceHandler)
+		v1.PUT("/conversation/voice", voiceHandler.PutSessionVoiceHandler)
+		v1.DELETE("/conversation/voice", voiceHandler.DeleteSessionVoiceHandler)
+		v1.GET("/voices", voiceHandler.GetVoicesHandler)
+		v1.GET("/tts/cache", voiceAssistantHandler.GetTTSCacheStatsHandler)
 	}
 
 	return router
@@ -0,0 +1,47 @@
+package services
+
+import "time"
+
+// PlanChunks splits the span from start to end into chunks no longer than
+// maxDuration. Cuts go in the middle of the pauses between speech segments
+// where possible, and fall back to a hard cut inside speech longer than
+// maxDuration.
+func PlanChunks(segments []SpeechSegment, start, end, maxDuration time.Duration) []SpeechSegment {
+	if maxDuration <= 0 || end-start <= maxDuration {
+		return []SpeechSegment{{Start: start, End: end}}
+	}
+
+	var pauses []time.Duration
+	for i := 1; i < len(segments); i++ {
+		pauses = append(pauses, (segments[i-1].End+segments[i].Start)/2)
+	}
+
+	var chunks []SpeechSegment
+	chunkStart := start
+	for end-chunkStart > maxDuration {
+		cut := chunkStart + maxDuration
+		for i := len(pauses) - 1; i >= 0; i-- {
+			if pauses[i] > chunkStart && pauses[i] <= chunkStart+maxDuration {
+				cut = pauses[i]
+				break
+			}
+		}
+
+		chunks = append(chunks, SpeechSegment{Start: chunkStart, End: cut})
+		chunkStart = cut
+	}
+
+	return append(chunks, SpeechSegment{Start: chunkStart, End: end})
+}
+
+// Duration is the length of decoded audio, or of encoded audio as its
+// container tells
+func (a *Audio) Duration() time.Duration {
+	if !a.IsPCM() {
+		return a.EncodedDuration
+	}
+	if a.SampleRate <= 0 || a.Channels < 1 {
+		return 0
+	}
+	return time.Duration(int64(len(a.Samples)/a.Channels) * int64(time.Second) / int64(a.SampleRate))
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanChunks(t *testing.T) {
+	s := time.Second
+	speech := func(bounds ...time.Duration) []SpeechSegment {
+		var segments []SpeechSegment
+		for i := 0; i < len(bounds); i += 2 {
+			segments = append(segments, SpeechSegment{Start: bounds[i], End: bounds[i+1]})
+		}
+		return segments
+	}
+
+	tests := []struct {
+		name        string
+		segments    []SpeechSegment
+		start, end  time.Duration
+		maxDuration time.Duration
+		want        []SpeechSegment
+	}{
+		{
+			name:        "fits",
+			segments:    speech(0, 4*s, 5*s, 9*s),
+			end:         10 * s,
+			maxDuration: 10 * s,
+			want:        speech(0, 10*s),
+		},
+		{
+			name:        "no limit",
+			end:         time.Hour,
+			maxDuration: 0,
+			want:        speech(0, time.Hour),
+		},
+		{
+			name:        "latest pause that fits",
+			segments:    speech(0, 4*s, 5*s, 9*s, 10*s, 14*s, 15*s, 19*s),
+			end:         20 * s,
+			maxDuration: 10 * s,
+			want:        speech(0, 9500*time.Millisecond, 9500*time.Millisecond, 14500*time.Millisecond, 14500*time.Millisecond, 20*s),
+		},
+		{
+			name:        "hard cuts without pauses",
+			segments:    speech(0, 25*s),
+			end:         25 * s,
+			maxDuration: 10 * s,
+			want:        speech(0, 10*s, 10*s, 20*s, 20*s, 25*s),
+		},
+		{
+			name:        "hard cuts after the last pause",
+			segments:    speech(0, 2*s, 3*s, 30*s),
+			end:         30 * s,
+			maxDuration: 10 * s,
+			want:        speech(0, 2500*time.Millisecond, 2500*time.Millisecond, 12500*time.Millisecond, 12500*time.Millisecond, 22500*time.Millisecond, 22500*time.Millisecond, 30*s),
+		},
+		{
+			name:        "trimmed start",
+			segments:    speech(6*s, 10*s, 12*s, 20*s),
+			start:       5 * s,
+			end:         21 * s,
+			maxDuration: 10 * s,
+			want:        speech(5*s, 11*s, 11*s, 21*s),
+		},
+		{
+			name:        "pause before the start is ignored",
+			segments:    speech(0, 1*s, 2*s, 30*s),
+			start:       5 * s,
+			end:         20 * s,
+			maxDuration: 10 * s,
+			want:        speech(5*s, 15*s, 15*s, 20*s),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanChunks(tt.segments, tt.start, tt.end, tt.maxDuration)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("PlanChunks = %v, want %v", got, tt.want)
+			}
+
+			// Chunks cover the span without gaps and stay within the limit
+			if got[0].Start != tt.start || got[len(got)-1].End != tt.end {
+				t.Errorf("chunks cover %v to %v, want %v to %v", got[0].Start, got[len(got)-1].End, tt.start, tt.end)
+			}
+			for i, chunk := range got {
+				if i > 0 && chunk.Start != got[i-1].End {
+					t.Errorf("chunk %d starts at %v, the one before ends at %v", i, chunk.Start, got[i-1].End)
+				}
+				if tt.maxDuration > 0 && chunk.End-chunk.Start > tt.maxDuration {
+					t.Errorf("chunk %d is %v long, over %v", i, chunk.End-chunk.Start, tt.maxDuration)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,451 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-audio/wav"
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/mewkiz/flac"
+)
+
+// AudioFormat identifies the container and codec of an uploaded recording
+type AudioFormat string
+
+const (
+	AudioFormatUnknown  AudioFormat = ""
+	AudioFormatWAV      AudioFormat = "wav"
+	AudioFormatMP3      AudioFormat = "mp3"
+	AudioFormatFLAC     AudioFormat = "flac"
+	AudioFormatOggOpus  AudioFormat = "ogg_opus"
+	AudioFormatWebMOpus AudioFormat = "webm_opus"
+	AudioFormatPCM      AudioFormat = "pcm"
+	AudioFormatMP4      AudioFormat = "mp4"
+)
+
+const (
+	// Raw PCM has no header, so uploads named *.pcm or *.raw are taken to be
+	// little-endian 16-bit mono at this rate
+	RawPCMSampleRate = 16000
+
+	// Opus always decodes at 48 kHz whatever rate was recorded
+	opusSampleRate = 48000
+
+	sniffBytes = 4096
+)
+
+// Audio is an upload after ingestion. Formats that can be decoded in pure Go
+// come back as PCM samples. Opus is kept encoded, since the recognizers
+// accept it as is.
+type Audio struct {
+	Format     AudioFormat
+	SampleRate int
+	// Channels is 0 when the container does not say
+	Channels int
+	BitDepth int
+
+	// Samples are interleaved, signed and BitDepth bits wide. Nil for
+	// encoded audio.
+	Samples []int
+	Encoded []byte
+	// EncodedDuration is the length of encoded audio as its container
+	// tells, 0 when it does not
+	EncodedDuration time.Duration
+}
+
+func (a *Audio) IsPCM() bool {
+	return a.Samples != nil
+}
+
+// LoadAudio reads and decodes an audio file of any supported format
+func LoadAudio(path string) (*Audio, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio file: %v", err)
+	}
+	return DecodeAudio(data, filepath.Base(path))
+}
+
+// SniffAudioFile detects the format of a file from its first bytes
+func SniffAudioFile(path string) (AudioFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return AudioFormatUnknown, fmt.Errorf("failed to open audio file: %v", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, sniffBytes)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return AudioFormatUnknown, fmt.Errorf("failed to read audio file: %v", err)
+	}
+
+	return SniffAudioFormat(header[:n], filepath.Base(path)), nil
+}
+
+// SniffAudioFormat detects the format from magic numbers. The file name is
+// only consulted for raw PCM, which has none. It wins over the two byte MP3
+// frame sync, which raw samples like FF FF (-1) match by chance.
+func SniffAudioFormat(header []byte, filename string) AudioFormat {
+	isRawPCM := false
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pcm", ".raw":
+		isRawPCM = true
+	}
+
+	switch {
+	case len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE":
+		return AudioFormatWAV
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return AudioFormatFLAC
+	case bytes.HasPrefix(header, []byte("OggS")):
+		if bytes.Contains(header, []byte("OpusHead")) {
+			return AudioFormatOggOpus
+		}
+	case bytes.HasPrefix(header, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		if bytes.Contains(header, []byte("A_OPUS")) {
+			return AudioFormatWebMOpus
+		}
+	case len(header) >= 8 && string(header[4:8]) == "ftyp":
+		return AudioFormatMP4
+	case isRawPCM:
+		return AudioFormatPCM
+	case bytes.HasPrefix(header, []byte("ID3")), isMP3FrameSync(header):
+		return AudioFormatMP3
+	}
+
+	if isRawPCM {
+		return AudioFormatPCM
+	}
+	return AudioFormatUnknown
+}
+
+// isMP3FrameSync checks for an MPEG audio frame header without ID3 tag
+func isMP3FrameSync(header []byte) bool {
+	return len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x06 != 0
+}
+
+// DecodeAudio sniffs data and decodes it to PCM where possible
+func DecodeAudio(data []byte, filename string) (*Audio, error) {
+	header := data
+	if len(header) > sniffBytes {
+		header = header[:sniffBytes]
+	}
+
+	switch format := SniffAudioFormat(header, filename); format {
+	case AudioFormatWAV:
+		return decodeWAV(data)
+	case AudioFormatMP3:
+		return decodeMP3(data)
+	case AudioFormatFLAC:
+		return decodeFLAC(data)
+	case AudioFormatPCM:
+		return decodeRawPCM(data)
+	case AudioFormatOggOpus:
+		return &Audio{
+			Format:          format,
+			SampleRate:      opusSampleRate,
+			Channels:        oggOpusChannels(data),
+			Encoded:         data,
+			EncodedDuration: oggOpusDuration(data),
+		}, nil
+	case AudioFormatWebMOpus:
+		return &Audio{
+			Format:          format,
+			SampleRate:      opusSampleRate,
+			Encoded:         data,
+			EncodedDuration: webmDuration(data),
+		}, nil
+	case AudioFormatMP4:
+		return nil, fmt.Errorf("unsupported audio format: MP4/M4A (AAC) cannot be decoded, send WAV, MP3, FLAC, Ogg/Opus or WebM/Opus")
+	default:
+		return nil, fmt.Errorf("unsupported audio format: send WAV, MP3, FLAC, Ogg/Opus, WebM/Opus or raw 16-bit PCM (.pcm)")
+	}
+}
+
+func decodeWAV(data []byte) (*Audio, error) {
+	decoder := wav.NewDecoder(bytes.NewReader(data))
+	if !decoder.IsValidFile() {
+		return nil, fmt.Errorf("invalid WAV file")
+	}
+
+	// 1 is integer PCM, 0xFFFE the extensible header that wraps it
+	if decoder.WavAudioFormat != 1 && decoder.WavAudioFormat != 0xFFFE {
+		return nil, fmt.Errorf("unsupported WAV encoding %d: only integer PCM is supported", decoder.WavAudioFormat)
+	}
+
+	buf, err := decoder.FullPCMBuffer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode WAV file: %v", err)
+	}
+
+	bitDepth := int(decoder.BitDepth)
+	if bitDepth == 8 {
+		// 8-bit WAV samples are unsigned
+		for i := range buf.Data {
+			buf.Data[i] -= 128
+		}
+	}
+
+	return &Audio{
+		Format:     AudioFormatWAV,
+		SampleRate: buf.Format.SampleRate,
+		Channels:   buf.Format.NumChannels,
+		BitDepth:   bitDepth,
+		Samples:    buf.Data,
+	}, nil
+}
+
+func decodeMP3(data []byte) (*Audio, error) {
+	decoder, err := mp3.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode MP3 file: %v", err)
+	}
+
+	// The decoder always produces 16-bit little-endian stereo
+	pcm, err := io.ReadAll(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode MP3 file: %v", err)
+	}
+
+	return &Audio{
+		Format:     AudioFormatMP3,
+		SampleRate: decoder.SampleRate(),
+		Channels:   2,
+		BitDepth:   16,
+		Samples:    SamplesFromPCM16(pcm),
+	}, nil
+}
+
+func decodeFLAC(data []byte) (*Audio, error) {
+	stream, err := flac.New(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode FLAC file: %v", err)
+	}
+	defer stream.Close()
+
+	channels := int(stream.Info.NChannels)
+	samples := make([]int, 0, int(stream.Info.NSamples)*channels)
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode FLAC file: %v", err)
+		}
+
+		for i := 0; i < int(frame.BlockSize); i++ {
+			for _, subframe := range frame.Subframes {
+				samples = append(samples, int(subframe.Samples[i]))
+			}
+		}
+	}
+
+	return &Audio{
+		Format:     AudioFormatFLAC,
+		SampleRate: int(stream.Info.SampleRate),
+		Channels:   channels,
+		BitDepth:   int(stream.Info.BitsPerSample),
+		Samples:    samples,
+	}, nil
+}
+
+func decodeRawPCM(data []byte) (*Audio, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("raw PCM must be 16-bit samples, got an odd number of bytes")
+	}
+
+	return &Audio{
+		Format:     AudioFormatPCM,
+		SampleRate: RawPCMSampleRate,
+		Channels:   1,
+		BitDepth:   16,
+		Samples:    SamplesFromPCM16(data),
+	}, nil
+}
+
+// SamplesFromPCM16 decodes little-endian 16-bit PCM
+func SamplesFromPCM16(pcm []byte) []int {
+	samples := make([]int, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+	}
+	return samples
+}
+
+// oggOpusChannels reads the channel count from the OpusHead packet
+func oggOpusChannels(data []byte) int {
+	index := bytes.Index(data, []byte("OpusHead"))
+	if index < 0 || index+9 >= len(data) {
+		return 0
+	}
+	return int(data[index+9])
+}
+
+// oggOpusDuration reads the granule position of the last page, which counts
+// 48 kHz samples including the pre-skip of the OpusHead packet
+func oggOpusDuration(data []byte) time.Duration {
+	var preSkip int64
+	if index := bytes.Index(data, []byte("OpusHead")); index >= 0 && index+12 <= len(data) {
+		preSkip = int64(binary.LittleEndian.Uint16(data[index+10:]))
+	}
+
+	for end := len(data); end > 0; {
+		index := bytes.LastIndex(data[:end], []byte("OggS"))
+		if index < 0 {
+			break
+		}
+		end = index
+
+		// A granule of -1 marks a page on which no packet ends
+		if index+14 > len(data) {
+			continue
+		}
+		granule := int64(binary.LittleEndian.Uint64(data[index+6:]))
+		if granule < 0 {
+			continue
+		}
+		if granule <= preSkip {
+			return 0
+		}
+		return time.Duration((granule - preSkip) * int64(time.Second) / opusSampleRate)
+	}
+
+	return 0
+}
+
+// EBML element IDs used to find the length of a WebM recording
+const (
+	webmSegment       = 0x18538067
+	webmInfo          = 0x1549A966
+	webmTimecodeScale = 0x2AD7B1
+	webmInfoDuration  = 0x4489
+	webmCluster       = 0x1F43B675
+	webmTimecode      = 0xE7
+	webmBlockGroup    = 0xA0
+	webmBlock         = 0xA1
+	webmSimpleBlock   = 0xA3
+)
+
+// webmDuration takes the Duration of the segment info or, as browsers
+// recording live leave it out, the timecode of the last block. Segments and
+// clusters of unknown size, as recorders write them, are read through.
+func webmDuration(data []byte) time.Duration {
+	scale := int64(time.Millisecond)
+	var duration float64
+	var cluster int64
+
+	for pos := 0; pos < len(data); {
+		id, idLength := ebmlID(data[pos:])
+		if idLength == 0 {
+			break
+		}
+		size, sizeLength, unknown := ebmlSize(data[pos+idLength:])
+		if sizeLength == 0 {
+			break
+		}
+		pos += idLength + sizeLength
+
+		switch id {
+		case webmSegment, webmInfo, webmCluster, webmBlockGroup:
+			// Read the children in place
+			continue
+		}
+		if unknown || size > int64(len(data)-pos) {
+			break
+		}
+		body := data[pos : pos+int(size)]
+		pos += int(size)
+
+		switch id {
+		case webmTimecodeScale:
+			if value := ebmlUint(body); value > 0 {
+				scale = int64(value)
+			}
+		case webmInfoDuration:
+			switch len(body) {
+			case 4:
+				duration = math.Max(duration, float64(math.Float32frombits(binary.BigEndian.Uint32(body))))
+			case 8:
+				duration = math.Max(duration, math.Float64frombits(binary.BigEndian.Uint64(body)))
+			}
+		case webmTimecode:
+			cluster = int64(ebmlUint(body))
+		case webmBlock, webmSimpleBlock:
+			// The track number comes first, then the timecode relative to
+			// the cluster
+			_, trackLength, _ := ebmlSize(body)
+			if trackLength == 0 || len(body) < trackLength+2 {
+				continue
+			}
+			relative := int64(int16(binary.BigEndian.Uint16(body[trackLength:])))
+			duration = math.Max(duration, float64(cluster+relative))
+		}
+	}
+
+	return time.Duration(duration * float64(scale))
+}
+
+// ebmlID returns an element ID with its length marker, and its length
+func ebmlID(data []byte) (uint32, int) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0
+	}
+	length := 1
+	for mask := byte(0x80); data[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 4 || length > len(data) {
+		return 0, 0
+	}
+
+	var id uint32
+	for _, b := range data[:length] {
+		id = id<<8 | uint32(b)
+	}
+	return id, length
+}
+
+// ebmlSize decodes a variable length size. unknown is set when all its
+// bits are ones, which live recorders write for elements still growing.
+func ebmlSize(data []byte) (size int64, length int, unknown bool) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, false
+	}
+	length = 1
+	mask := byte(0x80)
+	for data[0]&mask == 0 {
+		length++
+		mask >>= 1
+	}
+	if length > len(data) {
+		return 0, 0, false
+	}
+
+	value := uint64(data[0] & (mask - 1))
+	allOnes := value == uint64(mask-1)
+	for _, b := range data[1:length] {
+		value = value<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+	if allOnes || value > math.MaxInt64 {
+		return 0, length, true
+	}
+	return int64(value), length, false
+}
+
+func ebmlUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The fixtures in testdata are tiny recordings of each supported container:
+// 0.25 s sines at 16 kHz for the lossless formats, and 20 silent frames or
+// 100 ms of silence for MP3 and Opus. The M4A holds just its ftyp box.
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return data
+}
+
+func TestDecodeAudioFixtures(t *testing.T) {
+	tests := []struct {
+		file       string
+		format     AudioFormat
+		sampleRate int
+		channels   int
+		bitDepth   int
+		// samples is the interleaved sample count, -1 for encoded audio
+		samples int
+	}{
+		{file: "sine_16k_stereo.wav", format: AudioFormatWAV, sampleRate: 16000, channels: 2, bitDepth: 16, samples: 8000},
+		{file: "sine_16k_mono.pcm", format: AudioFormatPCM, sampleRate: 16000, channels: 1, bitDepth: 16, samples: 4000},
+		{file: "sine_16k_mono.flac", format: AudioFormatFLAC, sampleRate: 16000, channels: 1, bitDepth: 16, samples: 4000},
+		{file: "silence_44k_mono.mp3", format: AudioFormatMP3, sampleRate: 44100, channels: 2, bitDepth: 16, samples: 2 * 20 * 1152},
+		{file: "silence_mono.opus", format: AudioFormatOggOpus, sampleRate: 48000, channels: 1, samples: -1},
+		{file: "silence_mono.webm", format: AudioFormatWebMOpus, sampleRate: 48000, samples: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data := readFixture(t, tt.file)
+
+			if got := SniffAudioFormat(data, tt.file); got != tt.format {
+				t.Fatalf("SniffAudioFormat = %q, want %q", got, tt.format)
+			}
+
+			audio, err := DecodeAudio(data, tt.file)
+			if err != nil {
+				t.Fatalf("DecodeAudio: %v", err)
+			}
+			if audio.Format != tt.format || audio.SampleRate != tt.sampleRate || audio.Channels != tt.channels || audio.BitDepth != tt.bitDepth {
+				t.Errorf("got %s %d Hz, %d channels, %d bits; want %s %d Hz, %d channels, %d bits",
+					audio.Format, audio.SampleRate, audio.Channels, audio.BitDepth,
+					tt.format, tt.sampleRate, tt.channels, tt.bitDepth)
+			}
+
+			if tt.samples < 0 {
+				if audio.IsPCM() || len(audio.Encoded) != len(data) {
+					t.Errorf("encoded audio not kept as uploaded")
+				}
+				return
+			}
+			if !audio.IsPCM() || len(audio.Samples) != tt.samples {
+				t.Errorf("got %d samples, want %d", len(audio.Samples), tt.samples)
+			}
+		})
+	}
+}
+
+func TestDecodeAudioLosslessSamples(t *testing.T) {
+	pcm, err := DecodeAudio(readFixture(t, "sine_16k_mono.pcm"), "sine_16k_mono.pcm")
+	if err != nil {
+		t.Fatalf("decode PCM: %v", err)
+	}
+	if pcm.Samples[0] != -1 {
+		t.Errorf("first PCM sample = %d, want -1", pcm.Samples[0])
+	}
+
+	flac, err := DecodeAudio(readFixture(t, "sine_16k_mono.flac"), "sine_16k_mono.flac")
+	if err != nil {
+		t.Fatalf("decode FLAC: %v", err)
+	}
+	// Both hold the same 440 Hz sine, apart from the PCM's leading -1
+	for i := 1; i < len(flac.Samples); i++ {
+		if flac.Samples[i-1] != pcm.Samples[i] {
+			t.Fatalf("FLAC sample %d = %d, PCM has %d", i-1, flac.Samples[i-1], pcm.Samples[i])
+		}
+	}
+}
+
+func TestDecodeAudioRejectsM4A(t *testing.T) {
+	data := readFixture(t, "ftyp_only.m4a")
+	if got := SniffAudioFormat(data, "ftyp_only.m4a"); got != AudioFormatMP4 {
+		t.Fatalf("SniffAudioFormat = %q, want %q", got, AudioFormatMP4)
+	}
+	if _, err := DecodeAudio(data, "ftyp_only.m4a"); err == nil || !strings.Contains(err.Error(), "MP4/M4A") {
+		t.Fatalf("DecodeAudio error = %v, want the unsupported MP4 error", err)
+	}
+}
+
+func TestSniffRawPCMBeforeMP3FrameSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte
+		filename string
+		want     AudioFormat
+	}{
+		{name: "minus one", header: []byte{0xFF, 0xFF, 0x00, 0x00}, filename: "speech.pcm", want: AudioFormatPCM},
+		{name: "frame sync pattern", header: []byte{0xFF, 0xFB, 0x10, 0xC4}, filename: "speech.RAW", want: AudioFormatPCM},
+		{name: "mp3 named mp3", header: []byte{0xFF, 0xFB, 0x10, 0xC4}, filename: "speech.mp3", want: AudioFormatMP3},
+		{name: "mp3 without name", header: []byte{0xFF, 0xFB, 0x10, 0xC4}, filename: "", want: AudioFormatMP3},
+		{name: "wav named pcm", header: []byte("RIFF\x00\x00\x00\x00WAVE"), filename: "speech.pcm", want: AudioFormatWAV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffAudioFormat(tt.header, tt.filename); got != tt.want {
+				t.Errorf("SniffAudioFormat = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodedOpusDuration(t *testing.T) {
+	ogg, err := DecodeAudio(readFixture(t, "silence_mono.opus"), "silence_mono.opus")
+	if err != nil {
+		t.Fatalf("decode Ogg: %v", err)
+	}
+	// Five 20 ms packets
+	if got := ogg.Duration(); got != 100*time.Millisecond {
+		t.Errorf("Ogg duration = %v, want 100ms", got)
+	}
+
+	webm, err := DecodeAudio(readFixture(t, "silence_mono.webm"), "silence_mono.webm")
+	if err != nil {
+		t.Fatalf("decode WebM: %v", err)
+	}
+	// The fixture has no Duration, so the last block's start counts
+	if got := webm.Duration(); got != 80*time.Millisecond {
+		t.Errorf("WebM duration = %v, want 80ms", got)
+	}
+}
+
+func TestWebMDurationLiveRecording(t *testing.T) {
+	// Browsers write the segment and clusters with unknown sizes
+	unknownSize := []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	segment := concatBytes([]byte{0x18, 0x53, 0x80, 0x67}, unknownSize)
+	timecodeScale := []byte{0x2A, 0xD7, 0xB1, 0x83, 0x0F, 0x42, 0x40} // 1ms
+	duration := []byte{0x44, 0x89, 0x84, 0x44, 0xBB, 0x80, 0x00}      // 1500 as float32
+	cluster := concatBytes([]byte{0x1F, 0x43, 0xB6, 0x75}, unknownSize,
+		[]byte{0xE7, 0x82, 0x03, 0xE8},                   // Timecode 1000
+		[]byte{0xA3, 0x85, 0x81, 0x01, 0x90, 0x80, 0xF8}, // SimpleBlock at +400
+	)
+
+	tests := []struct {
+		name string
+		info []byte
+		want time.Duration
+	}{
+		{name: "with duration", info: concatBytes([]byte{0x15, 0x49, 0xA9, 0x66, 0x8E}, timecodeScale, duration), want: 1500 * time.Millisecond},
+		{name: "without duration", info: concatBytes([]byte{0x15, 0x49, 0xA9, 0x66, 0x87}, timecodeScale), want: 1400 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webmDuration(concatBytes(segment, tt.info, cluster)); got != tt.want {
+				t.Errorf("webmDuration = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func concatBytes(parts ...[]byte) []byte {
+	var data []byte
+	for _, part := range parts {
+		data = append(data, part...)
+	}
+	return data
+}
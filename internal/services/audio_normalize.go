@@ -0,0 +1,220 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// DefaultRecognitionSampleRate is what speech recognizers are tuned for
+const DefaultRecognitionSampleRate = 16000
+
+// Normalize returns the audio as 16-bit mono at sampleRate, the format every
+// recognizer takes. It works in memory on decoded audio of any bit depth and
+// channel count. A sampleRate of 0 keeps the original rate.
+func (a *Audio) Normalize(sampleRate int) (*Audio, error) {
+	return a.normalize(sampleRate, false)
+}
+
+// NormalizeChannels is Normalize without the down-mix, for recognizing each
+// channel on its own
+func (a *Audio) NormalizeChannels(sampleRate int) (*Audio, error) {
+	return a.normalize(sampleRate, true)
+}
+
+func (a *Audio) normalize(sampleRate int, keepChannels bool) (*Audio, error) {
+	if !a.IsPCM() {
+		return nil, fmt.Errorf("%s audio is not decoded", a.Format)
+	}
+	if a.Channels < 1 {
+		return nil, fmt.Errorf("invalid channel count %d", a.Channels)
+	}
+	if a.BitDepth < 1 || a.BitDepth > 32 {
+		return nil, fmt.Errorf("unsupported bit depth %d", a.BitDepth)
+	}
+
+	if sampleRate <= 0 {
+		sampleRate = a.SampleRate
+	}
+
+	channels := a.Channels
+	samples := a.Samples
+	if !keepChannels {
+		samples = DownmixToMono(samples, channels)
+		channels = 1
+	}
+	samples = ConvertBitDepth(samples, a.BitDepth, 16)
+
+	return &Audio{
+		Format:     a.Format,
+		SampleRate: sampleRate,
+		Channels:   channels,
+		BitDepth:   16,
+		Samples:    Resample(samples, channels, 16, a.SampleRate, sampleRate),
+	}, nil
+}
+
+// Channel returns one channel of decoded audio as mono
+func (a *Audio) Channel(index int) *Audio {
+	channels := a.Channels
+	if channels < 1 {
+		channels = 1
+	}
+
+	samples := make([]int, len(a.Samples)/channels)
+	for i := range samples {
+		samples[i] = a.Samples[i*channels+index]
+	}
+
+	mono := *a
+	mono.Channels = 1
+	mono.Samples = samples
+	return &mono
+}
+
+// Describe summarizes the format for API responses
+func (a *Audio) Describe() *models.AudioFormatModel {
+	description := &models.AudioFormatModel{
+		Format:     string(a.Format),
+		SampleRate: a.SampleRate,
+		Channels:   a.Channels,
+		BitDepth:   a.BitDepth,
+	}
+	description.DurationSeconds = a.Duration().Seconds()
+	return description
+}
+
+// DownmixToMono averages the channels of interleaved samples, rounding to
+// the nearest value. The sum is kept in 64 bits so loud input cannot
+// overflow, and a trailing partial frame is dropped.
+func DownmixToMono(samples []int, channels int) []int {
+	if channels <= 1 {
+		return samples
+	}
+
+	mono := make([]int, len(samples)/channels)
+	half := int64(channels) / 2
+	for i := range mono {
+		var sum int64
+		for _, sample := range samples[i*channels : (i+1)*channels] {
+			sum += int64(sample)
+		}
+		// Integer division truncates towards zero, so round away from it
+		if sum < 0 {
+			mono[i] = int((sum - half) / int64(channels))
+		} else {
+			mono[i] = int((sum + half) / int64(channels))
+		}
+	}
+
+	return mono
+}
+
+// ConvertBitDepth rescales signed samples from one bit depth to another.
+// Reducing depth rounds to the nearest value and clamps to the target range.
+func ConvertBitDepth(samples []int, from, to int) []int {
+	if from == to {
+		return samples
+	}
+
+	converted := make([]int, len(samples))
+	if from < to {
+		shift := to - from
+		for i, sample := range samples {
+			converted[i] = sample << shift
+		}
+		return converted
+	}
+
+	shift := from - to
+	half := int64(1) << (shift - 1)
+	maxValue := int64(1)<<(to-1) - 1
+	minValue := -(int64(1) << (to - 1))
+	for i, sample := range samples {
+		scaled := (int64(sample) + half) >> shift
+		if scaled > maxValue {
+			scaled = maxValue
+		} else if scaled < minValue {
+			scaled = minValue
+		}
+		converted[i] = int(scaled)
+	}
+
+	return converted
+}
+
+// Mono returns the samples mixed down to one channel
+func (a *Audio) Mono() []int {
+	return DownmixToMono(a.Samples, a.Channels)
+}
+
+// PCM16 returns the samples as interleaved little-endian 16-bit PCM
+func (a *Audio) PCM16() []byte {
+	samples := ConvertBitDepth(a.Samples, a.BitDepth, 16)
+
+	pcm := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(sample)))
+	}
+	return pcm
+}
+
+// PreparedAudio is an upload made ready for a recognizer
+type PreparedAudio struct {
+	Original *Audio
+	Audio    *Audio
+	// Segments is the speech found by voice activity detection, relative to
+	// the original audio. Nil when detection did not run.
+	Segments []SpeechSegment
+	// Offset is where Audio starts in the original once leading silence is
+	// trimmed
+	Offset time.Duration
+}
+
+// PrepareAudio loads a file and normalizes decoded audio for recognition.
+// With a VAD config, silence before the first and after the last speech is
+// trimmed, and ErrNoSpeechDetected returned when there is no speech at all.
+// Channels are mixed down unless keepChannels is set. Audio that stays
+// encoded, like Opus, is prepared as loaded.
+func PrepareAudio(path string, sampleRate int, vad *VADConfig, keepChannels bool) (*PreparedAudio, error) {
+	original, err := LoadAudio(path)
+	if err != nil {
+		return nil, err
+	}
+
+	prepared := &PreparedAudio{Original: original, Audio: original}
+	if !original.IsPCM() {
+		return prepared, nil
+	}
+
+	prepared.Audio, err = original.normalize(sampleRate, keepChannels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize audio: %v", err)
+	}
+
+	if vad != nil {
+		prepared.Segments = DetectSpeech(prepared.Audio.Mono(), prepared.Audio.SampleRate, *vad)
+		if len(prepared.Segments) == 0 {
+			return nil, ErrNoSpeechDetected
+		}
+
+		prepared.Offset = prepared.Segments[0].Start
+		prepared.Audio = prepared.Audio.Slice(prepared.Offset, prepared.Segments[len(prepared.Segments)-1].End)
+	}
+
+	return prepared, nil
+}
+
+// Report adds the formats and speech segments to a transcription result
+func (p *PreparedAudio) Report(result *models.VoiceToTextModel) {
+	result.OriginalFormat = p.Original.Describe()
+	result.FinalFormat = p.Audio.Describe()
+	for _, segment := range p.Segments {
+		result.SpeechSegments = append(result.SpeechSegments, models.SpeechSegmentModel{
+			Start: segment.Start.Seconds(),
+			End:   segment.End.Seconds(),
+		})
+	}
+}
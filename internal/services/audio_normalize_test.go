@@ -0,0 +1,168 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var (
+	testBitDepths    = []int{8, 16, 24, 32}
+	testChannelCount = []int{1, 2, 3, 6}
+)
+
+// generatedSine is one random sine per channel at the given bit depth,
+// interleaved. Amplitudes are fractions of full scale.
+type generatedSine struct {
+	bitDepth   int
+	channels   int
+	sampleRate int
+	amplitudes []float64
+	freqs      []float64
+	phases     []float64
+}
+
+func randomSine(rng *rand.Rand, bitDepth, channels int, fullScale bool) generatedSine {
+	sine := generatedSine{bitDepth: bitDepth, channels: channels, sampleRate: 16000}
+	for c := 0; c < channels; c++ {
+		amplitude := 1.0
+		if !fullScale {
+			amplitude = 0.05 + 0.9*rng.Float64()
+		}
+		sine.amplitudes = append(sine.amplitudes, amplitude)
+		sine.freqs = append(sine.freqs, 50+2000*rng.Float64())
+		sine.phases = append(sine.phases, 2*math.Pi*rng.Float64())
+	}
+	return sine
+}
+
+// value is the exact sample of channel c at frame i, before quantization, at
+// a bit depth of depth
+func (s generatedSine) value(c, i, depth int) float64 {
+	scale := float64(int64(1)<<(depth-1)) - 1
+	return s.amplitudes[c] * scale * math.Sin(2*math.Pi*s.freqs[c]*float64(i)/float64(s.sampleRate)+s.phases[c])
+}
+
+func (s generatedSine) audio(frames int) *Audio {
+	samples := make([]int, 0, frames*s.channels)
+	for i := 0; i < frames; i++ {
+		for c := 0; c < s.channels; c++ {
+			samples = append(samples, int(math.Round(s.value(c, i, s.bitDepth))))
+		}
+	}
+	return &Audio{Format: AudioFormatWAV, SampleRate: s.sampleRate, Channels: s.channels, BitDepth: s.bitDepth, Samples: samples}
+}
+
+func TestDownmixKeepsAmplitude(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const frames = 1600
+
+	for _, bitDepth := range testBitDepths {
+		for _, channels := range testChannelCount {
+			for trial := 0; trial < 20; trial++ {
+				sine := randomSine(rng, bitDepth, channels, false)
+				mono := DownmixToMono(sine.audio(frames).Samples, channels)
+				if len(mono) != frames {
+					t.Fatalf("%d bit, %d channels: %d frames, want %d", bitDepth, channels, len(mono), frames)
+				}
+
+				for i, got := range mono {
+					// The mean of the rounded channels, within one LSB
+					var want float64
+					for c := 0; c < channels; c++ {
+						want += sine.value(c, i, bitDepth)
+					}
+					want /= float64(channels)
+					if math.Abs(float64(got)-want) > 1 {
+						t.Fatalf("%d bit, %d channels, frame %d: %d, want %.2f ±1", bitDepth, channels, i, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNormalizeFullScale(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	const frames = 1600
+
+	for _, bitDepth := range testBitDepths {
+		for _, channels := range testChannelCount {
+			// All channels at the extremes of the range, and full scale sines
+			for _, extreme := range []int64{int64(1)<<(bitDepth-1) - 1, -(int64(1) << (bitDepth - 1))} {
+				samples := make([]int, frames*channels)
+				for i := range samples {
+					samples[i] = int(extreme)
+				}
+				audio := &Audio{Format: AudioFormatWAV, SampleRate: 16000, Channels: channels, BitDepth: bitDepth, Samples: samples}
+				normalized, err := audio.Normalize(0)
+				if err != nil {
+					t.Fatalf("Normalize: %v", err)
+				}
+
+				// The extreme rescaled to 16 bits, which is short of full
+				// scale for positive 8 bit audio
+				want := int(math.Max(math.MinInt16, math.Min(math.MaxInt16,
+					math.Round(float64(extreme)*math.Pow(2, float64(16-bitDepth))))))
+				for i, got := range normalized.Samples {
+					if got != want {
+						t.Fatalf("%d bit, %d channels, extreme %d: sample %d is %d, want %d", bitDepth, channels, extreme, i, got, want)
+					}
+				}
+			}
+
+			sine := randomSine(rng, bitDepth, channels, true)
+			normalized, err := sine.audio(frames).Normalize(0)
+			if err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			for i, got := range normalized.Samples {
+				if got > math.MaxInt16 || got < math.MinInt16 {
+					t.Fatalf("%d bit, %d channels: sample %d is %d, outside 16 bits", bitDepth, channels, i, got)
+				}
+				var want float64
+				for c := 0; c < channels; c++ {
+					want += sine.value(c, i, bitDepth)
+				}
+				want *= math.Pow(2, float64(16-bitDepth)) / float64(channels)
+				// One output LSB, or one input LSB for audio coarser than 16 bits
+				tolerance := math.Max(1, math.Pow(2, float64(16-bitDepth)))
+				if math.Abs(float64(got)-want) > tolerance {
+					t.Fatalf("%d bit, %d channels, frame %d: %d, want %.2f ±%.0f", bitDepth, channels, i, got, want, tolerance)
+				}
+			}
+		}
+	}
+}
+
+func TestNormalizeKeepsDuration(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+
+	for _, bitDepth := range testBitDepths {
+		for _, channels := range testChannelCount {
+			for _, sampleRate := range []int{8000, 16000, 22050, 44100, 48000} {
+				sine := randomSine(rng, bitDepth, channels, false)
+				sine.sampleRate = sampleRate
+				audio := sine.audio(sampleRate / 2)
+
+				for _, targetRate := range []int{0, DefaultRecognitionSampleRate} {
+					normalized, err := audio.Normalize(targetRate)
+					if err != nil {
+						t.Fatalf("Normalize: %v", err)
+					}
+					if normalized.Channels != 1 || normalized.BitDepth != 16 {
+						t.Fatalf("got %d channels at %d bits, want 16-bit mono", normalized.Channels, normalized.BitDepth)
+					}
+
+					// Within one output sample of the input's duration
+					slack := time.Second / time.Duration(normalized.SampleRate)
+					if diff := normalized.Duration() - audio.Duration(); diff > slack || diff < -slack {
+						t.Errorf("%d bit, %d channels, %d Hz to %d Hz: duration %v, want %v",
+							bitDepth, channels, sampleRate, normalized.SampleRate, normalized.Duration(), audio.Duration())
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// AudioStreamWriter joins separately synthesized clips into one playable
+// stream. MP3 frames concatenate as they are. WAV clips share a single
+// header whose sizes are set to the maximum, the convention for streamed
+// WAV of unknown length, and only their samples follow.
+type AudioStreamWriter struct {
+	w       io.Writer
+	wav     bool
+	started bool
+}
+
+func NewAudioStreamWriter(w io.Writer, contentType string) *AudioStreamWriter {
+	return &AudioStreamWriter{
+		w:   w,
+		wav: contentType == "audio/wav" || contentType == "audio/x-wav",
+	}
+}
+
+func (a *AudioStreamWriter) WriteClip(clip []byte) error {
+	if !a.wav {
+		_, err := a.w.Write(clip)
+		return err
+	}
+
+	header, samples, err := splitWAV(clip)
+	if err != nil {
+		return err
+	}
+
+	if !a.started {
+		streamHeader := append([]byte(nil), header...)
+		binary.LittleEndian.PutUint32(streamHeader[4:8], 0xFFFFFFFF)
+		binary.LittleEndian.PutUint32(streamHeader[len(streamHeader)-4:], 0xFFFFFFFF)
+		if _, err := a.w.Write(streamHeader); err != nil {
+			return err
+		}
+		a.started = true
+	}
+
+	_, err = a.w.Write(samples)
+	return err
+}
+
+// splitWAV returns everything up to and including the data chunk header,
+// and the samples of the data chunk
+func splitWAV(clip []byte) ([]byte, []byte, error) {
+	if len(clip) < 12 || string(clip[0:4]) != "RIFF" || string(clip[8:12]) != "WAVE" {
+		return nil, nil, fmt.Errorf("invalid WAV clip")
+	}
+
+	for offset := 12; offset+8 <= len(clip); {
+		chunkID := string(clip[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(clip[offset+4 : offset+8]))
+		if chunkID == "data" {
+			end := offset + 8 + chunkSize
+			if end > len(clip) || end < offset {
+				end = len(clip)
+			}
+			return clip[:offset+8], clip[offset+8 : end], nil
+		}
+		// Chunks are padded to an even size
+		offset += 8 + chunkSize + chunkSize%2
+	}
+
+	return nil, nil, fmt.Errorf("WAV clip has no data chunk")
+}
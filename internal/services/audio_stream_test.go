@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// withListChunk inserts an odd-sized LIST chunk, padded to an even size,
+// between the fmt and data chunks of a WAV clip
+func withListChunk(clip []byte) []byte {
+	list := []byte("LIST\x03\x00\x00\x00abc\x00")
+	return concatBytes(clip[:36], list, clip[36:])
+}
+
+func TestSplitWAV(t *testing.T) {
+	samples := []byte{1, 2, 3, 4, 5, 6}
+	plain := EncodeWAV(samples, 16000, 1)
+
+	// A streamed clip whose data size runs past its end
+	streamed := append([]byte(nil), plain...)
+	binary.LittleEndian.PutUint32(streamed[40:44], 0xFFFFFFFF)
+
+	tests := []struct {
+		name       string
+		clip       []byte
+		headerSize int
+		wantErr    bool
+	}{
+		{name: "plain", clip: plain, headerSize: 44},
+		{name: "extra chunk", clip: withListChunk(plain), headerSize: 56},
+		{name: "unknown data size", clip: streamed, headerSize: 44},
+		{name: "not RIFF", clip: []byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00"), wantErr: true},
+		{name: "no data chunk", clip: plain[:36], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, got, err := splitWAV(tt.clip)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("splitWAV accepted the clip")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitWAV: %v", err)
+			}
+			if len(header) != tt.headerSize || string(header[len(header)-8:len(header)-4]) != "data" {
+				t.Errorf("header is %d bytes ending %q, want %d ending in the data chunk header", len(header), header[len(header)-8:], tt.headerSize)
+			}
+			if !bytes.Equal(got, samples) {
+				t.Errorf("samples = %v, want %v", got, samples)
+			}
+		})
+	}
+}
+
+func TestAudioStreamWriterJoinsWAV(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewAudioStreamWriter(&out, "audio/wav")
+
+	for _, clip := range [][]byte{
+		EncodeWAV([]byte{1, 2}, 16000, 1),
+		withListChunk(EncodeWAV([]byte{3, 4, 5, 6}, 16000, 1)),
+		EncodeWAV([]byte{7, 8}, 16000, 1),
+	} {
+		if err := writer.WriteClip(clip); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stream := out.Bytes()
+	header, samples, err := splitWAV(stream)
+	if err != nil {
+		t.Fatalf("joined stream is not WAV: %v", err)
+	}
+	if len(header) != 44 || !bytes.Equal(header[12:36], EncodeWAV(nil, 16000, 1)[12:36]) {
+		t.Errorf("stream header %v does not carry the clips' format", header)
+	}
+	// Sizes are unknown while streaming
+	if size := binary.LittleEndian.Uint32(stream[4:8]); size != 0xFFFFFFFF {
+		t.Errorf("RIFF size = %#x, want the streaming maximum", size)
+	}
+	if size := binary.LittleEndian.Uint32(stream[40:44]); size != 0xFFFFFFFF {
+		t.Errorf("data size = %#x, want the streaming maximum", size)
+	}
+	if want := []byte{1, 2, 3, 4, 5, 6, 7, 8}; !bytes.Equal(samples, want) {
+		t.Errorf("samples = %v, want %v", samples, want)
+	}
+
+	if err := writer.WriteClip([]byte("not audio")); err == nil {
+		t.Error("WriteClip accepted a clip that is not WAV")
+	}
+}
+
+func TestAudioStreamWriterPassesMP3Through(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewAudioStreamWriter(&out, "audio/mpeg")
+	for _, clip := range [][]byte{{0xFF, 0xFB, 1}, {0xFF, 0xFB, 2}} {
+		if err := writer.WriteClip(clip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []byte{0xFF, 0xFB, 1, 0xFF, 0xFB, 2}; !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("stream = %v, want the clips as they are", out.Bytes())
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang-gin-boilerplate/internal/interfaces"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// conversationMemoryPrefix marks the rolling summary system message that
+// follows the main system prompt
+const conversationMemoryPrefix = "Summary of the earlier conversation:\n"
+
+// CompactionConfig controls summarization based context compaction
+type CompactionConfig struct {
+	// TriggerRatio of MaxTokens above which compaction runs
+	TriggerRatio float64
+	// TargetRatio of MaxTokens the verbatim recent turns are cut down to
+	TargetRatio float64
+	// KeepRecentMessages are never summarized, however long they are
+	KeepRecentMessages int
+}
+
+func DefaultCompactionConfig() CompactionConfig {
+	return CompactionConfig{
+		TriggerRatio:       0.8,
+		TargetRatio:        0.5,
+		KeepRecentMessages: 4,
+	}
+}
+
+// Compact folds the oldest turns into the conversation memory once the
+// context grows past the trigger threshold. The summarizer runs without
+// holding the lock. If it fails the context falls back to plain trimming
+// and the error is returned for logging.
+func (cc *ConversationContext) Compact(ctx context.Context, summarizer interfaces.Summarizer, config CompactionConfig) error {
+	cc.mu.RLock()
+	if cc.calculateTotalTokens() <= int(float64(cc.MaxTokens)*config.TriggerRatio) {
+		cc.mu.RUnlock()
+		return nil
+	}
+
+	pinned := cc.pinnedCountUnlocked()
+	start := cc.keepRecentUnlocked(pinned, int(float64(cc.MaxTokens)*config.TargetRatio), config.KeepRecentMessages)
+	evicted := copyMessages(cc.Messages[pinned:start])
+	previousSummary := cc.memoryUnlocked()
+	cc.mu.RUnlock()
+
+	if len(evicted) == 0 {
+		// Only recent turns left, which are never summarized
+		cc.TrimContext()
+		return nil
+	}
+
+	summary, err := summarizer.Summarize(ctx, previousSummary, evicted)
+	if err == nil && strings.TrimSpace(summary) == "" {
+		err = fmt.Errorf("summarizer returned an empty summary")
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if err != nil {
+		cc.trimContextUnlocked()
+		return fmt.Errorf("context compaction failed: %v", err)
+	}
+
+	// Only replace the evicted turns if nobody rewrote them meanwhile
+	pinned = cc.pinnedCountUnlocked()
+	if !sameMessages(cc.Messages, pinned, evicted) {
+		cc.trimContextUnlocked()
+		return nil
+	}
+
+	compacted := make([]openai.ChatCompletionMessage, 0, len(cc.Messages)-len(evicted)+2)
+	compacted = append(compacted, cc.Messages[0], openai.ChatCompletionMessage{
+		Role:    string(MessageTypeSystem),
+		Content: conversationMemoryPrefix + strings.TrimSpace(summary),
+	})
+	compacted = append(compacted, cc.Messages[pinned+len(evicted):]...)
+	cc.Messages = compacted
+
+	// The summary itself may still leave us over the hard limit
+	cc.trimContextUnlocked()
+
+	return nil
+}
+
+// Memory returns the rolling summary of evicted turns, if any
+func (cc *ConversationContext) Memory() string {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.memoryUnlocked()
+}
+
+func (cc *ConversationContext) memoryUnlocked() string {
+	if cc.pinnedCountUnlocked() < 2 {
+		return ""
+	}
+	return strings.TrimPrefix(cc.Messages[1].Content, conversationMemoryPrefix)
+}
+
+// pinnedCountUnlocked returns how many leading messages trimming must keep:
+// the system prompt and, when present, the conversation memory
+func (cc *ConversationContext) pinnedCountUnlocked() int {
+	if len(cc.Messages) == 0 {
+		return 0
+	}
+	if len(cc.Messages) > 1 &&
+		cc.Messages[1].Role == string(MessageTypeSystem) &&
+		strings.HasPrefix(cc.Messages[1].Content, conversationMemoryPrefix) {
+		return 2
+	}
+	return 1
+}
+
+func sameMessages(messages []openai.ChatCompletionMessage, offset int, expected []openai.ChatCompletionMessage) bool {
+	if len(messages)-offset < len(expected) {
+		return false
+	}
+	for i, message := range expected {
+		current := messages[offset+i]
+		if current.Role != message.Role || current.Name != message.Name || current.Content != message.Content {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+type recordingSummarizer struct {
+	summarized []openai.ChatCompletionMessage
+	err        error
+}
+
+func (s *recordingSummarizer) Summarize(_ context.Context, _ string, messages []openai.ChatCompletionMessage) (string, error) {
+	s.summarized = append(s.summarized, messages...)
+	if s.err != nil {
+		return "", s.err
+	}
+	return "The user asked about the weather.", nil
+}
+
+// compactionTestContext returns a context holding a few short turns that stay
+// under the compaction trigger
+func compactionTestContext(t *testing.T) *ConversationContext {
+	t.Helper()
+	cc := NewConversationContext(openai.GPT3Dot5Turbo)
+	cc.AddMessage(MessageTypeUser, "What is the weather like in Paris?")
+	cc.AddMessage(MessageTypeAssistant, "It is sunny in Paris today.")
+	cc.MaxTokens = cc.CalculateTotalTokens() + 40
+	return cc
+}
+
+func TestCompactSummarizesTurnThatJumpsPastLimit(t *testing.T) {
+	cc := compactionTestContext(t)
+	summarizer := &recordingSummarizer{}
+	config := CompactionConfig{TriggerRatio: 0.8, TargetRatio: 0.5, KeepRecentMessages: 1}
+
+	long := strings.Repeat("Tell me everything about the history of Paris. ", 10)
+	cc.AppendUserInput(TextInput(long))
+	if cc.CalculateTotalTokens() <= cc.MaxTokens {
+		t.Fatalf("test input does not exceed MaxTokens %d", cc.MaxTokens)
+	}
+
+	if err := cc.Compact(context.Background(), summarizer, config); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	if len(summarizer.summarized) != 2 ||
+		summarizer.summarized[0].Content != "What is the weather like in Paris?" ||
+		summarizer.summarized[1].Content != "It is sunny in Paris today." {
+		t.Fatalf("summarized %+v, want both earlier turns", summarizer.summarized)
+	}
+	if got := cc.Memory(); got != "The user asked about the weather." {
+		t.Errorf("Memory() = %q", got)
+	}
+}
+
+func TestAddUserInputDropsTurnsCompactCannotSee(t *testing.T) {
+	// This is the behaviour the controller avoids by appending first: once
+	// AddUserInput has trimmed, the earlier turns are gone before Compact runs
+	cc := compactionTestContext(t)
+	summarizer := &recordingSummarizer{}
+
+	cc.AddUserInput(TextInput(strings.Repeat("Tell me everything about the history of Paris. ", 10)))
+	if err := cc.Compact(context.Background(), summarizer, DefaultCompactionConfig()); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	for _, message := range summarizer.summarized {
+		if strings.Contains(message.Content, "weather") {
+			t.Fatalf("trimmed turn reached the summarizer: %+v", message)
+		}
+	}
+}
+
+func TestCompactFallsBackToTrimming(t *testing.T) {
+	cc := compactionTestContext(t)
+	summarizer := &recordingSummarizer{err: errors.New("unavailable")}
+	config := CompactionConfig{TriggerRatio: 0.8, TargetRatio: 0.5, KeepRecentMessages: 1}
+
+	cc.AppendUserInput(TextInput(strings.Repeat("Tell me everything about the history of Paris. ", 10)))
+	if err := cc.Compact(context.Background(), summarizer, config); err == nil {
+		t.Fatal("Compact succeeded with a failing summarizer")
+	}
+	// Trimming keeps the system prompt and the newest message, however long
+	if got := len(cc.Snapshot()); got != 2 {
+		t.Errorf("context has %d messages after fallback, want 2", got)
+	}
+	if cc.Memory() != "" {
+		t.Errorf("Memory() = %q after failed summary", cc.Memory())
+	}
+}
+
+func TestCompactTrimsWhenNothingCanBeSummarized(t *testing.T) {
+	cc := compactionTestContext(t)
+	cc.AppendUserInput(TextInput(strings.Repeat("A single very long question. ", 20)))
+
+	if err := cc.Compact(context.Background(), &recordingSummarizer{}, DefaultCompactionConfig()); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if got := len(cc.Snapshot()); got != 2 {
+		t.Errorf("context has %d messages, want the system prompt and the question", got)
+	}
+}
@@ -1,11 +1,13 @@
 package services
 
 import (
+	"strings"
 	"sync"
 
+	"golang-gin-boilerplate/internal/models"
+
 	"github.com/sashabaranov/go-openai"
 	"golang.org/x/text/language"
-	"golang.org/x/text/message"
 )
 
 // ConversationMessageType defines the type of message in the conversation
@@ -17,12 +19,19 @@ const (
 	MessageTypeAssistant ConversationMessageType = "assistant"
 )
 
+// languageInstructionPrefix starts the sentence added to the system prompt
+// once the conversation has a language. Stores keep only messages, so the
+// language is read back from it when a conversation is restored.
+const languageInstructionPrefix = "\n\nConversation language: "
+
 // ConversationContext manages the entire conversation state
 type ConversationContext struct {
-	mu                 sync.RWMutex
-	Messages           []openai.ChatCompletionMessage
-	MaxTokens          int
-	CurrentModel       string
+	mu           sync.RWMutex
+	Messages     []openai.ChatCompletionMessage
+	MaxTokens    int
+	CurrentModel string
+	// LanguagePreference is the language replies are written in, language.Und
+	// to follow the user
 	LanguagePreference language.Tag
 }
 
@@ -32,15 +41,25 @@ func NewConversationContext(model string) *ConversationContext {
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    "system",
-				Content: "You are a helpful AI assistant. Maintain context of our ongoing conversation.",
+				Content: Localize(language.Und, MsgSystemPrompt),
 			},
 		},
 		MaxTokens:          4096,
 		CurrentModel:       model,
-		LanguagePreference: language.English,
+		LanguagePreference: language.Und,
 	}
 }
 
+// NewConversationContextFromMessages restores a conversation from stored history
+func NewConversationContextFromMessages(model string, messages []openai.ChatCompletionMessage) *ConversationContext {
+	cc := NewConversationContext(model)
+	if len(messages) > 0 {
+		cc.Messages = messages
+		cc.restoreLanguage()
+	}
+	return cc
+}
+
 // AddMessage adds a new message to the conversation context
 func (cc *ConversationContext) AddMessage(
 	messageType ConversationMessageType,
@@ -49,14 +68,130 @@ func (cc *ConversationContext) AddMessage(
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
 
-	newMessage := openai.ChatCompletionMessage{
+	cc.appendMessageUnlocked(messageType, content)
+
+	// Automatically trim context if needed
+	cc.trimContextUnlocked() // Use an unlocked version
+}
+
+// AppendMessage adds a message without trimming, for callers that compact
+// the context next so old turns are summarized rather than dropped
+func (cc *ConversationContext) AppendMessage(messageType ConversationMessageType, content string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.appendMessageUnlocked(messageType, content)
+}
+
+func (cc *ConversationContext) appendMessageUnlocked(messageType ConversationMessageType, content string) {
+	cc.Messages = append(cc.Messages, openai.ChatCompletionMessage{
 		Role:    string(messageType),
 		Content: content,
+	})
+}
+
+// ConversationInput is what the user said in one turn. With Speakers set the
+// words are attributed to speakers and Text is only their concatenation.
+// Language, when known, becomes the language of the conversation.
+type ConversationInput struct {
+	Text     string
+	Speakers []SpeakerTurn
+	Language language.Tag
+}
+
+// SpeakerTurn is what one speaker said before someone else spoke
+type SpeakerTurn struct {
+	Speaker string `json:"speaker"`
+	Text    string `json:"text"`
+}
+
+// TextInput is input from a single user
+func TextInput(text string) ConversationInput {
+	return ConversationInput{Text: text}
+}
+
+// TranscriptInput keeps the speaker labels and detected language of a
+// transcription. Consecutive segments of one speaker make up one turn.
+func TranscriptInput(result models.VoiceToTextModel) ConversationInput {
+	input := ConversationInput{Text: result.Text}
+	if lang, err := language.Parse(result.Language); err == nil {
+		input.Language = lang
 	}
-	cc.Messages = append(cc.Messages, newMessage)
 
-	// Automatically trim context if needed
-	cc.trimContextUnlocked() // Use an unlocked version
+	for _, segment := range result.Segments {
+		if segment.Speaker == "" || segment.Text == "" {
+			continue
+		}
+		if n := len(input.Speakers); n > 0 && input.Speakers[n-1].Speaker == segment.Speaker {
+			input.Speakers[n-1].Text += " " + segment.Text
+			continue
+		}
+		input.Speakers = append(input.Speakers, SpeakerTurn{Speaker: segment.Speaker, Text: segment.Text})
+	}
+
+	return input
+}
+
+// AddUserInput adds the input as a user message, or as one user message per
+// speaker turn named after the speaker
+func (cc *ConversationContext) AddUserInput(input ConversationInput) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.appendUserInputUnlocked(input)
+	cc.trimContextUnlocked()
+}
+
+// AppendUserInput is AddUserInput without trimming, like AppendMessage
+func (cc *ConversationContext) AppendUserInput(input ConversationInput) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.appendUserInputUnlocked(input)
+}
+
+func (cc *ConversationContext) appendUserInputUnlocked(input ConversationInput) {
+	cc.Messages = append(cc.Messages, userInputMessages(input)...)
+}
+
+// RemoveUserInput takes back the most recent messages added for input, for
+// a turn the model never answered. Messages trimming already dropped stay
+// dropped.
+func (cc *ConversationContext) RemoveUserInput(input ConversationInput) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	messages := userInputMessages(input)
+	pinned := cc.pinnedCountUnlocked()
+	for skip := range messages {
+		expected := messages[skip:]
+		for start := len(cc.Messages) - len(expected); start >= pinned; start-- {
+			if sameMessages(cc.Messages, start, expected) {
+				remaining := make([]openai.ChatCompletionMessage, 0, len(cc.Messages)-len(expected))
+				remaining = append(remaining, cc.Messages[:start]...)
+				cc.Messages = append(remaining, cc.Messages[start+len(expected):]...)
+				return
+			}
+		}
+	}
+}
+
+// userInputMessages returns the user messages for input
+func userInputMessages(input ConversationInput) []openai.ChatCompletionMessage {
+	if len(input.Speakers) == 0 {
+		return []openai.ChatCompletionMessage{{
+			Role:    string(MessageTypeUser),
+			Content: input.Text,
+		}}
+	}
+
+	messages := make([]openai.ChatCompletionMessage, 0, len(input.Speakers))
+	for _, turn := range input.Speakers {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    string(MessageTypeUser),
+			Content: turn.Text,
+			Name:    turn.Speaker,
+		})
+	}
+	return messages
 }
 
 // trimContextUnlocked is an internal method that assumes the mutex is already held
@@ -66,28 +201,35 @@ func (cc *ConversationContext) trimContextUnlocked() {
 		return
 	}
 
-	// Always keep the system message
-	systemMessage := cc.Messages[0]
-
-	// Create a new slice to store trimmed messages
-	var trimmedMessages []openai.ChatCompletionMessage
-	trimmedMessages = append(trimmedMessages, systemMessage)
+	// Always keep the system message and the conversation memory
+	pinned := cc.pinnedCountUnlocked()
+	kept := cc.keepRecentUnlocked(pinned, cc.MaxTokens, 1)
 
-	// Work backwards through conversation history
-	for i := len(cc.Messages) - 1; i > 0; i-- {
-		currentMessage := cc.Messages[i]
-
-		// Add message to trimmed messages
-		trimmedMessages = append([]openai.ChatCompletionMessage{currentMessage}, trimmedMessages...)
+	// Update the messages
+	trimmedMessages := make([]openai.ChatCompletionMessage, 0, pinned+len(cc.Messages)-kept)
+	trimmedMessages = append(trimmedMessages, cc.Messages[:pinned]...)
+	trimmedMessages = append(trimmedMessages, cc.Messages[kept:]...)
+	cc.Messages = trimmedMessages
+}
 
-		// Recalculate total tokens after adding this message
-		if cc.calculateTokensForMessageList(trimmedMessages) <= cc.MaxTokens {
+// keepRecentUnlocked works backwards through the history after the pinned
+// messages and returns the index of the oldest message that still fits in
+// budget together with everything newer. At least minRecent messages are
+// kept even if they do not fit.
+func (cc *ConversationContext) keepRecentUnlocked(pinned, budget, minRecent int) int {
+	tokens := cc.calculateTokensForMessageList(cc.Messages[:pinned])
+
+	start := len(cc.Messages)
+	for start > pinned {
+		messageTokens := CountMessageTokens(cc.CurrentModel, cc.Messages[start-1])
+		if len(cc.Messages)-start >= minRecent && tokens+messageTokens > budget {
 			break
 		}
+		tokens += messageTokens
+		start--
 	}
 
-	// Update the messages
-	cc.Messages = trimmedMessages
+	return start
 }
 
 // TrimContext is a public method that can be called externally
@@ -97,60 +239,95 @@ func (cc *ConversationContext) TrimContext() {
 	cc.trimContextUnlocked()
 }
 
-// CalculateTokensForMessage estimates tokens for a single message
-func (cc *ConversationContext) calculateTokensForMessage(
-	message openai.ChatCompletionMessage,
-) int {
-	// Rough estimation: ~4 characters per token
-	baseTokens := len(message.Content) / 4
-
-	// Add extra tokens based on role
-	switch message.Role {
-	case "system":
-		baseTokens += 10 // More weight for system messages
-	case "user":
-		baseTokens += 5
-	case "assistant":
-		baseTokens += 5
-	}
-
-	return baseTokens
-}
-
-// CalculateTokensForMessageList calculates tokens for multiple messages
+// CalculateTokensForMessageList counts the prompt tokens for multiple messages
 func (cc *ConversationContext) calculateTokensForMessageList(
 	messages []openai.ChatCompletionMessage,
 ) int {
-	totalTokens := 0
-	for _, msg := range messages {
-		totalTokens += cc.calculateTokensForMessage(msg)
-	}
-	return totalTokens
+	return CountMessagesTokens(cc.CurrentModel, messages)
 }
 
 // CalculateTotalTokens calculates tokens in current context
 func (cc *ConversationContext) calculateTotalTokens() int {
-	return cc.calculateTokensForMessageList(cc.Messages)
+	return cc.calculateTokensForMessageList(cc.outgoingUnlocked())
 }
 
-// SetLanguagePreference allows setting conversation language
+// SetLanguagePreference allows setting conversation language. A default
+// system prompt is replaced by the one of that language.
 func (cc *ConversationContext) SetLanguagePreference(lang language.Tag) {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
 	cc.LanguagePreference = lang
+
+	if len(cc.Messages) > 0 && cc.Messages[0].Role == string(MessageTypeSystem) && isDefaultSystemPrompt(cc.Messages[0].Content) {
+		prompt := Localize(lang, MsgSystemPrompt)
+		if prompt != cc.Messages[0].Content {
+			cc.Messages = append([]openai.ChatCompletionMessage(nil), cc.Messages...)
+			cc.Messages[0].Content = prompt
+		}
+	}
 }
 
-// GetLocalizedMessage provides localized system messages
-func (cc *ConversationContext) GetLocalizedMessage(key string) string {
-	p := message.NewPrinter(cc.LanguagePreference)
+// isDefaultSystemPrompt tells whether content is the system prompt of any locale
+func isDefaultSystemPrompt(content string) bool {
+	for _, locale := range Locales() {
+		if content == Localize(locale, MsgSystemPrompt) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Example localization map (you'd expand this)
-	messages := map[string]string{
-		"welcome": p.Sprintf("Welcome! How can I assist you today?"),
-		"help":    p.Sprintf("I'm here to help. What do you need?"),
+// Language returns the language replies are written in, language.Und when
+// none was chosen
+func (cc *ConversationContext) Language() language.Tag {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.LanguagePreference
+}
+
+// languageInstruction tells the model which language to answer in, in that
+// language when it has a locale
+func languageInstruction(lang language.Tag) string {
+	return languageInstructionPrefix + lang.String() + ". " + Localize(lang, MsgLanguageInstruction, LanguageName(lang))
+}
+
+// restoreLanguage moves the language instruction of stored messages back
+// into LanguagePreference
+func (cc *ConversationContext) restoreLanguage() {
+	if cc.Messages[0].Role != string(MessageTypeSystem) {
+		return
+	}
+
+	content := cc.Messages[0].Content
+	index := strings.LastIndex(content, languageInstructionPrefix)
+	if index < 0 {
+		return
+	}
+
+	tag, _, _ := strings.Cut(content[index+len(languageInstructionPrefix):], ". ")
+	if lang, err := language.Parse(tag); err == nil {
+		cc.Messages = append([]openai.ChatCompletionMessage(nil), cc.Messages...)
+		cc.Messages[0].Content = content[:index]
+		cc.LanguagePreference = lang
+	}
+}
+
+// outgoingUnlocked returns a copy of the messages with the language
+// instruction added to the system prompt
+func (cc *ConversationContext) outgoingUnlocked() []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, len(cc.Messages))
+	copy(messages, cc.Messages)
+
+	if cc.LanguagePreference != language.Und && len(messages) > 0 && messages[0].Role == string(MessageTypeSystem) {
+		messages[0].Content += languageInstruction(cc.LanguagePreference)
 	}
+	return messages
+}
 
-	return messages[key]
+// GetLocalizedMessage formats the message key (one of the Msg constants) in
+// the conversation's language
+func (cc *ConversationContext) GetLocalizedMessage(key string, args ...interface{}) string {
+	return Localize(cc.Language(), key, args...)
 }
 
 // ResetContext completely resets the conversation
@@ -161,7 +338,7 @@ func (cc *ConversationContext) ResetContext() {
 	cc.Messages = []openai.ChatCompletionMessage{
 		{
 			Role:    "system",
-			Content: "You are a helpful AI assistant. Maintain context of our ongoing conversation.",
+			Content: Localize(cc.LanguagePreference, MsgSystemPrompt),
 		},
 	}
 }
@@ -174,13 +351,19 @@ func (cc *ConversationContext) CalculateTotalTokens() int {
 	cc.mu.RLock()
 	defer cc.mu.RUnlock()
 
-	if len(cc.Messages) == 0 {
-		return 0
-	}
+	return cc.calculateTotalTokens()
+}
 
-	totalTokens := 0
-	for _, msg := range cc.Messages {
-		totalTokens += cc.calculateTokensForMessage(msg)
-	}
-	return totalTokens
+// Snapshot returns a copy of the messages exactly as they will be sent to the model
+func (cc *ConversationContext) Snapshot() []openai.ChatCompletionMessage {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	return cc.outgoingUnlocked()
+}
+
+// CountTokens counts tokens for an arbitrary list of messages, such as one
+// returned by Snapshot
+func (cc *ConversationContext) CountTokens(messages []openai.ChatCompletionMessage) int {
+	return cc.calculateTokensForMessageList(messages)
 }
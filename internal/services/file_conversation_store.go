@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// FileConversationStore writes one JSON-lines file per session, one message
+// per line. Files are replaced atomically so a crash never leaves half a
+// conversation behind.
+type FileConversationStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewFileConversationStore(dir string) (*FileConversationStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create conversation directory: %v", err)
+	}
+
+	return &FileConversationStore{dir: dir}, nil
+}
+
+func (s *FileConversationStore) Load(ctx context.Context, sessionID string) ([]openai.ChatCompletionMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path(sessionID))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open conversation file: %v", err)
+	}
+	defer file.Close()
+
+	var messages []openai.ChatCompletionMessage
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var message openai.ChatCompletionMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, false, fmt.Errorf("failed to decode conversation file: %v", err)
+		}
+		messages = append(messages, message)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read conversation file: %v", err)
+	}
+
+	return messages, true, nil
+}
+
+func (s *FileConversationStore) Save(ctx context.Context, sessionID string, messages []openai.ChatCompletionMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmpFile, err := os.CreateTemp(s.dir, "conversation-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create conversation file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	writer := bufio.NewWriter(tmpFile)
+	encoder := json.NewEncoder(writer)
+	for _, message := range messages {
+		if err := encoder.Encode(message); err != nil {
+			tmpFile.Close()
+			return fmt.Errorf("failed to encode conversation: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write conversation file: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write conversation file: %v", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), s.path(sessionID)); err != nil {
+		return fmt.Errorf("failed to replace conversation file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *FileConversationStore) Delete(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(sessionID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete conversation file: %v", err)
+	}
+	return nil
+}
+
+func (s *FileConversationStore) Close() error {
+	return nil
+}
+
+// path names the file after a hash of the session ID, so client supplied IDs
+// of any length or content can never escape dir or exceed the name limit
+func (s *FileConversationStore) path(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".jsonl")
+}
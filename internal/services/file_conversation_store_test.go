@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang-gin-boilerplate/internal/interfaces"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+var storedMessages = []openai.ChatCompletionMessage{
+	{Role: "system", Content: "Be brief."},
+	{Role: "user", Name: "speaker_1", Content: "Hello\nthere"},
+	{Role: "assistant", Content: "Hi! ¿Qué tal?"},
+}
+
+// checkStoreRoundTrip saves a conversation through one store, reads it back
+// through a second one opened on the same data, as after a restart, and
+// deletes it
+func checkStoreRoundTrip(t *testing.T, open func() interfaces.ConversationStore) {
+	t.Helper()
+	ctx := context.Background()
+	store := open()
+
+	if _, ok, err := store.Load(ctx, "s1"); err != nil || ok {
+		t.Fatalf("Load of unknown session = %v, %v, want not found", ok, err)
+	}
+	if err := store.Save(ctx, "s1", storedMessages[:1]); err != nil {
+		t.Fatal(err)
+	}
+	// Saving again replaces the conversation
+	if err := store.Save(ctx, "s1", storedMessages); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, "s2", storedMessages[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := open()
+	loaded, ok, err := restarted.Load(ctx, "s1")
+	if err != nil || !ok {
+		t.Fatalf("Load after restart = %v, %v", ok, err)
+	}
+	if !reflect.DeepEqual(loaded, storedMessages) {
+		t.Errorf("loaded %+v, want %+v", loaded, storedMessages)
+	}
+
+	if err := restarted.Delete(ctx, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := restarted.Load(ctx, "s1"); ok {
+		t.Error("session still stored after Delete")
+	}
+	if err := restarted.Delete(ctx, "s1"); err != nil {
+		t.Errorf("Delete of a deleted session: %v", err)
+	}
+	if loaded, ok, _ := restarted.Load(ctx, "s2"); !ok || len(loaded) != 2 {
+		t.Errorf("other session = %d messages, %v after Delete", len(loaded), ok)
+	}
+}
+
+func TestFileConversationStore(t *testing.T) {
+	dir := t.TempDir()
+	checkStoreRoundTrip(t, func() interfaces.ConversationStore {
+		store, err := NewFileConversationStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
+
+func TestFileConversationStoreSessionIDs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileConversationStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sessionID := range []string{
+		strings.Repeat("x", 1000),
+		"../../etc/passwd",
+		"a/b\\c\x00d",
+	} {
+		if err := store.Save(ctx, sessionID, storedMessages); err != nil {
+			t.Fatalf("Save(%.20q): %v", sessionID, err)
+		}
+		if loaded, ok, err := store.Load(ctx, sessionID); err != nil || !ok || len(loaded) != len(storedMessages) {
+			t.Errorf("Load(%.20q) = %d messages, %v, %v", sessionID, len(loaded), ok, err)
+		}
+	}
+
+	// Every file stays directly inside dir, and no temporary file is left
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("%d files in the store, want 3", len(entries))
+	}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".jsonl" {
+			t.Errorf("unexpected file %s", entry.Name())
+		}
+	}
+}
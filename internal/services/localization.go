@@ -0,0 +1,227 @@
+package services
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// Keys of the localized messages. Every locale file must define all of them.
+const (
+	MsgSystemPrompt           = "system_prompt"
+	MsgLanguageInstruction    = "language_instruction"
+	MsgWelcome                = "welcome"
+	MsgHelp                   = "help"
+	MsgConversationReset      = "conversation_reset"
+	MsgNoSpeech               = "error.no_speech"
+	MsgTranscriptionFailed    = "error.transcription_failed"
+	MsgConversationFailed     = "error.conversation_failed"
+	MsgRespondFailed          = "error.respond_failed"
+	MsgLoadConversationFailed = "error.load_conversation_failed"
+	MsgResetFailed            = "error.reset_failed"
+	MsgUtteranceTooLong       = "error.utterance_too_long"
+	MsgFallbackNotHeard       = "fallback.not_heard"
+	MsgFallbackNoAnswer       = "fallback.no_answer"
+)
+
+var messageKeys = []string{
+	MsgSystemPrompt, MsgLanguageInstruction, MsgWelcome, MsgHelp,
+	MsgConversationReset, MsgNoSpeech, MsgTranscriptionFailed,
+	MsgConversationFailed, MsgRespondFailed, MsgLoadConversationFailed,
+	MsgResetFailed, MsgUtteranceTooLong, MsgFallbackNotHeard,
+	MsgFallbackNoAnswer,
+}
+
+// fallbackLocale is used for languages without a locale file
+var fallbackLocale = language.English
+
+// pluralCases are the CLDR plural categories, in the order they are tried
+var pluralCases = []string{"zero", "one", "two", "few", "many", "other"}
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// messageCatalog holds every shipped locale. A locale missing a key, or
+// translating one with different arguments, stops the process at startup.
+var messageCatalog = mustLoadMessageCatalog()
+
+// Localize formats the message key in the locale closest to lang
+func Localize(lang language.Tag, key string, args ...interface{}) string {
+	return message.NewPrinter(matchLocale(lang), message.Catalog(messageCatalog)).Sprintf(key, args...)
+}
+
+// Locales lists the shipped languages, the fallback first
+func Locales() []language.Tag {
+	return messageCatalog.Languages()
+}
+
+// matchLocale picks the shipped locale for lang, the fallback when none is close
+func matchLocale(lang language.Tag) language.Tag {
+	_, index, confidence := messageCatalog.Matcher().Match(lang)
+	if confidence == language.No {
+		return fallbackLocale
+	}
+	return messageCatalog.Languages()[index]
+}
+
+// LanguageName names lang in the locale closest to it, "español de España"
+// for es-ES, so it can be used in sentences of that locale
+func LanguageName(lang language.Tag) string {
+	if name := display.Tags(matchLocale(lang)).Name(lang); name != "" {
+		return name
+	}
+	return lang.String()
+}
+
+func mustLoadMessageCatalog() *catalog.Builder {
+	builder, err := loadMessageCatalog()
+	if err != nil {
+		panic(err.Error())
+	}
+	return builder
+}
+
+// loadMessageCatalog reads locales/<language>.json. A locale file maps keys
+// to messages. A message is a string, or for plurals an object with the
+// index of the counted argument (starting at 1) and one format per plural
+// category, e.g.
+//
+//	{"arg": 1, "one": "%d second", "other": "%d seconds"}
+func loadMessageCatalog() (*catalog.Builder, error) {
+	builder := catalog.NewBuilder(catalog.Fallback(fallbackLocale))
+
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	locales := make(map[language.Tag]map[string]json.RawMessage)
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".json")
+		tag, err := language.Parse(name)
+		if err != nil {
+			return nil, fmt.Errorf("locale file %s: invalid language: %v", file.Name(), err)
+		}
+
+		data, err := localeFiles.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("locale file %s: %v", file.Name(), err)
+		}
+		locales[tag] = entries
+	}
+
+	reference, ok := locales[fallbackLocale]
+	if !ok {
+		return nil, fmt.Errorf("no locale file for the fallback language %s", fallbackLocale)
+	}
+
+	for tag, entries := range locales {
+		if err := checkLocaleKeys(entries); err != nil {
+			return nil, fmt.Errorf("locale %s: %v", tag, err)
+		}
+		for _, key := range messageKeys {
+			msg, verbs, err := parseLocaleMessage(entries[key])
+			if err != nil {
+				return nil, fmt.Errorf("locale %s, message %s: %v", tag, key, err)
+			}
+			_, want, _ := parseLocaleMessage(reference[key])
+			if verbs != want {
+				return nil, fmt.Errorf("locale %s, message %s: has %d arguments, %s has %d", tag, key, verbs, fallbackLocale, want)
+			}
+			if err := builder.Set(tag, key, msg); err != nil {
+				return nil, fmt.Errorf("locale %s, message %s: %v", tag, key, err)
+			}
+		}
+	}
+
+	return builder, nil
+}
+
+// checkLocaleKeys requires exactly the known keys
+func checkLocaleKeys(entries map[string]json.RawMessage) error {
+	var missing, unknown []string
+	known := make(map[string]bool, len(messageKeys))
+	for _, key := range messageKeys {
+		known[key] = true
+		if _, ok := entries[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	for key := range entries {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// parseLocaleMessage builds the catalog message and counts the arguments it
+// formats
+func parseLocaleMessage(raw json.RawMessage) (catalog.Message, int, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return catalog.String(text), formatVerbs(text), nil
+	}
+
+	var entry map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, 0, fmt.Errorf("want a string or a plural object")
+	}
+
+	var arg int
+	if err := json.Unmarshal(entry["arg"], &arg); err != nil || arg < 1 {
+		return nil, 0, fmt.Errorf("plural needs the index of its counted argument in arg")
+	}
+	if _, ok := entry["other"]; !ok {
+		return nil, 0, fmt.Errorf("plural needs an other case")
+	}
+
+	var cases []interface{}
+	verbs := -1
+	for _, category := range pluralCases {
+		value, ok := entry[category]
+		if !ok {
+			continue
+		}
+		var format string
+		if err := json.Unmarshal(value, &format); err != nil {
+			return nil, 0, fmt.Errorf("plural case %s is not a string", category)
+		}
+		if verbs >= 0 && formatVerbs(format) != verbs {
+			return nil, 0, fmt.Errorf("plural case %s formats a different number of arguments", category)
+		}
+		verbs = formatVerbs(format)
+		cases = append(cases, category, format)
+	}
+	if len(cases) != 2*(len(entry)-1) {
+		return nil, 0, fmt.Errorf("plural cases must be CLDR categories: %s", strings.Join(pluralCases, ", "))
+	}
+
+	return plural.Selectf(arg, "", cases...), verbs, nil
+}
+
+// formatVerbs counts the formatting verbs in format
+func formatVerbs(format string) int {
+	return strings.Count(format, "%") - 2*strings.Count(format, "%%")
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// localeFile reads a locale file from disk, as a translator would edit it
+func localeFile(t *testing.T, name string) map[string]json.RawMessage {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("locales", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return entries
+}
+
+// sampleArgs returns n arguments that suit %s and %v
+func sampleArgs(n int) []interface{} {
+	args := make([]interface{}, n)
+	for i := range args {
+		args[i] = fmt.Sprintf("arg%d", i+1)
+	}
+	return args
+}
+
+func TestEveryLocaleFormatsEveryMessage(t *testing.T) {
+	builder, err := loadMessageCatalog()
+	if err != nil {
+		t.Fatalf("loadMessageCatalog: %v", err)
+	}
+
+	files, err := os.ReadDir("locales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(builder.Languages()) {
+		t.Fatalf("%d locale files, catalog has %v", len(files), builder.Languages())
+	}
+
+	for _, file := range files {
+		tag := language.MustParse(strings.TrimSuffix(file.Name(), ".json"))
+		entries := localeFile(t, file.Name())
+		printer := message.NewPrinter(tag, message.Catalog(builder))
+
+		t.Run(tag.String(), func(t *testing.T) {
+			for _, key := range messageKeys {
+				var text string
+				if err := json.Unmarshal(entries[key], &text); err == nil {
+					args := sampleArgs(formatVerbs(text))
+					assertLocalized(t, key, printer.Sprintf(key, args...), fmt.Sprintf(text, args...))
+					continue
+				}
+
+				// A plural: the one case for a count of one, if the locale
+				// has it, and the other case for several
+				var plural map[string]interface{}
+				if err := json.Unmarshal(entries[key], &plural); err != nil {
+					t.Fatalf("%s: %v", key, err)
+				}
+				other, _ := plural["other"].(string)
+				one, ok := plural["one"].(string)
+				if !ok {
+					one = other
+				}
+				assertLocalized(t, key, printer.Sprintf(key, 1), fmt.Sprintf(one, 1))
+				assertLocalized(t, key, printer.Sprintf(key, 5), fmt.Sprintf(other, 5))
+			}
+		})
+	}
+}
+
+func assertLocalized(t *testing.T, key, got, want string) {
+	t.Helper()
+	if strings.Contains(got, "%!") {
+		t.Errorf("%s: bad formatting in %q", key, got)
+	}
+	if got != want {
+		t.Errorf("%s = %q, want %q", key, got, want)
+	}
+}
+
+func TestLocalizeFallsBackToEnglish(t *testing.T) {
+	want := Localize(language.English, MsgWelcome)
+	if got := Localize(language.Japanese, MsgWelcome); got != want {
+		t.Errorf("Localize(ja) = %q, want the English %q", got, want)
+	}
+	if got := Localize(language.MustParse("es-MX"), MsgUtteranceTooLong, 2); !strings.Contains(got, "2") || got == Localize(language.English, MsgUtteranceTooLong, 2) {
+		t.Errorf("Localize(es-MX) = %q, want the Spanish plural", got)
+	}
+}
+
+func TestParseLocaleMessageRejectsBadPlurals(t *testing.T) {
+	tests := []string{
+		`{"one": "%d second", "other": "%d seconds"}`,
+		`{"arg": 1, "one": "%d second"}`,
+		`{"arg": 1, "single": "%d second", "other": "%d seconds"}`,
+		`{"arg": 1, "one": "a second", "other": "%d seconds"}`,
+		`42`,
+	}
+	for _, raw := range tests {
+		if _, _, err := parseLocaleMessage(json.RawMessage(raw)); err == nil {
+			t.Errorf("parseLocaleMessage(%s) accepted", raw)
+		}
+	}
+}
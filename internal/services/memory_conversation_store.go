@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// MemoryConversationStore keeps history in process memory, selected with
+// CONVERSATION_STORE=memory. Like having no store, history is lost on
+// restart and not shared between instances.
+type MemoryConversationStore struct {
+	mu       sync.RWMutex
+	sessions map[string][]openai.ChatCompletionMessage
+}
+
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		sessions: make(map[string][]openai.ChatCompletionMessage),
+	}
+}
+
+func (s *MemoryConversationStore) Load(ctx context.Context, sessionID string) ([]openai.ChatCompletionMessage, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, false, nil
+	}
+
+	return copyMessages(messages), true, nil
+}
+
+func (s *MemoryConversationStore) Save(ctx context.Context, sessionID string, messages []openai.ChatCompletionMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[sessionID] = copyMessages(messages)
+	return nil
+}
+
+func (s *MemoryConversationStore) Delete(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func (s *MemoryConversationStore) Close() error {
+	return nil
+}
+
+func copyMessages(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	copied := make([]openai.ChatCompletionMessage, len(messages))
+	copy(copied, messages)
+	return copied
+}
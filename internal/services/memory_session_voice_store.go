@@ -0,0 +1,114 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// MemorySessionVoiceStore keeps session voices in process memory, so they
+// are lost on restart. Like SessionManager's sessions, voices expire after
+// idleTimeout without use and once maxSessions is reached the least
+// recently used voice is dropped to make room.
+type MemorySessionVoiceStore struct {
+	mu          sync.Mutex
+	voices      map[string]*list.Element
+	lru         *list.List // front is the most recently used voice
+	idleTimeout time.Duration
+	maxSessions int
+	now         func() time.Time
+}
+
+type voiceEntry struct {
+	voice    models.SessionVoiceModel
+	lastUsed time.Time
+}
+
+// NewMemorySessionVoiceStore creates the store. A zero idleTimeout disables
+// expiry and a zero maxSessions disables the cap.
+func NewMemorySessionVoiceStore(idleTimeout time.Duration, maxSessions int) *MemorySessionVoiceStore {
+	return &MemorySessionVoiceStore{
+		voices:      make(map[string]*list.Element),
+		lru:         list.New(),
+		idleTimeout: idleTimeout,
+		maxSessions: maxSessions,
+		now:         time.Now,
+	}
+}
+
+func (s *MemorySessionVoiceStore) Load(ctx context.Context, sessionID string) (models.SessionVoiceModel, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpiredUnlocked()
+	element, ok := s.voices[sessionID]
+	if !ok {
+		return models.SessionVoiceModel{}, false, nil
+	}
+	entry := element.Value.(*voiceEntry)
+	entry.lastUsed = s.now()
+	s.lru.MoveToFront(element)
+	return entry.voice, true, nil
+}
+
+func (s *MemorySessionVoiceStore) Save(ctx context.Context, voice models.SessionVoiceModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpiredUnlocked()
+	if element, ok := s.voices[voice.SessionID]; ok {
+		s.removeElementUnlocked(element)
+	}
+	// Make room for the new voice
+	for s.maxSessions > 0 && s.lru.Len() >= s.maxSessions {
+		s.removeElementUnlocked(s.lru.Back())
+	}
+	s.voices[voice.SessionID] = s.lru.PushFront(&voiceEntry{voice: voice, lastUsed: s.now()})
+	return nil
+}
+
+func (s *MemorySessionVoiceStore) Delete(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.voices[sessionID]; ok {
+		s.removeElementUnlocked(element)
+	}
+	return nil
+}
+
+// Len returns the number of live voices
+func (s *MemorySessionVoiceStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpiredUnlocked()
+	return s.lru.Len()
+}
+
+func (s *MemorySessionVoiceStore) Close() error {
+	return nil
+}
+
+// pruneExpiredUnlocked drops idle voices, oldest first. Assumes the mutex is held.
+func (s *MemorySessionVoiceStore) pruneExpiredUnlocked() {
+	if s.idleTimeout <= 0 {
+		return
+	}
+
+	cutoff := s.now().Add(-s.idleTimeout)
+	for element := s.lru.Back(); element != nil; element = s.lru.Back() {
+		if element.Value.(*voiceEntry).lastUsed.After(cutoff) {
+			return
+		}
+		s.removeElementUnlocked(element)
+	}
+}
+
+func (s *MemorySessionVoiceStore) removeElementUnlocked(element *list.Element) {
+	entry := s.lru.Remove(element).(*voiceEntry)
+	delete(s.voices, entry.voice.SessionID)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+func TestMemorySessionVoiceStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySessionVoiceStore(0, 2)
+
+	for _, id := range []string{"a", "b"} {
+		if err := store.Save(ctx, models.SessionVoiceModel{SessionID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Using a makes b the one to go
+	if _, ok, _ := store.Load(ctx, "a"); !ok {
+		t.Fatal("a missing")
+	}
+	if err := store.Save(ctx, models.SessionVoiceModel{SessionID: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := store.Load(ctx, id); ok != want {
+			t.Errorf("%s kept = %v, want %v", id, ok, want)
+		}
+	}
+	if n := store.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+
+	// Saving a voice again replaces it rather than taking another place
+	if err := store.Save(ctx, models.SessionVoiceModel{SessionID: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := store.Len(); n != 2 {
+		t.Errorf("Len after saving again = %d, want 2", n)
+	}
+}
+
+func TestMemorySessionVoiceStoreExpiresIdleVoices(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemorySessionVoiceStore(time.Minute, 0)
+	store.now = func() time.Time { return now }
+
+	store.Save(ctx, models.SessionVoiceModel{SessionID: "idle"})
+	store.Save(ctx, models.SessionVoiceModel{SessionID: "busy"})
+
+	now = now.Add(40 * time.Second)
+	store.Load(ctx, "busy")
+	now = now.Add(40 * time.Second)
+
+	if _, ok, _ := store.Load(ctx, "idle"); ok {
+		t.Error("idle voice kept past the timeout")
+	}
+	if _, ok, _ := store.Load(ctx, "busy"); !ok {
+		t.Error("voice in use expired")
+	}
+	if n := store.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// MemoryTranscriptionJobStore keeps jobs in process memory, so they are lost
+// on restart
+type MemoryTranscriptionJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]models.TranscriptionJobModel
+}
+
+func NewMemoryTranscriptionJobStore() *MemoryTranscriptionJobStore {
+	return &MemoryTranscriptionJobStore{
+		jobs: make(map[string]models.TranscriptionJobModel),
+	}
+}
+
+func (s *MemoryTranscriptionJobStore) Load(ctx context.Context, id string) (models.TranscriptionJobModel, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	return job, ok, nil
+}
+
+func (s *MemoryTranscriptionJobStore) Save(ctx context.Context, job models.TranscriptionJobModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemoryTranscriptionJobStore) ListUnfinished(ctx context.Context) ([]models.TranscriptionJobModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var jobs []models.TranscriptionJobModel
+	for _, job := range s.jobs {
+		if !job.Finished() {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+func (s *MemoryTranscriptionJobStore) DeleteFinishedBefore(ctx context.Context, cutoff time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, job := range s.jobs {
+		if job.Finished() && job.CompletedAt != nil && job.CompletedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryTranscriptionJobStore) Close() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// MemoryVocabularyStore keeps vocabularies in process memory, so they are
+// lost on restart
+type MemoryVocabularyStore struct {
+	mu           sync.RWMutex
+	vocabularies map[string]models.VocabularyModel
+}
+
+func NewMemoryVocabularyStore() *MemoryVocabularyStore {
+	return &MemoryVocabularyStore{
+		vocabularies: make(map[string]models.VocabularyModel),
+	}
+}
+
+func (s *MemoryVocabularyStore) Load(ctx context.Context, tenantID string) (models.VocabularyModel, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vocabulary, ok := s.vocabularies[tenantID]
+	return vocabulary, ok, nil
+}
+
+func (s *MemoryVocabularyStore) Save(ctx context.Context, vocabulary models.VocabularyModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.vocabularies[vocabulary.TenantID] = vocabulary
+	return nil
+}
+
+func (s *MemoryVocabularyStore) Delete(ctx context.Context, tenantID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.vocabularies, tenantID)
+	return nil
+}
+
+func (s *MemoryVocabularyStore) Close() error {
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sashabaranov/go-openai"
+)
+
+// RedisConversationStore shares sessions between instances. Keys expire
+// after ttl so abandoned conversations clean themselves up; a zero ttl keeps
+// them forever.
+type RedisConversationStore struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisConversationStore connects using a redis:// URL, which also works
+// against a miniredis instance in tests
+func NewRedisConversationStore(url string, ttl time.Duration) (*RedisConversationStore, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %v", err)
+	}
+
+	return &RedisConversationStore{
+		client: redis.NewClient(options),
+		prefix: "conversation:",
+		ttl:    ttl,
+	}, nil
+}
+
+func (s *RedisConversationStore) Load(ctx context.Context, sessionID string) ([]openai.ChatCompletionMessage, bool, error) {
+	data, err := s.client.Get(ctx, s.prefix+sessionID).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load conversation: %v", err)
+	}
+
+	var messages []openai.ChatCompletionMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, false, fmt.Errorf("failed to decode conversation: %v", err)
+	}
+
+	return messages, true, nil
+}
+
+func (s *RedisConversationStore) Save(ctx context.Context, sessionID string, messages []openai.ChatCompletionMessage) error {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %v", err)
+	}
+
+	if err := s.client.Set(ctx, s.prefix+sessionID, data, s.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save conversation: %v", err)
+	}
+
+	return nil
+}
+
+func (s *RedisConversationStore) Delete(ctx context.Context, sessionID string) error {
+	if err := s.client.Del(ctx, s.prefix+sessionID).Err(); err != nil {
+		return fmt.Errorf("failed to delete conversation: %v", err)
+	}
+	return nil
+}
+
+func (s *RedisConversationStore) Close() error {
+	return s.client.Close()
+}
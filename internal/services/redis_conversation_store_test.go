@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/sashabaranov/go-openai"
+)
+
+func newTestRedisStore(t *testing.T, ttl time.Duration) (*RedisConversationStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	store, err := NewRedisConversationStore("redis://"+server.Addr(), ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, server
+}
+
+func TestRedisConversationStore(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestRedisStore(t, 0)
+
+	if _, ok, err := store.Load(ctx, "s1"); err != nil || ok {
+		t.Fatalf("Load of unknown session = %v, %v, want not found", ok, err)
+	}
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Name: "speaker_1", Content: "Hello"},
+		{Role: "assistant", Content: "Hi!"},
+	}
+	if err := store.Save(ctx, "s1", messages); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, ok, err := store.Load(ctx, "s1")
+	if err != nil || !ok {
+		t.Fatalf("Load = %v, %v", ok, err)
+	}
+	if !reflect.DeepEqual(loaded, messages) {
+		t.Errorf("loaded %+v, want %+v", loaded, messages)
+	}
+
+	if err := store.Delete(ctx, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := store.Load(ctx, "s1"); ok {
+		t.Error("session still stored after Delete")
+	}
+}
+
+func TestRedisConversationStoreExpires(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t, time.Minute)
+
+	if err := store.Save(ctx, "s1", []openai.ChatCompletionMessage{{Role: "user", Content: "Hello"}}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.TTL("conversation:s1"); ttl != time.Minute {
+		t.Errorf("TTL = %v, want %v", ttl, time.Minute)
+	}
+
+	server.FastForward(2 * time.Minute)
+	if _, ok, _ := store.Load(ctx, "s1"); ok {
+		t.Error("session still stored after its TTL")
+	}
+}
+
+func TestRedisConversationStoreUnavailable(t *testing.T) {
+	store, server := newTestRedisStore(t, 0)
+	server.Close()
+
+	if _, _, err := store.Load(context.Background(), "s1"); err == nil {
+		t.Error("Load succeeded without a server")
+	}
+}
+
+// Two instances sharing the store each see the turns the other saved
+func TestSessionManagersShareRedisStore(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestRedisStore(t, 0)
+	first := NewSessionManager(openai.GPT3Dot5Turbo, 0, 0, store)
+	second := NewSessionManager(openai.GPT3Dot5Turbo, 0, 0, store)
+
+	turn := func(sessions *SessionManager, user, reply string) {
+		t.Helper()
+		conversation, err := sessions.Get(ctx, "shared")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conversation.AddMessage(MessageTypeUser, user)
+		conversation.AddMessage(MessageTypeAssistant, reply)
+		if err := sessions.Save(ctx, "shared", conversation); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	turn(first, "one", "1")
+	turn(second, "two", "2")
+	// The first instance still has the session in memory, yet continues
+	// from what the second saved
+	turn(first, "three", "3")
+
+	conversation, ok, err := second.Lookup(ctx, "shared")
+	if err != nil || !ok {
+		t.Fatalf("Lookup = %v, %v", ok, err)
+	}
+	var contents []string
+	for _, message := range conversation.Snapshot()[1:] {
+		contents = append(contents, message.Content)
+	}
+	if want := []string{"one", "1", "two", "2", "three", "3"}; !reflect.DeepEqual(contents, want) {
+		t.Errorf("conversation = %q, want %q", contents, want)
+	}
+}
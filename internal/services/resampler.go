@@ -0,0 +1,129 @@
+package services
+
+import (
+	"math"
+)
+
+const (
+	// Zero crossings of the sinc kept on each side of a sample. More gives a
+	// sharper low-pass at the cost of speed.
+	resampleZeroCrossings = 16
+
+	// Above this many phases the filter is evaluated per sample instead of
+	// from a table
+	resampleMaxPhases = 4096
+)
+
+// Resample converts interleaved samples between sample rates with a
+// Blackman windowed-sinc filter. The filter cuts off at the lower of the two
+// Nyquist frequencies, so downsampling does not alias. Results are rounded
+// and clamped to bitDepth.
+func Resample(samples []int, channels, bitDepth, fromRate, toRate int) []int {
+	if fromRate == toRate || fromRate <= 0 || toRate <= 0 || channels < 1 || len(samples) == 0 {
+		return samples
+	}
+
+	filter := newResampleFilter(fromRate, toRate)
+
+	frames := len(samples) / channels
+	outFrames := int(int64(frames) * int64(toRate) / int64(fromRate))
+	maxValue := float64(int64(1)<<(bitDepth-1) - 1)
+	minValue := -float64(int64(1) << (bitDepth - 1))
+
+	resampled := make([]int, outFrames*channels)
+	for n := 0; n < outFrames; n++ {
+		// Output frame n sits at input position n*down/up
+		position := int64(n) * int64(filter.down)
+		center := int(position / int64(filter.up))
+		taps := filter.taps(int(position % int64(filter.up)))
+
+		for channel := 0; channel < channels; channel++ {
+			var sum float64
+			for k, tap := range taps {
+				index := center + k - filter.halfWidth + 1
+				if index < 0 || index >= frames {
+					continue
+				}
+				sum += tap * float64(samples[index*channels+channel])
+			}
+
+			sum = math.Round(sum)
+			if sum > maxValue {
+				sum = maxValue
+			} else if sum < minValue {
+				sum = minValue
+			}
+			resampled[n*channels+channel] = int(sum)
+		}
+	}
+
+	return resampled
+}
+
+// resampleFilter holds the polyphase decomposition of the low-pass filter
+// for an up/down ratio
+type resampleFilter struct {
+	up, down  int
+	cutoff    float64
+	halfWidth int
+	table     [][]float64
+}
+
+func newResampleFilter(fromRate, toRate int) *resampleFilter {
+	divisor := gcd(fromRate, toRate)
+	filter := &resampleFilter{
+		up:     toRate / divisor,
+		down:   fromRate / divisor,
+		cutoff: math.Min(1, float64(toRate)/float64(fromRate)),
+	}
+	filter.halfWidth = int(math.Ceil(resampleZeroCrossings / filter.cutoff))
+
+	if filter.up <= resampleMaxPhases {
+		filter.table = make([][]float64, filter.up)
+	}
+	return filter
+}
+
+// taps returns the weights applied to the input samples around an output
+// sample that falls phase/up of the way past an input sample
+func (f *resampleFilter) taps(phase int) []float64 {
+	if f.table != nil && f.table[phase] != nil {
+		return f.table[phase]
+	}
+
+	fraction := float64(phase) / float64(f.up)
+	taps := make([]float64, 2*f.halfWidth)
+	for k := range taps {
+		// Distance in input samples from the output position
+		t := float64(k-f.halfWidth+1) - fraction
+		taps[k] = f.cutoff * sinc(f.cutoff*t) * blackman(t, float64(f.halfWidth))
+	}
+
+	if f.table != nil {
+		f.table[phase] = taps
+	}
+	return taps
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman is the Blackman window over [-halfWidth, halfWidth]
+func blackman(t, halfWidth float64) float64 {
+	if math.Abs(t) >= halfWidth {
+		return 0
+	}
+	x := math.Pi * (t/halfWidth + 1)
+	return 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
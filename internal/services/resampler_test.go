@@ -0,0 +1,131 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+// sine is frames of a tone at frequency Hz, as 16-bit samples
+func sine(frames, rate int, frequency, amplitude float64) []int {
+	samples := make([]int, frames)
+	for n := range samples {
+		samples[n] = int(math.Round(amplitude * math.Sin(2*math.Pi*frequency*float64(n)/float64(rate))))
+	}
+	return samples
+}
+
+// toneAmplitude measures the amplitude of the frequency component in
+// samples with a single DFT bin, leaving out the edges the filter has to
+// guess at
+func toneAmplitude(samples []int, rate int, frequency float64) float64 {
+	edge := len(samples) / 10
+	samples = samples[edge : len(samples)-edge]
+
+	var re, im float64
+	for n, sample := range samples {
+		phase := 2 * math.Pi * frequency * float64(n) / float64(rate)
+		re += float64(sample) * math.Cos(phase)
+		im -= float64(sample) * math.Sin(phase)
+	}
+	return 2 * math.Hypot(re, im) / float64(len(samples))
+}
+
+// channelSamples picks one channel out of interleaved samples
+func channelSamples(samples []int, channels, index int) []int {
+	picked := make([]int, 0, len(samples)/channels)
+	for i := index; i < len(samples); i += channels {
+		picked = append(picked, samples[i])
+	}
+	return picked
+}
+
+func TestResampleKeepsTone(t *testing.T) {
+	tests := []struct {
+		fromRate, toRate int
+	}{
+		{44100, 16000},
+		{48000, 16000},
+		{8000, 16000},
+		{16000, 22050},
+	}
+
+	for _, tt := range tests {
+		input := sine(tt.fromRate/2, tt.fromRate, 1000, 10000)
+		output := Resample(input, 1, 16, tt.fromRate, tt.toRate)
+
+		if want := tt.toRate / 2; len(output) != want {
+			t.Errorf("%d to %d Hz: %d samples, want %d", tt.fromRate, tt.toRate, len(output), want)
+		}
+		if amplitude := toneAmplitude(output, tt.toRate, 1000); math.Abs(amplitude-10000) > 100 {
+			t.Errorf("%d to %d Hz: 1 kHz amplitude %.0f, want 10000", tt.fromRate, tt.toRate, amplitude)
+		}
+		// Nothing leaks to neighbouring frequencies
+		if stray := toneAmplitude(output, tt.toRate, 1500); stray > 50 {
+			t.Errorf("%d to %d Hz: %.0f at 1.5 kHz, want the tone alone", tt.fromRate, tt.toRate, stray)
+		}
+	}
+}
+
+func TestResampleFiltersAboveNyquist(t *testing.T) {
+	// 10 kHz is above the 8 kHz Nyquist frequency of 16 kHz audio and would
+	// fold back to 6 kHz
+	input := sine(22050, 44100, 10000, 10000)
+	output := Resample(input, 1, 16, 44100, 16000)
+
+	if aliased := toneAmplitude(output, 16000, 6000); aliased > 100 {
+		t.Errorf("10 kHz tone aliased to 6 kHz with amplitude %.0f, want it filtered out", aliased)
+	}
+	var energy float64
+	for _, sample := range output[len(output)/10 : len(output)*9/10] {
+		energy += float64(sample) * float64(sample)
+	}
+	if rms := math.Sqrt(energy / float64(len(output)*8/10)); rms > 100 {
+		t.Errorf("output RMS %.0f, want the tone attenuated below 1%%", rms)
+	}
+}
+
+func TestResampleInterleavedChannels(t *testing.T) {
+	const frames = 44100 / 2
+	tone := sine(frames, 44100, 1000, 8000)
+	input := make([]int, 0, 3*frames)
+	for n := 0; n < frames; n++ {
+		// A tone, silence and a constant level
+		input = append(input, tone[n], 0, 1000)
+	}
+
+	output := Resample(input, 3, 16, 44100, 16000)
+	if want := 3 * 8000; len(output) != want {
+		t.Fatalf("%d samples, want %d", len(output), want)
+	}
+
+	if amplitude := toneAmplitude(channelSamples(output, 3, 0), 16000, 1000); math.Abs(amplitude-8000) > 80 {
+		t.Errorf("tone channel amplitude %.0f, want 8000", amplitude)
+	}
+	for i, sample := range channelSamples(output, 3, 1) {
+		if sample != 0 {
+			t.Fatalf("silent channel has %d at frame %d", sample, i)
+		}
+	}
+	level := channelSamples(output, 3, 2)
+	for i := len(level) / 10; i < len(level)*9/10; i++ {
+		if math.Abs(float64(level[i]-1000)) > 2 {
+			t.Fatalf("constant channel is %d at frame %d, want 1000", level[i], i)
+		}
+	}
+}
+
+func TestResampleClampsToBitDepth(t *testing.T) {
+	// A full-scale square wave rings past full scale once filtered
+	input := make([]int, 4410)
+	for n := range input {
+		input[n] = 127
+		if n/20%2 == 1 {
+			input[n] = -128
+		}
+	}
+	for _, sample := range Resample(input, 1, 8, 44100, 16000) {
+		if sample > 127 || sample < -128 {
+			t.Fatalf("sample %d outside 8 bits", sample)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentenceClosers may follow the final punctuation of a sentence
+const sentenceClosers = "\"')]}”’»"
+
+var sentenceAbbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true,
+	"jr": true, "st": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"no": true, "approx": true, "inc": true, "ltd": true, "co": true,
+}
+
+// SentenceSplitter cuts streamed text into sentences as soon as they are
+// complete. Sentences shorter than MinLength are merged with the next one
+// so speech synthesis is not called for fragments like "Sure."
+type SentenceSplitter struct {
+	MinLength int
+	pending   string
+}
+
+func NewSentenceSplitter() *SentenceSplitter {
+	return &SentenceSplitter{MinLength: 20}
+}
+
+// Push appends text and returns every sentence it completed
+func (s *SentenceSplitter) Push(text string) []string {
+	s.pending += text
+
+	var sentences []string
+	from := 0
+	for {
+		end := s.boundary(from)
+		if end < 0 {
+			break
+		}
+
+		sentence := strings.TrimSpace(s.pending[:end])
+		if utf8.RuneCountInString(sentence) < s.MinLength {
+			from = end
+			continue
+		}
+
+		sentences = append(sentences, sentence)
+		s.pending = s.pending[end:]
+		from = 0
+	}
+
+	return sentences
+}
+
+// Flush returns whatever text is left once the stream has ended
+func (s *SentenceSplitter) Flush() string {
+	rest := strings.TrimSpace(s.pending)
+	s.pending = ""
+	return rest
+}
+
+// boundary returns the byte offset just past the first sentence end at or
+// after from, or -1 when more text is needed to decide
+func (s *SentenceSplitter) boundary(from int) int {
+	text := s.pending
+	for i := from; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		next := i + size
+
+		switch r {
+		case '\n', '。', '！', '？':
+			return next
+		case '.', '!', '?', '…':
+			end := next
+			for end < len(text) {
+				closer, closerSize := utf8.DecodeRuneInString(text[end:])
+				if !strings.ContainsRune(sentenceClosers, closer) {
+					break
+				}
+				end += closerSize
+			}
+
+			// Wait for the next character: "3." may become "3.5"
+			if end >= len(text) {
+				return -1
+			}
+			following, _ := utf8.DecodeRuneInString(text[end:])
+			if unicode.IsSpace(following) && !(r == '.' && endsWithAbbreviation(text[:i])) {
+				return end
+			}
+		}
+
+		i = next
+	}
+
+	return -1
+}
+
+// endsWithAbbreviation reports whether the word before a period is a known
+// abbreviation or a single letter initial, as in "Dr. Smith" or "J. Doe"
+func endsWithAbbreviation(text string) bool {
+	word := text[strings.LastIndexFunc(text, unicode.IsSpace)+1:]
+	word = strings.TrimLeft(word, "\"'([{“‘«")
+	if utf8.RuneCountInString(word) == 1 {
+		return unicode.IsLetter([]rune(word)[0])
+	}
+	return sentenceAbbreviations[strings.ToLower(word)]
+}
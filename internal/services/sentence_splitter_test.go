@@ -0,0 +1,109 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSentenceSplitter(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// minLength replaces the default when set, -1 for none
+		minLength int
+		want      []string
+		rest      string
+	}{
+		{
+			name: "waits for what follows the last period",
+			text: "Hello there, how are you today? I am fine.",
+			want: []string{"Hello there, how are you today?"},
+			rest: "I am fine.",
+		},
+		{
+			name: "abbreviation",
+			text: "Dr. Smith will see you at noon today. Thanks",
+			want: []string{"Dr. Smith will see you at noon today."},
+			rest: "Thanks",
+		},
+		{
+			name: "initial",
+			text: "The letter came from J. Doe in Boston. Yes",
+			want: []string{"The letter came from J. Doe in Boston."},
+			rest: "Yes",
+		},
+		{
+			name: "decimal",
+			text: "It costs 3.5 dollars in total here. ",
+			want: []string{"It costs 3.5 dollars in total here."},
+		},
+		{
+			name: "short sentences merged",
+			text: "Sure. That is a great question to ask! ",
+			want: []string{"Sure. That is a great question to ask!"},
+		},
+		{
+			name:      "no minimum",
+			text:      "Sure. Okay! Right? ",
+			minLength: -1,
+			want:      []string{"Sure.", "Okay!", "Right?"},
+		},
+		{
+			name: "closing quote",
+			text: `He said "stop right there, please." Then he left`,
+			want: []string{`He said "stop right there, please."`},
+			rest: "Then he left",
+		},
+		{
+			name:      "newline and CJK",
+			text:      "Line one\n你好世界。再见",
+			minLength: -1,
+			want:      []string{"Line one", "你好世界。"},
+			rest:      "再见",
+		},
+		{
+			name: "too short to stand alone",
+			text: "Hi. Bye.",
+			rest: "Hi. Bye.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := func(pieces []string) ([]string, string) {
+				splitter := NewSentenceSplitter()
+				if tt.minLength != 0 {
+					splitter.MinLength = tt.minLength
+				}
+				var sentences []string
+				for _, piece := range pieces {
+					sentences = append(sentences, splitter.Push(piece)...)
+				}
+				return sentences, splitter.Flush()
+			}
+
+			// All at once, and one character at a time as tokens stream in
+			var characters []string
+			for _, r := range tt.text {
+				characters = append(characters, string(r))
+			}
+			for _, pieces := range [][]string{{tt.text}, characters} {
+				got, rest := split(pieces)
+				if !reflect.DeepEqual(got, tt.want) || rest != tt.rest {
+					t.Errorf("split %d pieces = %q, rest %q; want %q, rest %q", len(pieces), got, rest, tt.want, tt.rest)
+				}
+			}
+		})
+	}
+}
+
+func TestSentenceSplitterFlushEmpties(t *testing.T) {
+	splitter := NewSentenceSplitter()
+	splitter.Push("  trailing words ")
+	if rest := splitter.Flush(); rest != "trailing words" {
+		t.Errorf("Flush = %q, want %q", rest, "trailing words")
+	}
+	if rest := splitter.Flush(); rest != "" {
+		t.Errorf("second Flush = %q, want nothing", rest)
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"golang-gin-boilerplate/internal/interfaces"
+)
+
+// SessionManager maps session IDs to their own ConversationContext. Sessions
+// expire after idleTimeout without use, and once maxSessions is reached the
+// least recently used session is evicted to make room. When a store is
+// configured it holds the history, which is read back on every use so that
+// instances sharing the store continue each other's conversations. Turns on
+// one session run one at a time, see LockTurn.
+type SessionManager struct {
+	mu          sync.Mutex
+	sessions    map[string]*list.Element
+	turns       map[string]*turnLock
+	lru         *list.List // front is the most recently used session
+	model       string
+	idleTimeout time.Duration
+	maxSessions int
+	store       interfaces.ConversationStore
+	now         func() time.Time
+}
+
+type sessionEntry struct {
+	id       string
+	context  *ConversationContext
+	lastUsed time.Time
+}
+
+// turnLock is held by the running turn of a session. refs counts the turns
+// holding or waiting for it, so it can be dropped once there are none.
+type turnLock struct {
+	held chan struct{}
+	refs int
+}
+
+// NewSessionManager creates a session manager. A zero idleTimeout disables
+// expiry, a zero maxSessions disables the cap and a nil store keeps history
+// in this process only.
+func NewSessionManager(model string, idleTimeout time.Duration, maxSessions int, store interfaces.ConversationStore) *SessionManager {
+	return &SessionManager{
+		sessions:    make(map[string]*list.Element),
+		turns:       make(map[string]*turnLock),
+		lru:         list.New(),
+		model:       model,
+		idleTimeout: idleTimeout,
+		maxSessions: maxSessions,
+		store:       store,
+		now:         time.Now,
+	}
+}
+
+// Get returns the context for the session, loading it from the store or
+// creating it if needed
+func (m *SessionManager) Get(ctx context.Context, sessionID string) (*ConversationContext, error) {
+	conversation, ok, err := m.Lookup(ctx, sessionID)
+	if err != nil || ok {
+		return conversation, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Another request may have created it while the lock was released
+	if element, ok := m.sessions[sessionID]; ok {
+		return m.touchUnlocked(element), nil
+	}
+
+	return m.insertUnlocked(sessionID, NewConversationContext(m.model)), nil
+}
+
+// Lookup returns the context for an existing session without creating one.
+// With a store, the stored history is read on every call so that turns
+// another instance saved are never missed; memory only serves sessions that
+// have not been saved yet.
+func (m *SessionManager) Lookup(ctx context.Context, sessionID string) (*ConversationContext, bool, error) {
+	m.mu.Lock()
+	m.pruneExpiredUnlocked()
+	if m.store == nil {
+		defer m.mu.Unlock()
+		if element, ok := m.sessions[sessionID]; ok {
+			return m.touchUnlocked(element), true, nil
+		}
+		return nil, false, nil
+	}
+	m.mu.Unlock()
+
+	// Read the store without holding the lock during I/O
+	messages, stored, err := m.store.Load(ctx, sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, cached := m.sessions[sessionID]
+	if !stored {
+		if cached {
+			return m.touchUnlocked(element), true, nil
+		}
+		return nil, false, nil
+	}
+
+	// The running turn, if any, keeps the context it loaded; it holds the
+	// turn lock, so no other turn saves over it
+	conversation := NewConversationContextFromMessages(m.model, messages)
+	if cached {
+		element.Value.(*sessionEntry).context = conversation
+		m.touchUnlocked(element)
+		return conversation, true, nil
+	}
+	return m.insertUnlocked(sessionID, conversation), true, nil
+}
+
+// LockTurn waits until no other turn runs on the session and returns the
+// function that ends this one. A turn holds it from Get until Save, so that
+// concurrent turns each build on the one before instead of the last Save
+// dropping the others.
+func (m *SessionManager) LockTurn(ctx context.Context, sessionID string) (func(), error) {
+	m.mu.Lock()
+	lock, ok := m.turns[sessionID]
+	if !ok {
+		lock = &turnLock{held: make(chan struct{}, 1)}
+		m.turns[sessionID] = lock
+	}
+	lock.refs++
+	m.mu.Unlock()
+
+	release := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(m.turns, sessionID)
+		}
+	}
+
+	select {
+	case lock.held <- struct{}{}:
+		return func() {
+			<-lock.held
+			release()
+		}, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+// Save writes the session's current messages to the store, if any
+func (m *SessionManager) Save(ctx context.Context, sessionID string, conversation *ConversationContext) error {
+	if m.store == nil {
+		return nil
+	}
+	return m.store.Save(ctx, sessionID, conversation.Snapshot())
+}
+
+// Delete forgets a session, including its stored history
+func (m *SessionManager) Delete(ctx context.Context, sessionID string) error {
+	m.mu.Lock()
+	if element, ok := m.sessions[sessionID]; ok {
+		m.removeElementUnlocked(element)
+	}
+	m.mu.Unlock()
+
+	if m.store == nil {
+		return nil
+	}
+	return m.store.Delete(ctx, sessionID)
+}
+
+// Len returns the number of live sessions
+func (m *SessionManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneExpiredUnlocked()
+	return m.lru.Len()
+}
+
+// pruneExpiredUnlocked drops idle sessions, oldest first. Assumes the mutex is held.
+func (m *SessionManager) pruneExpiredUnlocked() {
+	if m.idleTimeout <= 0 {
+		return
+	}
+
+	cutoff := m.now().Add(-m.idleTimeout)
+	for element := m.lru.Back(); element != nil; element = m.lru.Back() {
+		if element.Value.(*sessionEntry).lastUsed.After(cutoff) {
+			return
+		}
+		m.removeElementUnlocked(element)
+	}
+}
+
+func (m *SessionManager) touchUnlocked(element *list.Element) *ConversationContext {
+	entry := element.Value.(*sessionEntry)
+	entry.lastUsed = m.now()
+	m.lru.MoveToFront(element)
+	return entry.context
+}
+
+func (m *SessionManager) insertUnlocked(sessionID string, conversation *ConversationContext) *ConversationContext {
+	// Make room for the new session
+	for m.maxSessions > 0 && m.lru.Len() >= m.maxSessions {
+		m.removeElementUnlocked(m.lru.Back())
+	}
+
+	entry := &sessionEntry{
+		id:       sessionID,
+		context:  conversation,
+		lastUsed: m.now(),
+	}
+	m.sessions[sessionID] = m.lru.PushFront(entry)
+
+	return conversation
+}
+
+func (m *SessionManager) removeElementUnlocked(element *list.Element) {
+	entry := m.lru.Remove(element).(*sessionEntry)
+	delete(m.sessions, entry.id)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteConversationStore keeps every session as one row holding its
+// messages as JSON. It uses the pure Go driver so CGO stays disabled.
+type SQLiteConversationStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteConversationStore(path string) (*SQLiteConversationStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+
+	// SQLite allows a single writer; serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	schema := `
+		CREATE TABLE IF NOT EXISTS conversations (
+			session_id TEXT PRIMARY KEY,
+			messages   TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create conversations table: %v", err)
+	}
+
+	return &SQLiteConversationStore{db: db}, nil
+}
+
+func (s *SQLiteConversationStore) Load(ctx context.Context, sessionID string) ([]openai.ChatCompletionMessage, bool, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT messages FROM conversations WHERE session_id = ?`, sessionID,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load conversation: %v", err)
+	}
+
+	var messages []openai.ChatCompletionMessage
+	if err := json.Unmarshal([]byte(data), &messages); err != nil {
+		return nil, false, fmt.Errorf("failed to decode conversation: %v", err)
+	}
+
+	return messages, true, nil
+}
+
+func (s *SQLiteConversationStore) Save(ctx context.Context, sessionID string, messages []openai.ChatCompletionMessage) error {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %v", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO conversations (session_id, messages, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET messages = excluded.messages, updated_at = excluded.updated_at`,
+		sessionID, string(data), time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save conversation: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteConversationStore) Delete(ctx context.Context, sessionID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM conversations WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete conversation: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteConversationStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang-gin-boilerplate/internal/interfaces"
+)
+
+func TestSQLiteConversationStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conversations.db")
+	checkStoreRoundTrip(t, func() interfaces.ConversationStore {
+		store, err := NewSQLiteConversationStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"golang-gin-boilerplate/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteSessionVoiceStore keeps every session's voice as one row holding it
+// as JSON
+type SQLiteSessionVoiceStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteSessionVoiceStore(path string) (*SQLiteSessionVoiceStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+
+	// SQLite allows a single writer; serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	schema := `
+		CREATE TABLE IF NOT EXISTS session_voices (
+			session_id TEXT PRIMARY KEY,
+			voice      TEXT NOT NULL
+		)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create session_voices table: %v", err)
+	}
+
+	return &SQLiteSessionVoiceStore{db: db}, nil
+}
+
+func (s *SQLiteSessionVoiceStore) Load(ctx context.Context, sessionID string) (models.SessionVoiceModel, bool, error) {
+	var voice models.SessionVoiceModel
+	var data string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT voice FROM session_voices WHERE session_id = ?`, sessionID,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return voice, false, nil
+	}
+	if err != nil {
+		return voice, false, fmt.Errorf("failed to load session voice: %v", err)
+	}
+
+	if err := json.Unmarshal([]byte(data), &voice); err != nil {
+		return voice, false, fmt.Errorf("failed to decode session voice: %v", err)
+	}
+
+	return voice, true, nil
+}
+
+func (s *SQLiteSessionVoiceStore) Save(ctx context.Context, voice models.SessionVoiceModel) error {
+	data, err := json.Marshal(voice)
+	if err != nil {
+		return fmt.Errorf("failed to encode session voice: %v", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO session_voices (session_id, voice) VALUES (?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET voice = excluded.voice`,
+		voice.SessionID, string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save session voice: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteSessionVoiceStore) Delete(ctx context.Context, sessionID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM session_voices WHERE session_id = ?`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session voice: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteSessionVoiceStore) Close() error {
+	return s.db.Close()
+}
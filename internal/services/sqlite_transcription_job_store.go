@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteTranscriptionJobStore keeps every job as one row holding it as JSON,
+// so queued jobs survive a restart
+type SQLiteTranscriptionJobStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteTranscriptionJobStore(path string) (*SQLiteTranscriptionJobStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+
+	// SQLite allows a single writer; serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	schema := `
+		CREATE TABLE IF NOT EXISTS transcription_jobs (
+			id           TEXT PRIMARY KEY,
+			status       TEXT NOT NULL,
+			audio_path   TEXT NOT NULL,
+			job          TEXT NOT NULL,
+			created_at   INTEGER NOT NULL,
+			completed_at INTEGER
+		)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create transcription_jobs table: %v", err)
+	}
+
+	return &SQLiteTranscriptionJobStore{db: db}, nil
+}
+
+func (s *SQLiteTranscriptionJobStore) Load(ctx context.Context, id string) (models.TranscriptionJobModel, bool, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT job, audio_path FROM transcription_jobs WHERE id = ?`, id,
+	)
+
+	job, err := scanTranscriptionJob(row)
+	if err == sql.ErrNoRows {
+		return job, false, nil
+	}
+	if err != nil {
+		return job, false, err
+	}
+
+	return job, true, nil
+}
+
+func (s *SQLiteTranscriptionJobStore) Save(ctx context.Context, job models.TranscriptionJobModel) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode transcription job: %v", err)
+	}
+
+	var completedAt sql.NullInt64
+	if job.CompletedAt != nil {
+		completedAt = sql.NullInt64{Int64: job.CompletedAt.Unix(), Valid: true}
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO transcription_jobs (id, status, audio_path, job, created_at, completed_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status, audio_path = excluded.audio_path,
+			job = excluded.job, completed_at = excluded.completed_at`,
+		job.ID, job.Status, job.AudioPath, string(data), job.CreatedAt.UnixNano(), completedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save transcription job: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteTranscriptionJobStore) ListUnfinished(ctx context.Context) ([]models.TranscriptionJobModel, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT job, audio_path FROM transcription_jobs WHERE status IN (?, ?) ORDER BY created_at`,
+		models.TranscriptionJobQueued, models.TranscriptionJobRunning,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcription jobs: %v", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.TranscriptionJobModel
+	for rows.Next() {
+		job, err := scanTranscriptionJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list transcription jobs: %v", err)
+	}
+
+	return jobs, nil
+}
+
+func (s *SQLiteTranscriptionJobStore) DeleteFinishedBefore(ctx context.Context, cutoff time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM transcription_jobs WHERE completed_at IS NOT NULL AND completed_at < ?`, cutoff.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete transcription jobs: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteTranscriptionJobStore) Close() error {
+	return s.db.Close()
+}
+
+// scanTranscriptionJob decodes a row of job and audio_path
+func scanTranscriptionJob(row interface{ Scan(...any) error }) (models.TranscriptionJobModel, error) {
+	var job models.TranscriptionJobModel
+	var data string
+	if err := row.Scan(&data, &job.AudioPath); err != nil {
+		if err == sql.ErrNoRows {
+			return job, err
+		}
+		return job, fmt.Errorf("failed to load transcription job: %v", err)
+	}
+
+	audioPath := job.AudioPath
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return job, fmt.Errorf("failed to decode transcription job: %v", err)
+	}
+	job.AudioPath = audioPath
+
+	return job, nil
+}
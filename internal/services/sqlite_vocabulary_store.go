@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"golang-gin-boilerplate/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteVocabularyStore keeps every tenant's vocabulary as one row holding
+// it as JSON
+type SQLiteVocabularyStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteVocabularyStore(path string) (*SQLiteVocabularyStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+
+	// SQLite allows a single writer; serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	schema := `
+		CREATE TABLE IF NOT EXISTS vocabularies (
+			tenant_id  TEXT PRIMARY KEY,
+			vocabulary TEXT NOT NULL
+		)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create vocabularies table: %v", err)
+	}
+
+	return &SQLiteVocabularyStore{db: db}, nil
+}
+
+func (s *SQLiteVocabularyStore) Load(ctx context.Context, tenantID string) (models.VocabularyModel, bool, error) {
+	var vocabulary models.VocabularyModel
+	var data string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT vocabulary FROM vocabularies WHERE tenant_id = ?`, tenantID,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return vocabulary, false, nil
+	}
+	if err != nil {
+		return vocabulary, false, fmt.Errorf("failed to load vocabulary: %v", err)
+	}
+
+	if err := json.Unmarshal([]byte(data), &vocabulary); err != nil {
+		return vocabulary, false, fmt.Errorf("failed to decode vocabulary: %v", err)
+	}
+
+	return vocabulary, true, nil
+}
+
+func (s *SQLiteVocabularyStore) Save(ctx context.Context, vocabulary models.VocabularyModel) error {
+	data, err := json.Marshal(vocabulary)
+	if err != nil {
+		return fmt.Errorf("failed to encode vocabulary: %v", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO vocabularies (tenant_id, vocabulary) VALUES (?, ?)
+		ON CONFLICT(tenant_id) DO UPDATE SET vocabulary = excluded.vocabulary`,
+		vocabulary.TenantID, string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save vocabulary: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteVocabularyStore) Delete(ctx context.Context, tenantID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM vocabularies WHERE tenant_id = ?`, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete vocabulary: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteVocabularyStore) Close() error {
+	return s.db.Close()
+}
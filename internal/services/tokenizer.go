@@ -0,0 +1,119 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+	"github.com/sashabaranov/go-openai"
+)
+
+func init() {
+	// Use the vocabularies embedded in the binary instead of downloading them
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = make(map[string]*tiktoken.Tiktoken)
+)
+
+// encodingNameForModel picks the BPE vocabulary a model was trained with.
+// Self-hosted and unknown models fall back to cl100k_base, which is a far
+// better estimate than counting characters.
+func encodingNameForModel(model string) string {
+	if name, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return name
+	}
+	for prefix, name := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return name
+		}
+	}
+
+	// Newer OpenAI families not yet known to the tokenizer package
+	if strings.HasPrefix(model, "o1") || strings.HasPrefix(model, "o3") || strings.HasPrefix(model, "o4") || strings.HasPrefix(model, "gpt-5") {
+		return tiktoken.MODEL_O200K_BASE
+	}
+
+	return tiktoken.MODEL_CL100K_BASE
+}
+
+// encodingForModel returns a cached tokenizer for the model, or nil if the
+// vocabulary could not be loaded
+func encodingForModel(model string) *tiktoken.Tiktoken {
+	name := encodingNameForModel(model)
+
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if encoding, ok := encodings[name]; ok {
+		return encoding
+	}
+
+	encoding, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		log.Printf("Failed to load %s tokenizer, falling back to estimates: %v", name, err)
+	}
+	// Cache failures too so a broken vocab is only reported once
+	encodings[name] = encoding
+
+	return encoding
+}
+
+// CountTextTokens returns the number of BPE tokens in text for the model
+func CountTextTokens(model, text string) int {
+	encoding := encodingForModel(model)
+	if encoding == nil {
+		// Rough estimation: ~4 characters per token
+		return len(text) / 4
+	}
+	return len(encoding.EncodeOrdinary(text))
+}
+
+// chatFormatOverhead returns the tokens the chat format adds around each
+// message and around a message name, as documented in the OpenAI cookbook
+func chatFormatOverhead(model string) (perMessage, perName int) {
+	if model == "gpt-3.5-turbo-0301" {
+		// Every message follows <|start|>{role/name}\n{content}<|end|>\n
+		return 4, -1
+	}
+	return 3, 1
+}
+
+// replyPrimingTokens covers the <|start|>assistant<|message|> every reply is primed with
+const replyPrimingTokens = 3
+
+// CountMessageTokens counts a single chat message the way the API bills it
+func CountMessageTokens(model string, message openai.ChatCompletionMessage) int {
+	perMessage, perName := chatFormatOverhead(model)
+
+	tokens := perMessage
+	tokens += CountTextTokens(model, message.Role)
+	tokens += CountTextTokens(model, message.Content)
+	for _, part := range message.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			tokens += CountTextTokens(model, part.Text)
+		}
+	}
+	if message.Name != "" {
+		tokens += CountTextTokens(model, message.Name) + perName
+	}
+
+	return tokens
+}
+
+// CountMessagesTokens counts the prompt tokens of a full chat request
+func CountMessagesTokens(model string, messages []openai.ChatCompletionMessage) int {
+	if len(messages) == 0 {
+		return 0
+	}
+
+	tokens := replyPrimingTokens
+	for _, message := range messages {
+		tokens += CountMessageTokens(model, message)
+	}
+	return tokens
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Counts from tiktoken and the OpenAI cookbook ("How to count tokens with
+// tiktoken")
+func TestCountTextTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{"gpt-3.5-turbo", "", 0},
+		{"gpt-3.5-turbo", "hello world", 2},
+		{"gpt-3.5-turbo", "tiktoken is great!", 6},
+		{"gpt-3.5-turbo", "antidisestablishmentarianism", 6},
+		{"gpt-3.5-turbo", "2 + 2 = 4", 7},
+		{"gpt-3.5-turbo", "お誕生日おめでとう", 9},
+		{"gpt-4", "tiktoken is great!", 6},
+		{"gpt-4", "antidisestablishmentarianism", 6},
+		{"gpt-3.5-turbo-0301", "tiktoken is great!", 6},
+		// Unknown and self-hosted models are counted with cl100k_base
+		{"llama3", "tiktoken is great!", 6},
+	}
+	for _, test := range tests {
+		if got := CountTextTokens(test.model, test.text); got != test.want {
+			t.Errorf("CountTextTokens(%q, %q) = %d, want %d", test.model, test.text, got, test.want)
+		}
+	}
+}
+
+func TestEncodingNameForModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-3.5-turbo", tiktoken.MODEL_CL100K_BASE},
+		{"gpt-3.5-turbo-0301", tiktoken.MODEL_CL100K_BASE},
+		{"gpt-4", tiktoken.MODEL_CL100K_BASE},
+		{"gpt-4-0613", tiktoken.MODEL_CL100K_BASE},
+		{"o1-mini", tiktoken.MODEL_O200K_BASE},
+		{"gpt-5", tiktoken.MODEL_O200K_BASE},
+		{"mistral-7b-instruct", tiktoken.MODEL_CL100K_BASE},
+	}
+	for _, test := range tests {
+		if got := encodingNameForModel(test.model); got != test.want {
+			t.Errorf("encodingNameForModel(%q) = %q, want %q", test.model, got, test.want)
+		}
+	}
+}
+
+// cookbookMessages is the cookbook's example conversation, with names
+var cookbookMessages = []openai.ChatCompletionMessage{
+	{Role: "system", Content: "You are a helpful, pattern-following assistant that translates corporate jargon into plain English."},
+	{Role: "system", Name: "example_user", Content: "New synergies will help drive top-line growth."},
+	{Role: "system", Name: "example_assistant", Content: "Things working well together will increase revenue."},
+	{Role: "system", Name: "example_user", Content: "Let's circle back when we have more bandwidth to touch base on opportunities for increased leverage."},
+	{Role: "system", Name: "example_assistant", Content: "Let's talk later when we're less busy about how to do better."},
+	{Role: "user", Content: "This late pivot means we don't have time to boil the ocean for the client deliverable."},
+}
+
+// The API reports these prompt token counts for cookbookMessages
+func TestCountMessagesTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-3.5-turbo-0301", 127},
+		{"gpt-3.5-turbo-0613", 129},
+		{"gpt-3.5-turbo", 129},
+		{"gpt-4-0314", 129},
+		{"gpt-4-0613", 129},
+		{"gpt-4", 129},
+	}
+	for _, test := range tests {
+		if got := CountMessagesTokens(test.model, cookbookMessages); got != test.want {
+			t.Errorf("CountMessagesTokens(%q) = %d, want %d", test.model, got, test.want)
+		}
+	}
+}
+
+func TestChatFormatOverhead(t *testing.T) {
+	tests := []struct {
+		model               string
+		perMessage, perName int
+	}{
+		{"gpt-3.5-turbo-0301", 4, -1},
+		{"gpt-3.5-turbo", 3, 1},
+		{"gpt-4", 3, 1},
+	}
+	for _, test := range tests {
+		perMessage, perName := chatFormatOverhead(test.model)
+		if perMessage != test.perMessage || perName != test.perName {
+			t.Errorf("chatFormatOverhead(%q) = %d, %d, want %d, %d", test.model, perMessage, perName, test.perMessage, test.perName)
+		}
+	}
+}
+
+// Each message costs its overhead on top of its text, and a request adds
+// the reply priming once
+func TestCountMessageTokensOverhead(t *testing.T) {
+	message := openai.ChatCompletionMessage{Role: "user", Content: "tiktoken is great!"}
+	named := openai.ChatCompletionMessage{Role: "user", Name: "example_user", Content: "tiktoken is great!"}
+
+	for _, model := range []string{"gpt-3.5-turbo-0301", "gpt-4"} {
+		perMessage, perName := chatFormatOverhead(model)
+		text := CountTextTokens(model, "user") + CountTextTokens(model, "tiktoken is great!")
+		name := CountTextTokens(model, "example_user")
+
+		if got, want := CountMessageTokens(model, message), perMessage+text; got != want {
+			t.Errorf("%s: message = %d tokens, want %d", model, got, want)
+		}
+		if got, want := CountMessageTokens(model, named), perMessage+text+name+perName; got != want {
+			t.Errorf("%s: named message = %d tokens, want %d", model, got, want)
+		}
+		if got, want := CountMessagesTokens(model, []openai.ChatCompletionMessage{message, named}),
+			replyPrimingTokens+CountMessageTokens(model, message)+CountMessageTokens(model, named); got != want {
+			t.Errorf("%s: request = %d tokens, want %d", model, got, want)
+		}
+	}
+
+	if replyPrimingTokens != 3 {
+		t.Errorf("replyPrimingTokens = %d, want 3", replyPrimingTokens)
+	}
+	if got := CountMessagesTokens("gpt-4", nil); got != 0 {
+		t.Errorf("empty request = %d tokens, want 0", got)
+	}
+}
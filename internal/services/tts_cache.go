@@ -0,0 +1,303 @@
+package services
+
+import (
+	"container/list"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// TTSCacheConfig sizes the tiers of a TTSCache. A tier with no capacity is
+// not used.
+type TTSCacheConfig struct {
+	MemoryBytes int64
+	// DiskDir holds one file per clip
+	DiskDir   string
+	DiskBytes int64
+}
+
+// TTSCache keeps synthesized clips by content key in a memory LRU backed by
+// a larger LRU on disk. Clips found on disk are moved back into memory.
+// Both tiers evict the least recently used clips to stay under their size.
+type TTSCache struct {
+	memory *byteLRU
+	disk   *byteLRU
+	dir    string
+
+	mu sync.Mutex // serializes disk writes and evictions
+
+	memoryHits atomic.Uint64
+	diskHits   atomic.Uint64
+	misses     atomic.Uint64
+}
+
+func NewTTSCache(config TTSCacheConfig) (*TTSCache, error) {
+	c := &TTSCache{}
+	if config.MemoryBytes > 0 {
+		c.memory = newByteLRU(config.MemoryBytes)
+	}
+	if config.DiskBytes > 0 && config.DiskDir != "" {
+		if err := os.MkdirAll(config.DiskDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create TTS cache directory: %v", err)
+		}
+		c.dir = config.DiskDir
+		c.disk = newByteLRU(config.DiskBytes)
+		if err := c.loadDisk(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Get returns the clip stored under key. The clip is shared and must not be
+// modified.
+func (c *TTSCache) Get(key string) ([]byte, bool) {
+	if c.memory != nil {
+		if audio, ok := c.memory.get(key); ok {
+			c.memoryHits.Add(1)
+			return audio, true
+		}
+	}
+
+	if c.disk != nil {
+		if _, ok := c.disk.get(key); ok {
+			path := c.diskPath(key)
+			audio, err := os.ReadFile(path)
+			if err == nil {
+				// The modification time orders clips when the index is rebuilt
+				now := time.Now()
+				os.Chtimes(path, now, now)
+				c.diskHits.Add(1)
+				if c.memory != nil {
+					c.memory.put(key, audio, int64(len(audio)))
+				}
+				return audio, true
+			}
+			// Removed behind our back
+			c.disk.remove(key)
+		}
+	}
+
+	c.misses.Add(1)
+	return nil, false
+}
+
+// Put stores a clip in every tier it fits in
+func (c *TTSCache) Put(key string, audio []byte) {
+	if c.memory != nil {
+		c.memory.put(key, audio, int64(len(audio)))
+	}
+	if c.disk != nil {
+		if err := c.writeDisk(key, audio); err != nil {
+			log.Printf("Failed to write TTS cache: %v", err)
+		}
+	}
+}
+
+// Stats reports hits, misses and how full the tiers are
+func (c *TTSCache) Stats() models.TTSCacheStatsModel {
+	stats := models.TTSCacheStatsModel{
+		Enabled:    true,
+		MemoryHits: c.memoryHits.Load(),
+		DiskHits:   c.diskHits.Load(),
+		Misses:     c.misses.Load(),
+	}
+	if lookups := stats.MemoryHits + stats.DiskHits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.MemoryHits+stats.DiskHits) / float64(lookups)
+	}
+	if c.memory != nil {
+		stats.Memory = c.memory.stats()
+	}
+	if c.disk != nil {
+		stats.Disk = c.disk.stats()
+	}
+	return stats
+}
+
+// writeDisk writes through a temporary file so readers never see a partial clip
+func (c *TTSCache) writeDisk(key string, audio []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.disk.get(key); ok {
+		return nil
+	}
+
+	path := c.diskPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(audio); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	for _, evicted := range c.disk.put(key, nil, int64(len(audio))) {
+		if err := os.Remove(c.diskPath(evicted)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to evict TTS cache file: %v", err)
+		}
+	}
+	return nil
+}
+
+// loadDisk indexes the clips a previous run left, least recently used first
+func (c *TTSCache) loadDisk() error {
+	type clip struct {
+		key  string
+		size int64
+		used int64
+	}
+	var clips []clip
+
+	err := filepath.WalkDir(c.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		name := entry.Name()
+		if strings.HasPrefix(name, ".tmp-") {
+			// Left over from an interrupted write
+			os.Remove(path)
+			return nil
+		}
+		if len(name) < 2 || filepath.Base(filepath.Dir(path)) != name[:2] {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		clips = append(clips, clip{key: name, size: info.Size(), used: info.ModTime().UnixNano()})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read TTS cache directory: %v", err)
+	}
+
+	sort.Slice(clips, func(i, j int) bool { return clips[i].used < clips[j].used })
+	for _, clip := range clips {
+		for _, evicted := range c.disk.put(clip.key, nil, clip.size) {
+			os.Remove(c.diskPath(evicted))
+		}
+	}
+	return nil
+}
+
+// diskPath spreads clips over 256 directories
+func (c *TTSCache) diskPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// byteLRU is an LRU bounded by the total size of its values. Values may be
+// nil when only the size is tracked, as for clips on disk.
+type byteLRU struct {
+	mu        sync.Mutex
+	capacity  int64
+	size      int64
+	order     *list.List // front is the most recently used
+	entries   map[string]*list.Element
+	evictions uint64
+}
+
+type byteLRUEntry struct {
+	key   string
+	value []byte
+	size  int64
+}
+
+func newByteLRU(capacity int64) *byteLRU {
+	return &byteLRU{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (l *byteLRU) get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(element)
+	return element.Value.(*byteLRUEntry).value, true
+}
+
+// put adds or refreshes key and returns the keys evicted to make room.
+// Values larger than the whole capacity are not kept.
+func (l *byteLRU) put(key string, value []byte, size int64) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if size > l.capacity {
+		return nil
+	}
+
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*byteLRUEntry)
+		l.size += size - entry.size
+		entry.value, entry.size = value, size
+		l.order.MoveToFront(element)
+	} else {
+		l.entries[key] = l.order.PushFront(&byteLRUEntry{key: key, value: value, size: size})
+		l.size += size
+	}
+
+	var evicted []string
+	for l.size > l.capacity {
+		oldest := l.order.Back()
+		entry := oldest.Value.(*byteLRUEntry)
+		l.order.Remove(oldest)
+		delete(l.entries, entry.key)
+		l.size -= entry.size
+		l.evictions++
+		evicted = append(evicted, entry.key)
+	}
+	return evicted
+}
+
+func (l *byteLRU) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.entries[key]; ok {
+		l.order.Remove(element)
+		delete(l.entries, key)
+		l.size -= element.Value.(*byteLRUEntry).size
+	}
+}
+
+func (l *byteLRU) stats() *models.TTSCacheTierModel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return &models.TTSCacheTierModel{
+		Entries:       len(l.entries),
+		Bytes:         l.size,
+		CapacityBytes: l.capacity,
+		Evictions:     l.evictions,
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// clip is ten bytes of audio marked with key
+func clip(key string) []byte {
+	return bytes.Repeat([]byte(key[:1]), 10)
+}
+
+func TestByteLRUEvictsUnderCap(t *testing.T) {
+	lru := newByteLRU(25)
+
+	if evicted := lru.put("a", clip("a"), 10); evicted != nil {
+		t.Errorf("evicted %v with room to spare", evicted)
+	}
+	lru.put("b", clip("b"), 10)
+	// Using a leaves b the least recently used
+	lru.get("a")
+	if evicted := lru.put("c", clip("c"), 10); !reflect.DeepEqual(evicted, []string{"b"}) {
+		t.Errorf("evicted %v, want [b]", evicted)
+	}
+
+	// A value bigger than the whole cache is refused and evicts nothing
+	if evicted := lru.put("huge", make([]byte, 26), 26); evicted != nil {
+		t.Errorf("huge value evicted %v", evicted)
+	}
+	if _, ok := lru.get("huge"); ok {
+		t.Error("value over the capacity was kept")
+	}
+
+	// Growing an entry makes room for it
+	if evicted := lru.put("c", make([]byte, 20), 20); !reflect.DeepEqual(evicted, []string{"a"}) {
+		t.Errorf("growing c evicted %v, want [a]", evicted)
+	}
+
+	stats := lru.stats()
+	if stats.Entries != 1 || stats.Bytes != 20 || stats.Evictions != 2 {
+		t.Errorf("stats = %+v, want 1 entry of 20 bytes after 2 evictions", stats)
+	}
+}
+
+func TestTTSCachePromotesDiskHits(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewTTSCache(TTSCacheConfig{MemoryBytes: 15, DiskDir: dir, DiskBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache.Put("aa01", clip("a"))
+	// b pushes a out of memory, but both stay on disk
+	cache.Put("bb02", clip("b"))
+
+	audio, ok := cache.Get("aa01")
+	if !ok || !bytes.Equal(audio, clip("a")) {
+		t.Fatalf("Get = %q, %v, want the clip from disk", audio, ok)
+	}
+	if _, ok := cache.Get("aa01"); !ok {
+		t.Fatal("promoted clip missing")
+	}
+
+	stats := cache.Stats()
+	if stats.DiskHits != 1 || stats.MemoryHits != 1 {
+		t.Errorf("disk hits %d, memory hits %d, want one of each", stats.DiskHits, stats.MemoryHits)
+	}
+	if stats.Memory.Entries != 1 || stats.Disk.Entries != 2 {
+		t.Errorf("memory holds %d and disk %d clips, want 1 and 2", stats.Memory.Entries, stats.Disk.Entries)
+	}
+
+	if _, ok := cache.Get("cc03"); ok {
+		t.Error("unknown key found")
+	}
+	if misses := cache.Stats().Misses; misses != 1 {
+		t.Errorf("misses = %d, want 1", misses)
+	}
+}
+
+func TestTTSCacheDiskCap(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewTTSCache(TTSCacheConfig{DiskDir: dir, DiskBytes: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache.Put("aa01", clip("a"))
+	cache.Put("bb02", clip("b"))
+	cache.Get("aa01")
+	cache.Put("cc03", clip("c"))
+
+	if got, want := diskClips(t, dir), []string{"aa01", "cc03"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("files on disk %v, want %v", got, want)
+	}
+	if stats := cache.Stats(); stats.Disk.Bytes != 20 || stats.Disk.Evictions != 1 {
+		t.Errorf("disk stats = %+v, want 20 bytes after 1 eviction", stats.Disk)
+	}
+}
+
+func TestTTSCacheReloadsDisk(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewTTSCache(TTSCacheConfig{MemoryBytes: 100, DiskDir: dir, DiskBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"aa01", "bb02", "cc03"} {
+		cache.Put(key, clip(key))
+	}
+
+	// Modification times record use: b is the oldest, a the newest
+	base := time.Now().Add(-time.Hour)
+	for i, key := range []string{"bb02", "cc03", "aa01"} {
+		used := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(filepath.Join(dir, key[:2], key), used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An interrupted write is cleaned up
+	if err := os.WriteFile(filepath.Join(dir, "aa", ".tmp-123"), []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restarted with room for two clips, the least recently used goes
+	restarted, err := NewTTSCache(TTSCacheConfig{MemoryBytes: 100, DiskDir: dir, DiskBytes: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := diskClips(t, dir), []string{"aa01", "cc03"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("files after restart %v, want %v", got, want)
+	}
+	for _, key := range []string{"aa01", "cc03"} {
+		if audio, ok := restarted.Get(key); !ok || !bytes.Equal(audio, clip(key)) {
+			t.Errorf("%s after restart = %q, %v", key, audio, ok)
+		}
+	}
+	if _, ok := restarted.Get("bb02"); ok {
+		t.Error("evicted clip still served")
+	}
+	if stats := restarted.Stats(); stats.DiskHits != 2 || stats.Memory.Entries != 2 {
+		t.Errorf("stats = %+v, want both clips read from disk into memory", stats)
+	}
+}
+
+// diskClips lists the clip files under dir
+func diskClips(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			names = append(names, info.Name())
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
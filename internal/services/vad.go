@@ -0,0 +1,274 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrNoSpeechDetected is returned instead of calling a recognizer on audio
+// that only holds silence or noise
+var ErrNoSpeechDetected = errors.New("no speech detected")
+
+// VADConfig tunes the energy and zero-crossing voice activity detector
+type VADConfig struct {
+	FrameDuration time.Duration
+	// Frames louder than ThresholdDB (dBFS) and NoiseMarginDB above the
+	// estimated noise floor are speech
+	ThresholdDB   float64
+	NoiseMarginDB float64
+	// Bursts shorter than MinSpeech are clicks, pauses shorter than
+	// MinSilence do not end a segment
+	MinSpeech  time.Duration
+	MinSilence time.Duration
+	// Padding is kept around speech when trimming
+	Padding time.Duration
+}
+
+func DefaultVADConfig() VADConfig {
+	return VADConfig{
+		FrameDuration: 20 * time.Millisecond,
+		ThresholdDB:   -45,
+		NoiseMarginDB: 10,
+		MinSpeech:     100 * time.Millisecond,
+		MinSilence:    400 * time.Millisecond,
+		Padding:       200 * time.Millisecond,
+	}
+}
+
+// SpeechSegment is a stretch of speech, relative to the start of the audio
+type SpeechSegment struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// VADEvent marks where speech starts or stops
+type VADEvent struct {
+	Speaking bool
+	At       time.Duration
+}
+
+// VoiceActivityDetector classifies 16-bit mono audio frame by frame as it
+// arrives. The noise floor adapts, dropping at once to quieter frames and
+// rising slowly with frames that are not speech, so a slowly rising
+// background is not taken for speech and long speech does not become noise.
+type VoiceActivityDetector struct {
+	config     VADConfig
+	sampleRate int
+	frameSize  int
+	pending    []int
+	frames     int
+	noiseFloor float64
+	speaking   bool
+	run        int
+	runStart   int
+	speechSeen bool
+	minSpeech  int
+	minSilence int
+}
+
+func NewVoiceActivityDetector(sampleRate int, config VADConfig) *VoiceActivityDetector {
+	frameSize := int(int64(sampleRate) * int64(config.FrameDuration) / int64(time.Second))
+	if frameSize < 1 {
+		frameSize = 1
+	}
+
+	return &VoiceActivityDetector{
+		config:     config,
+		sampleRate: sampleRate,
+		frameSize:  frameSize,
+		// Assume a quiet room until the audio says otherwise
+		noiseFloor: config.ThresholdDB - config.NoiseMarginDB,
+		minSpeech:  framesIn(config.MinSpeech, config.FrameDuration),
+		minSilence: framesIn(config.MinSilence, config.FrameDuration),
+	}
+}
+
+func framesIn(d, frame time.Duration) int {
+	if frame <= 0 {
+		return 1
+	}
+	frames := int((d + frame - 1) / frame)
+	if frames < 1 {
+		return 1
+	}
+	return frames
+}
+
+// Push feeds samples and returns the speech boundaries they completed.
+// Events lag the audio by MinSpeech or MinSilence, while the detector makes
+// sure a change is not a blip.
+func (d *VoiceActivityDetector) Push(samples []int) []VADEvent {
+	d.pending = append(d.pending, samples...)
+
+	var events []VADEvent
+	for len(d.pending) >= d.frameSize {
+		if event, ok := d.classify(d.pending[:d.frameSize]); ok {
+			events = append(events, event)
+		}
+		d.pending = d.pending[d.frameSize:]
+	}
+
+	return events
+}
+
+// Flush ends an open speech segment at the end of the audio
+func (d *VoiceActivityDetector) Flush() []VADEvent {
+	d.pending = nil
+	if !d.speaking {
+		return nil
+	}
+
+	d.speaking = false
+	end := d.frames
+	if d.run > 0 {
+		end = d.runStart
+	}
+	d.run = 0
+	return []VADEvent{{Speaking: false, At: d.frameTime(end)}}
+}
+
+// SpeechSeen reports whether any speech was detected so far
+func (d *VoiceActivityDetector) SpeechSeen() bool {
+	return d.speechSeen
+}
+
+func (d *VoiceActivityDetector) classify(frame []int) (VADEvent, bool) {
+	index := d.frames
+	d.frames++
+
+	voiced := d.isSpeech(frame)
+
+	// run counts consecutive frames that disagree with the current state
+	if voiced == d.speaking {
+		d.run = 0
+		return VADEvent{}, false
+	}
+	if d.run == 0 {
+		d.runStart = index
+	}
+	d.run++
+
+	needed := d.minSpeech
+	if d.speaking {
+		needed = d.minSilence
+	}
+	if d.run < needed {
+		return VADEvent{}, false
+	}
+
+	d.speaking = voiced
+	d.run = 0
+	if voiced {
+		d.speechSeen = true
+	}
+	return VADEvent{Speaking: voiced, At: d.frameTime(d.runStart)}, true
+}
+
+func (d *VoiceActivityDetector) isSpeech(frame []int) bool {
+	// Measure around the mean so a DC offset does not count as sound
+	var mean float64
+	for _, sample := range frame {
+		mean += float64(sample)
+	}
+	mean /= float64(len(frame))
+
+	var energy float64
+	crossings := 0
+	previous := 0.0
+	for i, sample := range frame {
+		value := float64(sample) - mean
+		energy += value * value
+		if i > 0 && (value >= 0) != (previous >= 0) {
+			crossings++
+		}
+		previous = value
+	}
+
+	rms := math.Sqrt(energy / float64(len(frame)))
+	level := -100.0
+	if rms > 0 {
+		level = 20 * math.Log10(rms/32768)
+	}
+
+	if level < d.noiseFloor {
+		d.noiseFloor = level
+	}
+
+	threshold := math.Max(d.config.ThresholdDB, d.noiseFloor+d.config.NoiseMarginDB)
+
+	// Unvoiced consonants like "s" and "f" are quieter but cross zero often
+	zeroCrossingRate := float64(crossings) / float64(len(frame))
+	speech := level >= threshold || (level >= threshold-6 && zeroCrossingRate > 0.3)
+
+	// Only noise may raise the floor, or speech would raise it to its own level
+	if !speech {
+		d.noiseFloor += (level - d.noiseFloor) * 0.002
+	}
+	return speech
+}
+
+func (d *VoiceActivityDetector) frameTime(frame int) time.Duration {
+	return time.Duration(int64(frame) * int64(d.frameSize) * int64(time.Second) / int64(d.sampleRate))
+}
+
+// DetectSpeech returns the padded speech segments of 16-bit mono audio.
+// Segments that overlap after padding are merged.
+func DetectSpeech(samples []int, sampleRate int, config VADConfig) []SpeechSegment {
+	if sampleRate <= 0 {
+		return nil
+	}
+
+	detector := NewVoiceActivityDetector(sampleRate, config)
+	events := append(detector.Push(samples), detector.Flush()...)
+	duration := time.Duration(int64(len(samples)) * int64(time.Second) / int64(sampleRate))
+
+	var segments []SpeechSegment
+	for _, event := range events {
+		if event.Speaking {
+			start := event.At - config.Padding
+			if start < 0 {
+				start = 0
+			}
+			if n := len(segments); n > 0 && start <= segments[n-1].End {
+				// Padding made it touch the previous segment
+				segments[n-1].End = duration
+				continue
+			}
+			segments = append(segments, SpeechSegment{Start: start, End: duration})
+			continue
+		}
+
+		end := event.At + config.Padding
+		if end > duration {
+			end = duration
+		}
+		segments[len(segments)-1].End = end
+	}
+
+	return segments
+}
+
+// Slice returns the part of decoded audio between two offsets
+func (a *Audio) Slice(start, end time.Duration) *Audio {
+	channels := a.Channels
+	if channels < 1 {
+		channels = 1
+	}
+	frames := len(a.Samples) / channels
+
+	toFrame := func(d time.Duration) int {
+		frame := int(int64(d) * int64(a.SampleRate) / int64(time.Second))
+		if frame < 0 {
+			return 0
+		}
+		if frame > frames {
+			return frames
+		}
+		return frame
+	}
+
+	sliced := *a
+	sliced.Samples = a.Samples[toFrame(start)*channels : toFrame(end)*channels]
+	return &sliced
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const vadTestRate = 16000
+
+// noise is white noise with the given peak amplitude
+func noise(rng *rand.Rand, duration time.Duration, amplitude float64) []int {
+	samples := make([]int, int(duration.Seconds()*vadTestRate))
+	for i := range samples {
+		samples[i] = int(amplitude * (2*rng.Float64() - 1))
+	}
+	return samples
+}
+
+// tone is a 300 Hz sine with the given amplitude, standing in for voiced speech
+func tone(duration time.Duration, amplitude float64) []int {
+	samples := make([]int, int(duration.Seconds()*vadTestRate))
+	for i := range samples {
+		samples[i] = int(amplitude * math.Sin(2*math.Pi*300*float64(i)/vadTestRate))
+	}
+	return samples
+}
+
+func concat(parts ...[]int) []int {
+	var samples []int
+	for _, part := range parts {
+		samples = append(samples, part...)
+	}
+	return samples
+}
+
+func TestDetectSpeechLongUtterance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	config := DefaultVADConfig()
+
+	// Half a minute without a pause, well past the time the noise floor
+	// would take to catch up with it if speech could raise it
+	samples := concat(noise(rng, time.Second, 10), tone(30*time.Second, 3000), noise(rng, time.Second, 10))
+
+	segments := DetectSpeech(samples, vadTestRate, config)
+	if len(segments) != 1 {
+		t.Fatalf("got %d segments, want 1: %+v", len(segments), segments)
+	}
+
+	start, end := time.Second-config.Padding, 31*time.Second+config.Padding
+	slack := config.FrameDuration
+	if diff := segments[0].Start - start; diff > slack || diff < -slack {
+		t.Errorf("segment starts at %v, want %v", segments[0].Start, start)
+	}
+	if diff := segments[0].End - end; diff > slack || diff < -slack {
+		t.Errorf("segment ends at %v, want %v", segments[0].End, end)
+	}
+}
+
+func TestVoiceActivityDetectorNoiseFloor(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	config := DefaultVADConfig()
+	detector := NewVoiceActivityDetector(vadTestRate, config)
+
+	// Quiet noise below the threshold raises the floor towards its level
+	initial := detector.noiseFloor
+	detector.Push(noise(rng, 10*time.Second, 150))
+	if detector.SpeechSeen() {
+		t.Fatal("noise below the threshold taken for speech")
+	}
+	if detector.noiseFloor <= initial {
+		t.Errorf("noise floor %.1f dB did not rise from %.1f dB with background noise", detector.noiseFloor, initial)
+	}
+
+	// Speech leaves it where it was
+	floor := detector.noiseFloor
+	detector.Push(tone(10*time.Second, 3000))
+	if !detector.SpeechSeen() {
+		t.Fatal("tone not taken for speech")
+	}
+	if detector.noiseFloor != floor {
+		t.Errorf("noise floor moved from %.1f dB to %.1f dB during speech", floor, detector.noiseFloor)
+	}
+
+	// A quieter room lowers it at once
+	detector.Push(noise(rng, time.Second, 5))
+	if detector.noiseFloor >= floor {
+		t.Errorf("noise floor %.1f dB did not drop after quieter audio", detector.noiseFloor)
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+// Replacer rewrites a vocabulary's replacements in transcripts. Matches are
+// whole words, regardless of case. Replacements run in order, each on the
+// output of the one before.
+type Replacer struct {
+	patterns []*regexp.Regexp
+	targets  []string
+}
+
+// NewReplacer compiles the replacements, nil when there are none
+func NewReplacer(replacements []models.ReplacementModel) *Replacer {
+	if len(replacements) == 0 {
+		return nil
+	}
+
+	r := &Replacer{}
+	for _, replacement := range replacements {
+		r.patterns = append(r.patterns, regexp.MustCompile(`(?i)`+regexp.QuoteMeta(replacement.From)))
+		r.targets = append(r.targets, replacement.To)
+	}
+	return r
+}
+
+// Replace rewrites text
+func (r *Replacer) Replace(text string) string {
+	if r == nil {
+		return text
+	}
+	for i, pattern := range r.patterns {
+		var out strings.Builder
+		last := 0
+		for _, match := range pattern.FindAllStringIndex(text, -1) {
+			// \b does not work next to punctuation, as in "C++", so a word
+			// ends at anything but a letter or digit
+			if !wordEdge(text, match[0], match[1]) {
+				continue
+			}
+			out.WriteString(text[last:match[0]])
+			out.WriteString(r.targets[i])
+			last = match[1]
+		}
+		if last > 0 {
+			out.WriteString(text[last:])
+			text = out.String()
+		}
+	}
+	return text
+}
+
+// wordEdge tells whether text[start:end] is not part of a longer word
+func wordEdge(text string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	return !isWordRune(before) && !isWordRune(after)
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// ReplaceTranscript rewrites the text, segments and alternatives of a
+// result. Words are left as they were recognized.
+func (r *Replacer) ReplaceTranscript(result *models.VoiceToTextModel) {
+	if r == nil {
+		return
+	}
+	result.Text = r.Replace(result.Text)
+	for i := range result.Segments {
+		segment := &result.Segments[i]
+		segment.Text = r.Replace(segment.Text)
+		for j := range segment.Alternatives {
+			segment.Alternatives[j].Text = r.Replace(segment.Alternatives[j].Text)
+		}
+	}
+}
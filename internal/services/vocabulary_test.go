@@ -0,0 +1,122 @@
+package services
+
+import (
+	"testing"
+
+	"golang-gin-boilerplate/internal/models"
+)
+
+func TestReplacer(t *testing.T) {
+	tests := []struct {
+		name         string
+		replacements []models.ReplacementModel
+		text         string
+		want         string
+	}{
+		{
+			name:         "whole words regardless of case",
+			replacements: []models.ReplacementModel{{From: "acne cloud", To: "Acme Cloud"}},
+			text:         "Deploy to ACNE Cloud, then acne cloud again.",
+			want:         "Deploy to Acme Cloud, then Acme Cloud again.",
+		},
+		{
+			name:         "not inside longer words",
+			replacements: []models.ReplacementModel{{From: "cat", To: "dog"}},
+			text:         "cat concatenate cats bobcat cat2 cat",
+			want:         "dog concatenate cats bobcat cat2 dog",
+		},
+		{
+			name:         "punctuation inside the match",
+			replacements: []models.ReplacementModel{{From: "C++", To: "C plus plus"}},
+			text:         "I write C++, c++. and C++11 but not C++x",
+			want:         "I write C plus plus, C plus plus. and C++11 but not C++x",
+		},
+		{
+			name:         "punctuation around the match",
+			replacements: []models.ReplacementModel{{From: "kube", To: "Kubernetes"}},
+			text:         "(kube) \"kube\" kube-proxy kube's kube_x",
+			want:         "(Kubernetes) \"Kubernetes\" Kubernetes-proxy Kubernetes's Kubernetes_x",
+		},
+		{
+			name:         "letters beyond ASCII are part of words",
+			replacements: []models.ReplacementModel{{From: "caf", To: "X"}, {From: "über", To: "uber"}},
+			text:         "café caf Über überall 東京caf caf。",
+			want:         "café X uber überall 東京caf X。",
+		},
+		{
+			name:         "regexp characters are literal",
+			replacements: []models.ReplacementModel{{From: "a.b", To: "AB"}},
+			text:         "a.b axb",
+			want:         "AB axb",
+		},
+		{
+			name: "chained in order",
+			replacements: []models.ReplacementModel{
+				{From: "gee pee tee", To: "GPT"},
+				{From: "GPT", To: "ChatGPT"},
+			},
+			text: "ask gee pee tee",
+			want: "ask ChatGPT",
+		},
+		{
+			name: "later replacements see earlier output only",
+			replacements: []models.ReplacementModel{
+				{From: "ChatGPT", To: "the assistant"},
+				{From: "gee pee tee", To: "ChatGPT"},
+			},
+			text: "ask gee pee tee",
+			want: "ask ChatGPT",
+		},
+		{
+			name:         "nothing matches",
+			replacements: []models.ReplacementModel{{From: "zebra", To: "horse"}},
+			text:         "no stripes here",
+			want:         "no stripes here",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewReplacer(tt.replacements).Replace(tt.text); got != tt.want {
+				t.Errorf("Replace(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacerWithoutReplacements(t *testing.T) {
+	replacer := NewReplacer(nil)
+	if replacer != nil {
+		t.Fatal("NewReplacer(nil) is not nil")
+	}
+	if got := replacer.Replace("unchanged"); got != "unchanged" {
+		t.Errorf("nil Replace = %q", got)
+	}
+	replacer.ReplaceTranscript(&models.VoiceToTextModel{Text: "unchanged"})
+}
+
+func TestReplaceTranscript(t *testing.T) {
+	result := models.VoiceToTextModel{
+		Text: "acne cloud is up",
+		Segments: []models.TranscriptSegmentModel{{
+			Text:         "acne cloud is up",
+			Words:        []models.WordModel{{Word: "acne"}, {Word: "cloud"}},
+			Alternatives: []models.TranscriptAlternativeModel{{Text: "acne cloud is up"}, {Text: "acme cloud is up"}},
+		}},
+	}
+	NewReplacer([]models.ReplacementModel{{From: "acne cloud", To: "Acme Cloud"}}).ReplaceTranscript(&result)
+
+	segment := result.Segments[0]
+	for _, text := range []string{result.Text, segment.Text, segment.Alternatives[0].Text} {
+		if text != "Acme Cloud is up" {
+			t.Errorf("text = %q, want it replaced", text)
+		}
+	}
+	if segment.Alternatives[1].Text != "acme cloud is up" {
+		t.Errorf("unmatched alternative changed to %q", segment.Alternatives[1].Text)
+	}
+	// Words keep what was recognized, so their timings still line up
+	if segment.Words[0].Word != "acne" {
+		t.Errorf("word changed to %q", segment.Words[0].Word)
+	}
+}
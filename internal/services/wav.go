@@ -0,0 +1,31 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// EncodeWAV wraps little-endian 16-bit PCM in a canonical RIFF/WAVE header
+func EncodeWAV(pcm []byte, sampleRate, channels int) []byte {
+	buf := new(bytes.Buffer)
+	buf.Grow(44 + len(pcm))
+
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(buf, binary.LittleEndian, uint16(channels))
+	binary.Write(buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(buf, binary.LittleEndian, uint32(sampleRate*channels*2))
+	binary.Write(buf, binary.LittleEndian, uint16(channels*2))
+	binary.Write(buf, binary.LittleEndian, uint16(16))
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+
+	return buf.Bytes()
+}